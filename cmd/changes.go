@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+	"github.com/vectra-guard/vectra-guard/internal/session"
+)
+
+const (
+	maxChangesShown = 20   // Lines of the change report printed after exec
+	maxChangesKept  = 1000 // Low-risk changes beyond this are left out of the session
+	maxExecsKept    = 1000 // Likewise for traced programs
+)
+
+// printChangeReport writes the file change summary shown after a sandboxed
+// command, riskiest changes first.
+func printChangeReport(w io.Writer, report *sandbox.FileChangeReport, cwd string) {
+	if len(report.Changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "📂 Sandbox file changes: %s\n", report.Summary())
+	rank := map[string]int{"low": 0, "medium": 1, "high": 2, "critical": 3}
+	changes := append([]sandbox.FileChange{}, report.Changes...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return rank[changes[i].RiskLevel] > rank[changes[j].RiskLevel]
+	})
+	for i, change := range changes {
+		if i == maxChangesShown {
+			fmt.Fprintf(w, "  ... and %d more\n", len(changes)-i)
+			break
+		}
+		line := fmt.Sprintf("  %-6s %s", change.Operation, displayPath(change.Path, cwd))
+		if change.Operation == "chmod" {
+			line += fmt.Sprintf(" (%v -> %v)", change.OldMode, change.NewMode)
+		}
+		if change.RiskLevel != "low" {
+			line += fmt.Sprintf("  [%s: %s]", change.RiskLevel, change.Reason)
+		}
+		fmt.Fprintln(w, line)
+	}
+	if report.Truncated {
+		fmt.Fprintln(w, "  ⚠️  Workspace too large to track completely; some changes may be missing")
+	}
+}
+
+// fileOperations converts a change report into session records. When there
+// are more than maxChangesKept changes, only the medium and higher risk ones
+// are kept.
+func fileOperations(report *sandbox.FileChangeReport, timestamp time.Time) []session.FileOperation {
+	var ops []session.FileOperation
+	for _, change := range report.Changes {
+		if len(report.Changes) > maxChangesKept && change.RiskLevel == "low" {
+			continue
+		}
+		ops = append(ops, session.FileOperation{
+			Timestamp: timestamp,
+			Operation: change.Operation,
+			Path:      change.Path,
+			Size:      change.Size,
+			RiskLevel: change.RiskLevel,
+			Allowed:   true, // It already happened, inside the sandbox
+			Reason:    change.Reason,
+		})
+	}
+	return ops
+}
+
+// printStragglers lists the background processes a command left running
+// after it exited.
+func printStragglers(w io.Writer, procs []namespace.Process, killed bool) {
+	if len(procs) == 0 {
+		return
+	}
+	verb := "are still running"
+	if killed {
+		verb = "were killed"
+	}
+	fmt.Fprintf(w, "⚠️  %d process(es) outlived the command and %s:\n", len(procs), verb)
+	for _, p := range procs {
+		fmt.Fprintf(w, "   %d  %s\n", p.PID, p.Command)
+	}
+}
+
+// stragglerRecords formats stragglers for the session log
+func stragglerRecords(procs []namespace.Process) []string {
+	var records []string
+	for _, p := range procs {
+		records = append(records, fmt.Sprintf("%d %s", p.PID, p.Command))
+	}
+	return records
+}
+
+// printTracedExecs lists the risky programs a traced command ran, and the
+// one it was killed for.
+func printTracedExecs(w io.Writer, execs []sandbox.TracedExec) {
+	var risky []sandbox.TracedExec
+	for _, ex := range execs {
+		if ex.RiskLevel != "low" {
+			risky = append(risky, ex)
+		}
+	}
+	if len(risky) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "🔎 Traced %d program(s), %d risky:\n", len(execs), len(risky))
+	for _, ex := range risky {
+		var codes []string
+		for _, f := range ex.Findings {
+			codes = append(codes, f.Code)
+		}
+		line := fmt.Sprintf("   %d  %s  [%s: %s]", ex.PID, ex.Command(), ex.RiskLevel, strings.Join(codes, ", "))
+		if ex.Killed {
+			line += "  ← sandbox killed"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// execRecords converts traced programs into session records. When there are
+// more than maxExecsKept, only the medium and higher risk ones are kept.
+func execRecords(execs []sandbox.TracedExec) []session.Exec {
+	var records []session.Exec
+	for _, ex := range execs {
+		if len(execs) > maxExecsKept && ex.RiskLevel == "low" {
+			continue
+		}
+		var codes []string
+		for _, f := range ex.Findings {
+			codes = append(codes, f.Code)
+		}
+		records = append(records, session.Exec{
+			Timestamp: ex.Time,
+			PID:       ex.PID,
+			PPID:      ex.PPID,
+			Args:      ex.Argv,
+			Cwd:       ex.Cwd,
+			RiskLevel: ex.RiskLevel,
+			Findings:  codes,
+			Killed:    ex.Killed,
+		})
+	}
+	return records
+}
+
+// printResourceUsage warns when the sandbox's memory limit killed processes,
+// which otherwise looks like an unexplained crash.
+func printResourceUsage(w io.Writer, usage *sandbox.ResourceUsage) {
+	if usage.OOMKills == 0 {
+		return
+	}
+	line := fmt.Sprintf("⚠️  Sandbox memory limit reached: %d process(es) killed", usage.OOMKills)
+	if usage.MemoryLimit > 0 {
+		line += fmt.Sprintf(" (limit %s)", formatBytes(usage.MemoryLimit))
+	}
+	fmt.Fprintln(w, line)
+	fmt.Fprintln(w, "   Raise sandbox.memory_limit if the command needs more.")
+}
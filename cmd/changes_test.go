@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+)
+
+func TestPrintChangeReport(t *testing.T) {
+	report := &sandbox.FileChangeReport{
+		Workspace: "/ws",
+		Changes: []sandbox.FileChange{
+			{Path: "/ws/a.txt", Operation: "modify", RiskLevel: "low"},
+			{Path: "/ws/run.sh", Operation: "chmod", OldMode: 0o644, NewMode: 0o755, RiskLevel: "medium", Reason: "executable bit added"},
+			{Path: "/ws/.git/hooks/pre-commit", Operation: "create", RiskLevel: "high", Reason: "sensitive file changed"},
+		},
+	}
+	var out bytes.Buffer
+	printChangeReport(&out, report, "/ws")
+	got := out.String()
+	if !strings.Contains(got, "1 created, 1 modified, 0 deleted, 1 permission change(s)") {
+		t.Errorf("missing summary:\n%s", got)
+	}
+	hook := strings.Index(got, ".git/hooks/pre-commit  [high: sensitive file changed]")
+	chmod := strings.Index(got, "run.sh (-rw-r--r-- -> -rwxr-xr-x)  [medium: executable bit added]")
+	plain := strings.Index(got, "modify a.txt\n")
+	if hook < 0 || chmod < 0 || plain < 0 || !(hook < chmod && chmod < plain) {
+		t.Errorf("expected changes riskiest first:\n%s", got)
+	}
+
+	out.Reset()
+	printChangeReport(&out, &sandbox.FileChangeReport{}, "/ws")
+	if out.Len() != 0 {
+		t.Errorf("expected no output without changes, got %q", out.String())
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+	report := &sandbox.FileChangeReport{}
+	for i := 0; i < maxChangesKept+1; i++ {
+		report.Changes = append(report.Changes, sandbox.FileChange{Path: fmt.Sprintf("/ws/f%d", i), Operation: "create", RiskLevel: "low"})
+	}
+	report.Changes = append(report.Changes, sandbox.FileChange{Path: "/ws/gone", Operation: "delete", RiskLevel: "medium", Reason: "deleted"})
+
+	ops := fileOperations(report, time.Now())
+	if len(ops) != 1 || ops[0].Path != "/ws/gone" || ops[0].RiskLevel != "medium" || !ops[0].Allowed {
+		t.Errorf("expected only the medium-risk change to be kept, got %d ops", len(ops))
+	}
+
+	report.Changes = report.Changes[:2]
+	if ops := fileOperations(report, time.Now()); len(ops) != 2 {
+		t.Errorf("expected every change to be kept in a small report, got %d", len(ops))
+	}
+}
+
+func TestPrintTracedExecs(t *testing.T) {
+	execs := []sandbox.TracedExec{
+		{ExecEvent: namespace.ExecEvent{PID: 10, Argv: []string{"npm", "install"}}, RiskLevel: "low"},
+		{ExecEvent: namespace.ExecEvent{PID: 11, Argv: []string{"rm", "-rf", "/"}}, RiskLevel: "critical",
+			Findings: []analyzer.Finding{{Code: "DANGEROUS_DELETE_ROOT", Severity: "critical"}}, Killed: true},
+	}
+	var out bytes.Buffer
+	printTracedExecs(&out, execs)
+	got := out.String()
+	if !strings.Contains(got, "Traced 2 program(s), 1 risky") || strings.Contains(got, "npm install") {
+		t.Errorf("expected only the risky program listed:\n%s", got)
+	}
+	if !strings.Contains(got, "11  rm -rf /  [critical: DANGEROUS_DELETE_ROOT]  ← sandbox killed") {
+		t.Errorf("missing killed program:\n%s", got)
+	}
+
+	out.Reset()
+	printTracedExecs(&out, execs[:1])
+	if out.Len() != 0 {
+		t.Errorf("expected no output without risky programs, got %q", out.String())
+	}
+
+	records := execRecords(execs)
+	if len(records) != 2 || !records[1].Killed || records[1].Findings[0] != "DANGEROUS_DELETE_ROOT" || records[0].Args[0] != "npm" {
+		t.Errorf("execRecords() = %+v", records)
+	}
+}
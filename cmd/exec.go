@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,7 +17,13 @@ import (
 	"github.com/vectra-guard/vectra-guard/internal/session"
 )
 
-func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID string) error {
+// exitTimedOut is the exit status of a command stopped by its timeout, as
+// with timeout(1)
+const exitTimedOut = 124
+
+// runExec analyzes and runs a command. A non-zero timeout overrides the
+// configured one; negative means none.
+func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID string, timeout time.Duration) error {
 	logger := logging.FromContext(ctx)
 	cfg := config.FromContext(ctx)
 
@@ -52,14 +60,41 @@ func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID
 		return executeCommandDirectly(cmdArgs)
 	}
 
+	// Fetch, analyze, then run piped install scripts instead of streaming them into a shell
+	if cfg.PipeToShell.Enabled {
+		if pipe, ok := analyzer.ParsePipeToShell(pipelineString(cmdArgs)); ok {
+			return runSafePipeToShell(ctx, pipe, interactive, sessionID, timeout)
+		}
+	}
+
 	cmdName := cmdArgs[0]
 	args := cmdArgs[1:]
 
 	// Build command string for analysis
 	cmdString := strings.Join(cmdArgs, " ")
 
+	// Resolve which cluster/account/workspace the command will act on
+	opts := analyzer.OptionsFromConfig(cfg)
+	opts.Context = config.DetectionContext{Command: cmdString}
+	if wd, err := os.Getwd(); err == nil {
+		opts.Context.WorkingDir = wd
+		opts.Workspace = workspaceRoot(cfg, wd)
+	}
+	opts.Context.ResolveCloudTargets(strings.Fields(pipelineString(cmdArgs)))
+	cloudTargets := opts.Context.CloudTargets()
+
 	// Analyze command for risks
-	findings := analyzer.AnalyzeScript("inline-command", []byte(cmdString), cfg.Policies)
+	findings := analyzer.AnalyzeScriptWithOptions("inline-command", []byte(cmdString), cfg.Policies, opts)
+
+	// Preview what `terraform apply PLAN` will delete or replace
+	planFindings, err := terraformPlanFindings(ctx, cfg, cmdArgs)
+	if err != nil {
+		logger.Warn("terraform plan preview failed", map[string]any{
+			"command": cmdString,
+			"error":   err.Error(),
+		})
+	}
+	findings = append(findings, planFindings...)
 	
 	riskLevel := "low"
 	var findingCodes []string
@@ -103,6 +138,7 @@ func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID
 				"severity":       f.Severity,
 				"description":    f.Description,
 				"recommendation": f.Recommendation,
+				"targets":        cloudTargets,
 			})
 		}
 
@@ -112,7 +148,9 @@ func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID
 		// Handle interactive approval or blocking
 		if requiresApproval {
 			if interactive {
-				approval := promptForApproval(riskLevel, cmdString, filteredFindings)
+				// Show what the command would actually delete, move or chmod
+				impacts := analyzer.PreviewImpact(pipelineString(cmdArgs), opts)
+				approval := promptForApproval(riskLevel, cmdString, filteredFindings, cloudTargets, impacts)
 				if !approval.approved {
 					logger.Info("command execution denied by user", map[string]any{
 						"command": cmdString,
@@ -216,62 +254,96 @@ func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID
 	// Decide execution mode (host vs sandbox)
 	// Pass findings so sandbox can make informed decisions
 	decision := executor.DecideExecutionMode(ctx, cmdArgs, riskLevel, filteredFindings)
+	decision.Timeout = timeout
+	decision.ApprovedFindings = findingCodes
 	
 	// Show user-friendly notice
 	displayExecutionNotice(decision, riskLevel)
+
+	// Snapshot the workspace so a risky host command can be undone
+	snapshotID := ""
+	if decision.Mode == sandbox.ExecutionModeHost && opts.Workspace != "" && shouldSnapshot(cfg.Snapshots, riskLevel) {
+		snapshotID = takeSnapshot(logger, cfg.Snapshots, opts.Workspace, cmdString)
+	}
+
+	// Give the sandbox a copy-on-write workspace; its changes are reviewed below
+	if decision.Mode == sandbox.ExecutionModeSandbox && cfg.Sandbox.WorkspaceMode == "overlay" {
+		overlay, err := executor.PrepareOverlay()
+		if err != nil {
+			return fmt.Errorf("prepare copy-on-write workspace: %w", err)
+		}
+		defer overlay.Cleanup()
+		decision.Overlay = overlay
+	}
 	
 	// Execute command in chosen mode
 	start := time.Now()
 	err = executor.Execute(ctx, cmdArgs, decision)
 	duration := time.Since(start)
 
-	exitCode := 0
+	var killed *sandbox.KilledExecError
+	errors.As(err, &killed)
+	exitCode, timedOut, err := executionStatus(err)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			logger.Error("command execution failed", map[string]any{
-				"command": cmdString,
-				"error":   err.Error(),
-				"mode":    decision.Mode,
-			})
-			return fmt.Errorf("execute command: %w", err)
-		}
+		logger.Error("command execution failed", map[string]any{
+			"command": cmdString,
+			"error":   err.Error(),
+			"mode":    decision.Mode,
+		})
+		return fmt.Errorf("execute command: %w", err)
 	}
 
-	// Track in session if available
-	if sessionID != "" || session.GetCurrentSession() != "" {
-		if sessionID == "" {
-			sessionID = session.GetCurrentSession()
-		}
-		
-		workspace, _ := os.Getwd()
-		mgr, err := session.NewManager(workspace, logger)
-		if err == nil {
-			sess, err := mgr.Load(sessionID)
-			if err == nil {
-				cmdRecord := session.Command{
-					Timestamp: start,
-					Command:   cmdName,
-					Args:      args,
-					ExitCode:  exitCode,
-					Duration:  duration,
-					RiskLevel: riskLevel,
-					Approved:  interactive || riskLevel == "low",
-					Findings:  findingCodes,
-				}
-				_ = mgr.AddCommand(sess, cmdRecord)
-			}
+	var fileOps []session.FileOperation
+	if report := executor.ChangeReport(); report != nil {
+		cwd, _ := os.Getwd()
+		printChangeReport(os.Stderr, report, cwd)
+		fileOps = fileOperations(report, start)
+	}
+
+	if usage := executor.ResourceUsage(); usage != nil {
+		printResourceUsage(os.Stderr, usage)
+	}
+
+	stragglers := executor.Stragglers()
+	printStragglers(os.Stderr, stragglers, timedOut || decision.Mode == sandbox.ExecutionModeSandbox)
+	printTracedExecs(os.Stderr, executor.TracedExecs())
+
+	if decision.Overlay != nil {
+		if err := reviewOverlay(decision.Overlay, os.Stdin, os.Stderr, stdinIsTerminal()); err != nil {
+			return fmt.Errorf("review sandbox changes: %w", err)
 		}
 	}
 
+	// Track in session if available
+	recordSessionCommand(logger, sessionID, session.Command{
+		Timestamp:  start,
+		Command:    cmdName,
+		Args:       args,
+		ExitCode:   exitCode,
+		Duration:   duration,
+		RiskLevel:  riskLevel,
+		Approved:   interactive || riskLevel == "low",
+		Findings:   findingCodes,
+		SnapshotID: snapshotID,
+		TimedOut:   timedOut,
+		Stragglers: stragglerRecords(stragglers),
+		Execs:      execRecords(executor.TracedExecs()),
+	}, fileOps...)
+
 	logger.Info("command executed", map[string]any{
 		"command":   cmdString,
 		"exit_code": exitCode,
 		"duration":  duration.String(),
 		"risk":      riskLevel,
+		"timed_out": timedOut,
 	})
 
+	if killed != nil {
+		return &exitError{message: killed.Error(), code: 3}
+	}
+	if timedOut {
+		return &exitError{message: fmt.Sprintf("command exceeded its timeout and was stopped after %s", duration.Round(time.Second)), code: exitTimedOut}
+	}
 	if exitCode != 0 {
 		return &exitError{message: fmt.Sprintf("command exited with code %d", exitCode), code: exitCode}
 	}
@@ -279,17 +351,86 @@ func runExec(ctx context.Context, cmdArgs []string, interactive bool, sessionID
 	return nil
 }
 
+// executionStatus maps the error from Executor.Execute to the command's exit
+// status, or returns it if the command didn't run to an exit.
+func executionStatus(err error) (exitCode int, timedOut bool, failure error) {
+	if err == nil {
+		return 0, false, nil
+	}
+	var timeoutErr *sandbox.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return exitTimedOut, true, nil
+	}
+	var killedErr *sandbox.KilledExecError
+	if errors.As(err, &killedErr) {
+		return 128 + 9, false, nil // SIGKILL, as the shell would report it
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), false, nil
+	}
+	return 0, false, err
+}
+
+// recordSessionCommand appends the command and the file operations it made
+// to the given or current session, if any
+func recordSessionCommand(logger *logging.Logger, sessionID string, cmdRecord session.Command, fileOps ...session.FileOperation) {
+	if sessionID == "" {
+		sessionID = session.GetCurrentSession()
+	}
+	if sessionID == "" {
+		return
+	}
+
+	workspace, _ := os.Getwd()
+	mgr, err := session.NewManager(workspace, logger)
+	if err != nil {
+		return
+	}
+	sess, err := mgr.Load(sessionID)
+	if err != nil {
+		return
+	}
+	_ = mgr.AddCommand(sess, cmdRecord)
+	if len(fileOps) > 0 {
+		_ = mgr.AddFileOperations(sess, fileOps)
+	}
+}
+
+// workspaceRoot is sandbox.workspace_dir if set, else the enclosing git
+// repository of cwd, else cwd itself.
+func workspaceRoot(cfg config.Config, cwd string) string {
+	if cfg.Sandbox.WorkspaceDir != "" {
+		return cfg.Sandbox.WorkspaceDir
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if filepath.Dir(dir) == dir {
+			return cwd
+		}
+	}
+}
+
 type approvalResult struct {
 	approved bool
 	remember bool
 	duration time.Duration
 }
 
-func promptForApproval(riskLevel, cmdString string, findings []analyzer.Finding) approvalResult {
+func promptForApproval(riskLevel, cmdString string, findings []analyzer.Finding, targets []string, impacts []analyzer.Impact) approvalResult {
 	result := approvalResult{approved: false, remember: false, duration: 0}
 	
 	fmt.Fprintf(os.Stderr, "\n⚠️  Command requires approval\n")
 	fmt.Fprintf(os.Stderr, "Command: %s\n", cmdString)
+	if analyzer.HasDeceptiveUnicode(cmdString) {
+		// Show the exact bytes and what the reader probably thinks they are approving
+		fmt.Fprintf(os.Stderr, "Escaped: %s\n", analyzer.EscapeUnicode(cmdString))
+		fmt.Fprintf(os.Stderr, "Looks like: %s\n", analyzer.NormalizeUnicode(cmdString))
+	}
+	for _, target := range targets {
+		fmt.Fprintf(os.Stderr, "Target: %s\n", target)
+	}
 	fmt.Fprintf(os.Stderr, "Risk Level: %s\n\n", strings.ToUpper(riskLevel))
 	
 	if len(findings) > 0 {
@@ -301,6 +442,9 @@ func promptForApproval(riskLevel, cmdString string, findings []analyzer.Finding)
 		fmt.Fprintln(os.Stderr)
 	}
 
+	cwd, _ := os.Getwd()
+	printImpact(os.Stderr, impacts, cwd)
+
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	fmt.Fprintf(os.Stderr, "  y  - Yes, run once\n")
 	fmt.Fprintf(os.Stderr, "  r  - Yes, and remember (trust permanently)\n")
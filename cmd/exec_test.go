@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/vectra-guard/vectra-guard/internal/analyzer"
 	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
 )
 
 func TestFilterFindingsByGuardLevel(t *testing.T) {
@@ -507,3 +513,72 @@ func TestSecurityImprovementsRegression(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkspaceRoot(t *testing.T) {
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "pkg", "api")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	if got := workspaceRoot(cfg, sub); got != repo {
+		t.Errorf("expected enclosing repository %s, got %s", repo, got)
+	}
+
+	plain := t.TempDir()
+	if got := workspaceRoot(cfg, plain); got != plain {
+		t.Errorf("expected cwd outside a repository, got %s", got)
+	}
+
+	cfg.Sandbox.WorkspaceDir = "/srv/app"
+	if got := workspaceRoot(cfg, sub); got != "/srv/app" {
+		t.Errorf("expected configured workspace_dir, got %s", got)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "90s", want: 90 * time.Second},
+		{value: "10m", want: 10 * time.Minute},
+		{value: "45", want: 45 * time.Second},
+		{value: "0", want: -1},
+		{value: "-5s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeout(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeout(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTimeout(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestExecutionStatus(t *testing.T) {
+	code, timedOut, err := executionStatus(&sandbox.TimeoutError{Timeout: time.Minute})
+	if code != exitTimedOut || !timedOut || err != nil {
+		t.Errorf("timeout = %d, %v, %v", code, timedOut, err)
+	}
+
+	code, timedOut, err = executionStatus(exec.Command("sh", "-c", "exit 7").Run())
+	if code != 7 || timedOut || err != nil {
+		t.Errorf("exit status = %d, %v, %v", code, timedOut, err)
+	}
+
+	failure := errors.New("no runtime")
+	if _, _, err := executionStatus(failure); err != failure {
+		t.Errorf("failure = %v, want %v", err, failure)
+	}
+}
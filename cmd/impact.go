@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+)
+
+// printImpact writes the impact preview shown in the approval prompt.
+func printImpact(w io.Writer, impacts []analyzer.Impact, cwd string) {
+	if len(impacts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Impact preview:\n")
+	for _, impact := range impacts {
+		count := fmt.Sprintf("%d", impact.Files)
+		if impact.Truncated || impact.Filtered {
+			count = "up to " + count
+		}
+		fmt.Fprintf(w, "  %s: %s %s file(s), %s\n", impact.Command, impact.Operation, count, formatBytes(impact.Bytes))
+		if len(impact.Paths) > 1 {
+			fmt.Fprintf(w, "    %d path(s) after glob expansion\n", len(impact.Paths))
+		}
+		for _, dir := range impact.TopDirs {
+			fmt.Fprintf(w, "    %-40s %6d file(s) %10s\n", displayPath(dir.Path, cwd), dir.Files, formatBytes(dir.Bytes))
+		}
+		if len(impact.Modified) > 0 {
+			fmt.Fprintf(w, "    ⚠️  %d git-tracked file(s) with uncommitted changes:\n", len(impact.Modified))
+			for i, path := range impact.Modified {
+				if i == 10 {
+					fmt.Fprintf(w, "      ... and %d more\n", len(impact.Modified)-i)
+					break
+				}
+				fmt.Fprintf(w, "      %s\n", displayPath(path, cwd))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// displayPath shortens paths under the working directory.
+func displayPath(path, cwd string) string {
+	if cwd == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+		return rel
+	}
+	return path
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+)
+
+func TestPrintImpact(t *testing.T) {
+	var buf bytes.Buffer
+	printImpact(&buf, []analyzer.Impact{{
+		Command:   "rm -rf build",
+		Operation: "delete",
+		Paths:     []string{"/ws/build"},
+		Files:     3,
+		Bytes:     3 << 20,
+		TopDirs:   []analyzer.DirImpact{{Path: "/ws/build/assets", Files: 2, Bytes: 2 << 20}},
+		Modified:  []string{"/ws/build/keep.txt"},
+		Filtered:  true,
+	}}, "/ws")
+
+	out := buf.String()
+	for _, want := range []string{"rm -rf build: delete up to 3 file(s), 3.0 MiB", "build/assets", "2.0 MiB", "1 git-tracked file(s) with uncommitted changes", "build/keep.txt"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("impact preview missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	printImpact(&buf, nil, "/ws")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without impacts, got %q", buf.String())
+	}
+}
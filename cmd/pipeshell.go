@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/logging"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
+	"github.com/vectra-guard/vectra-guard/internal/session"
+)
+
+// runSafePipeToShell replaces `curl URL | sh` with: download to disk, analyze
+// the script, show its sha256, and run the saved copy in the sandbox once approved.
+func runSafePipeToShell(ctx context.Context, pipe analyzer.PipeToShell, interactive bool, sessionID string, timeout time.Duration) error {
+	logger := logging.FromContext(ctx)
+	cfg := config.FromContext(ctx)
+
+	executor, err := sandbox.NewExecutor(cfg, logger)
+	if err != nil {
+		return &exitError{message: fmt.Sprintf("cannot review piped script without sandbox: %v", err), code: 3}
+	}
+
+	maxKB := cfg.PipeToShell.MaxSizeKB
+	if maxKB <= 0 {
+		maxKB = config.DefaultConfig().PipeToShell.MaxSizeKB
+	}
+	script, err := executor.FetchScript(ctx, pipe.URL, int64(maxKB)*1024)
+	if err != nil {
+		return &exitError{message: fmt.Sprintf("fetch script %s: %v", pipe.URL, err), code: 3}
+	}
+
+	findings := filterFindingsByGuardLevel(analyzer.AnalyzeScriptWithOptions(script.Path, script.Content, cfg.Policies, analyzer.OptionsFromConfig(cfg)), cfg.GuardLevel.Level)
+	riskLevel := highestSeverity(findings)
+	var findingCodes []string
+	for _, f := range findings {
+		findingCodes = append(findingCodes, f.Code)
+	}
+
+	fmt.Fprintf(os.Stderr, "\n📥 Fetched %s for review\n", pipe.URL)
+	fmt.Fprintf(os.Stderr, "   SHA256: %s\n", script.SHA256)
+	fmt.Fprintf(os.Stderr, "   Size:   %d bytes\n", script.Size)
+	fmt.Fprintf(os.Stderr, "   Saved:  %s\n", script.Path)
+
+	logger.Info("piped script fetched", map[string]any{
+		"url":      pipe.URL,
+		"sha256":   script.SHA256,
+		"size":     script.Size,
+		"findings": findingCodes,
+	})
+
+	cmdArgs := append([]string{pipe.Interpreter, script.Path}, pipe.ScriptArgs...)
+	cmdString := strings.Join(cmdArgs, " ")
+	trustKey := "sha256:" + script.SHA256
+
+	trustStore, _ := sandbox.NewTrustStore(cfg.Sandbox.TrustStorePath)
+	pinned := isPinnedScript(cfg.PipeToShell.PinnedHashes, script.SHA256) ||
+		(trustStore != nil && trustStore.IsTrusted(trustKey))
+
+	approved := pinned
+	switch {
+	case pinned:
+		fmt.Fprintln(os.Stderr, "✅ Script hash is pinned; running without prompt")
+	case interactive:
+		approval := promptForApproval(riskLevel, cmdString, findings, nil, nil)
+		if !approval.approved {
+			logger.Info("piped script denied by user", map[string]any{"url": pipe.URL, "sha256": script.SHA256})
+			return &exitError{message: "execution denied", code: 3}
+		}
+		approved = true
+		if approval.remember && trustStore != nil {
+			if err := trustStore.Add(trustKey, approval.duration, "Pinned script "+pipe.URL); err == nil {
+				fmt.Fprintln(os.Stderr, "✅ Approved and pinned script hash")
+			}
+		}
+	default:
+		for i, f := range findings {
+			fmt.Fprintf(os.Stderr, "%d. [%s] %s\n", i+1, f.Code, f.Description)
+		}
+		return &exitError{
+			message: fmt.Sprintf("piped script %s requires approval (use --interactive, or pin sha256 %s in pipe_to_shell.pinned_hashes)", pipe.URL, script.SHA256),
+			code:    3,
+		}
+	}
+
+	decision := sandbox.ExecutionDecision{
+		Mode:             sandbox.ExecutionModeSandbox,
+		Reason:           "fetched script " + pipe.URL,
+		RiskLevel:        riskLevel,
+		SecurityLevel:    string(cfg.Sandbox.SecurityLevel),
+		Timeout:          timeout,
+		ApprovedFindings: findingCodes,
+	}
+	displayExecutionNotice(decision, riskLevel)
+
+	start := time.Now()
+	err = executor.Execute(ctx, cmdArgs, decision)
+	duration := time.Since(start)
+
+	var killed *sandbox.KilledExecError
+	errors.As(err, &killed)
+	exitCode, timedOut, err := executionStatus(err)
+	if err != nil {
+		return fmt.Errorf("execute script: %w", err)
+	}
+	stragglers := executor.Stragglers()
+	printStragglers(os.Stderr, stragglers, true)
+	printTracedExecs(os.Stderr, executor.TracedExecs())
+
+	recordSessionCommand(logger, sessionID, session.Command{
+		Timestamp:  start,
+		Command:    pipe.Interpreter,
+		Args:       cmdArgs[1:],
+		ExitCode:   exitCode,
+		Duration:   duration,
+		RiskLevel:  riskLevel,
+		Approved:   approved,
+		Findings:   findingCodes,
+		TimedOut:   timedOut,
+		Stragglers: stragglerRecords(stragglers),
+		Execs:      execRecords(executor.TracedExecs()),
+		Metadata: map[string]interface{}{
+			"source_url": pipe.URL,
+			"sha256":     script.SHA256,
+			"pinned":     pinned,
+		},
+	})
+
+	if killed != nil {
+		return &exitError{message: killed.Error(), code: 3}
+	}
+	if timedOut {
+		return &exitError{message: fmt.Sprintf("script exceeded its timeout and was stopped after %s", duration.Round(time.Second)), code: exitTimedOut}
+	}
+	if exitCode != 0 {
+		return &exitError{message: fmt.Sprintf("command exited with code %d", exitCode), code: exitCode}
+	}
+	return nil
+}
+
+// pipelineString returns the shell text of the command, unwrapping `sh -c "..."`
+func pipelineString(cmdArgs []string) string {
+	if len(cmdArgs) == 3 && cmdArgs[1] == "-c" {
+		switch cmdArgs[0] {
+		case "sh", "bash", "zsh", "/bin/sh", "/bin/bash":
+			return cmdArgs[2]
+		}
+	}
+	return strings.Join(cmdArgs, " ")
+}
+
+// highestSeverity returns the most severe level among findings ("low" if none)
+func highestSeverity(findings []analyzer.Finding) string {
+	rank := map[string]int{"low": 0, "medium": 1, "high": 2, "critical": 3}
+	level := "low"
+	for _, f := range findings {
+		if rank[f.Severity] > rank[level] {
+			level = f.Severity
+		}
+	}
+	return level
+}
+
+func isPinnedScript(pinned []string, digest string) bool {
+	for _, hash := range pinned {
+		if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(hash), "sha256:"), digest) {
+			return true
+		}
+	}
+	return false
+}
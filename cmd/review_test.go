@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/sandbox"
+)
+
+func TestReviewOverlay(t *testing.T) {
+	setup := func(t *testing.T) (string, *sandbox.OverlayWorkspace) {
+		ws := t.TempDir()
+		os.WriteFile(filepath.Join(ws, "main.go"), []byte("package main\n"), 0o644)
+		os.WriteFile(filepath.Join(ws, "notes.txt"), []byte("notes\n"), 0o644)
+		overlay, err := sandbox.NewOverlayWorkspace(ws, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { overlay.Cleanup() })
+		os.WriteFile(filepath.Join(overlay.Upper, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+		os.Remove(filepath.Join(overlay.Upper, "notes.txt"))
+		return ws, overlay
+	}
+
+	t.Run("select", func(t *testing.T) {
+		ws, overlay := setup(t)
+		var out bytes.Buffer
+		if err := reviewOverlay(overlay, strings.NewReader("s\ny\nn\n"), &out, true); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"0 created, 1 modified, 1 deleted", "~ main.go", "- notes.txt", "+func main() {}", "Promoted 1 of 2"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, out.String())
+			}
+		}
+		if data, _ := os.ReadFile(filepath.Join(ws, "main.go")); !strings.Contains(string(data), "func main") {
+			t.Error("expected main.go to be promoted")
+		}
+		if _, err := os.Stat(filepath.Join(ws, "notes.txt")); err != nil {
+			t.Error("expected notes.txt to be kept")
+		}
+	})
+
+	t.Run("non-interactive", func(t *testing.T) {
+		ws, overlay := setup(t)
+		var out bytes.Buffer
+		if err := reviewOverlay(overlay, strings.NewReader("a\n"), &out, false); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "discarded") {
+			t.Errorf("expected changes to be discarded:\n%s", out.String())
+		}
+		if data, _ := os.ReadFile(filepath.Join(ws, "main.go")); string(data) != "package main\n" {
+			t.Errorf("main.go changed without confirmation: %q", data)
+		}
+	})
+}
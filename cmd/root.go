@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/vectra-guard/vectra-guard/internal/config"
 	"github.com/vectra-guard/vectra-guard/internal/logging"
@@ -84,17 +86,65 @@ func execute(args []string) error {
 			return usageError()
 		}
 		return runExplain(ctx, subFlags.Arg(0))
+	case "tfplan":
+		subFlags := flag.NewFlagSet("tfplan", flag.ContinueOnError)
+		if err := subFlags.Parse(subArgs); err != nil {
+			return err
+		}
+		if subFlags.NArg() != 1 {
+			return usageError()
+		}
+		return runTFPlan(ctx, subFlags.Arg(0))
 	case "exec":
 		subFlags := flag.NewFlagSet("exec", flag.ContinueOnError)
 		interactive := subFlags.Bool("interactive", false, "Prompt for approval on risky commands")
 		sessionID := subFlags.String("session", "", "Track execution in session")
+		safePipe := subFlags.Bool("safe-pipe", false, "Fetch and analyze piped install scripts before running them")
+		takeSnapshot := subFlags.Bool("snapshot", false, "Snapshot the workspace before running on the host, whatever the risk")
+		overlay := subFlags.Bool("overlay", false, "Give the sandbox a copy-on-write workspace and review its changes")
+		trace := subFlags.Bool("trace", false, "Trace and analyze every program the sandboxed command runs")
+		timeoutFlag := subFlags.String("timeout", "", "Stop the command after this long (e.g. 90s, 10m, or seconds; 0 for no timeout)")
 		if err := subFlags.Parse(subArgs); err != nil {
 			return err
 		}
 		if subFlags.NArg() < 1 {
 			return usageError()
 		}
-		return runExec(ctx, subFlags.Args(), *interactive, *sessionID)
+		timeout, err := parseTimeout(*timeoutFlag)
+		if err != nil {
+			return err
+		}
+		if *safePipe {
+			cfg.PipeToShell.Enabled = true
+			ctx = config.WithConfig(ctx, cfg)
+		}
+		if *takeSnapshot {
+			cfg.Snapshots.Enabled = true
+			cfg.Snapshots.MinRisk = "low"
+			ctx = config.WithConfig(ctx, cfg)
+		}
+		if *overlay {
+			cfg.Sandbox.WorkspaceMode = "overlay"
+			ctx = config.WithConfig(ctx, cfg)
+		}
+		if *trace {
+			cfg.Tracing.Enabled = true
+			ctx = config.WithConfig(ctx, cfg)
+		}
+		return runExec(ctx, subFlags.Args(), *interactive, *sessionID, timeout)
+	case "undo":
+		subFlags := flag.NewFlagSet("undo", flag.ContinueOnError)
+		list := subFlags.Bool("list", false, "List snapshots of the current workspace")
+		if err := subFlags.Parse(subArgs); err != nil {
+			return err
+		}
+		if *list {
+			return runUndoList(ctx)
+		}
+		if subFlags.NArg() > 1 {
+			return usageError()
+		}
+		return runUndo(ctx, subFlags.Arg(0))
 	case "session":
 		if len(subArgs) < 1 {
 			return usageError()
@@ -193,6 +243,29 @@ func runVersion(ctx context.Context, outputFormat string) error {
 	return nil
 }
 
+// parseTimeout parses --timeout: a duration, or whole seconds. Zero disables
+// the timeout and comes back negative; empty keeps the configured one.
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid --timeout %q: %w", value, err)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid --timeout %q: must not be negative", value)
+	}
+	if timeout == 0 {
+		return -1, nil
+	}
+	return timeout, nil
+}
+
 func usageError() error {
 	exe, _ := os.Executable()
 	name := filepath.Base(exe)
@@ -202,7 +275,15 @@ Commands:
   init                         Initialize configuration file
   validate <script>            Validate a shell script for security issues
   explain <script>             Explain security risks in a script
+  tfplan <plan>                Report deletes/replacements in a terraform plan
   exec [--interactive] <cmd>   Execute command with security validation
+       [--safe-pipe]           Fetch, analyze, then run "curl URL | sh" pipelines
+       [--snapshot]            Snapshot the workspace first (see undo)
+       [--overlay]             Sandbox writes to a copy; review and promote changes
+       [--timeout DURATION]    Stop the command after DURATION (0 for none)
+       [--trace]               Analyze every program the sandboxed command runs
+  undo [command-id|snap-id]    Restore the snapshot taken before a command
+  undo --list                  List snapshots of the current workspace
   session start                Start an agent session
   session end <id>             End an agent session
   session list                 List all sessions
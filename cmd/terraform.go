@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/logging"
+)
+
+// terraformApplyValueFlags take the next word as their value
+var terraformApplyValueFlags = map[string]bool{
+	"-var": true, "-var-file": true, "-target": true, "-replace": true, "-state": true,
+	"-state-out": true, "-backup": true, "-lock-timeout": true, "-parallelism": true,
+}
+
+// terraformSavedPlan recognizes `terraform [-chdir=DIR] apply [flags] PLAN` and
+// returns the terraform binary, its working directory and the plan path.
+func terraformSavedPlan(args []string) (bin, dir, plan string, ok bool) {
+	if len(args) == 0 {
+		return "", "", "", false
+	}
+	switch filepath.Base(args[0]) {
+	case "terraform", "tofu":
+	default:
+		return "", "", "", false
+	}
+	bin = args[0]
+
+	i := 1
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		if strings.HasPrefix(args[i], "-chdir=") {
+			dir = strings.TrimPrefix(args[i], "-chdir=")
+		}
+	}
+	if i >= len(args) || args[i] != "apply" {
+		return "", "", "", false
+	}
+
+	for i++; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") {
+			if terraformApplyValueFlags[arg] {
+				i++
+			}
+			continue
+		}
+		plan = arg
+	}
+	if plan == "" {
+		return "", "", "", false
+	}
+	if info, err := os.Stat(planFilePath(dir, plan)); err != nil || info.IsDir() {
+		return "", "", "", false
+	}
+	return bin, dir, plan, true
+}
+
+// terraformPlanFindings previews the saved plan passed to `terraform apply`.
+// It returns no findings for any other command.
+func terraformPlanFindings(ctx context.Context, cfg config.Config, cmdArgs []string) ([]analyzer.Finding, error) {
+	if !cfg.Terraform.Enabled {
+		return nil, nil
+	}
+	bin, dir, plan, ok := terraformSavedPlan(strings.Fields(pipelineString(cmdArgs)))
+	if !ok {
+		return nil, nil
+	}
+	return analyzeTerraformPlanFile(ctx, cfg, bin, dir, plan)
+}
+
+// analyzeTerraformPlanFile reads a plan as JSON, running `terraform show -json`
+// first when the file is a binary plan.
+func analyzeTerraformPlanFile(ctx context.Context, cfg config.Config, bin, dir, plan string) ([]analyzer.Finding, error) {
+	data, err := os.ReadFile(planFilePath(dir, plan))
+	if err != nil {
+		return nil, fmt.Errorf("read terraform plan: %w", err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		showCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		show := exec.CommandContext(showCtx, bin, "show", "-json", plan)
+		show.Dir = dir
+		show.Stderr = os.Stderr
+		data, err = show.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s show -json %s: %w", bin, plan, err)
+		}
+	}
+
+	changes, err := analyzer.ParseTerraformPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	patterns := cfg.Terraform.SensitiveResources
+	if len(patterns) == 0 {
+		patterns = config.DefaultConfig().Terraform.SensitiveResources
+	}
+	return analyzer.AnalyzeTerraformPlan(changes, patterns), nil
+}
+
+// planFilePath resolves plan the way terraform does, relative to -chdir.
+func planFilePath(dir, plan string) string {
+	if dir == "" || filepath.IsAbs(plan) {
+		return plan
+	}
+	return filepath.Join(dir, plan)
+}
+
+// runTFPlan reports the deletes and replacements in a saved or JSON plan.
+func runTFPlan(ctx context.Context, planPath string) error {
+	logger := logging.FromContext(ctx)
+	cfg := config.FromContext(ctx)
+
+	findings, err := analyzeTerraformPlanFile(ctx, cfg, "terraform", "", planPath)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		logger.Info("terraform plan has no deletes or replacements", map[string]any{"path": planPath})
+		return nil
+	}
+
+	for _, f := range findings {
+		logger.Warn("finding", map[string]any{
+			"path":           planPath,
+			"code":           f.Code,
+			"severity":       f.Severity,
+			"description":    f.Description,
+			"recommendation": f.Recommendation,
+		})
+	}
+	return &exitError{message: "violations detected", code: 2}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/logging"
+)
+
+func TestTerraformSavedPlan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tfplan"), []byte("binary"), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	_, gotDir, plan, ok := terraformSavedPlan([]string{"terraform", "-chdir=" + dir, "apply", "-auto-approve", "-parallelism", "4", "tfplan"})
+	if !ok || gotDir != dir || plan != "tfplan" {
+		t.Fatalf("expected saved plan tfplan in %s, got %q %q %v", dir, gotDir, plan, ok)
+	}
+
+	for _, args := range [][]string{
+		{"terraform", "-chdir=" + dir, "apply"},
+		{"terraform", "-chdir=" + dir, "apply", "-var", "tfplan"},
+		{"terraform", "-chdir=" + dir, "plan", "-out", "tfplan"},
+		{"terraform", "-chdir=" + dir, "apply", "missing.plan"},
+		{"echo", "apply", "tfplan"},
+	} {
+		if _, _, _, ok := terraformSavedPlan(args); ok {
+			t.Errorf("did not expect a saved plan for %v", args)
+		}
+	}
+}
+
+func TestRunTFPlanReportsDestroys(t *testing.T) {
+	dir := t.TempDir()
+	plan := filepath.Join(dir, "plan.json")
+	body := `{"resource_changes": [{"address": "google_sql_database_instance.main", "mode": "managed", "type": "google_sql_database_instance", "change": {"actions": ["delete"]}}]}`
+	if err := os.WriteFile(plan, []byte(body), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+
+	ctx := context.Background()
+	ctx = config.WithConfig(ctx, config.DefaultConfig())
+	ctx = logging.WithLogger(ctx, logging.NewLogger("text", os.Stdout))
+
+	err := runTFPlan(ctx, plan)
+	if exitErr, ok := err.(*exitError); !ok || exitErr.code != 2 {
+		t.Fatalf("expected exit error code 2, got %#v", err)
+	}
+
+	clean := filepath.Join(dir, "clean.json")
+	if err := os.WriteFile(clean, []byte(`{"resource_changes": []}`), 0o644); err != nil {
+		t.Fatalf("write plan: %v", err)
+	}
+	if err := runTFPlan(ctx, clean); err != nil {
+		t.Fatalf("expected clean plan to pass, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/logging"
+	"github.com/vectra-guard/vectra-guard/internal/session"
+	"github.com/vectra-guard/vectra-guard/internal/snapshot"
+)
+
+// shouldSnapshot reports whether a host command at riskLevel gets a snapshot.
+func shouldSnapshot(cfg config.SnapshotConfig, riskLevel string) bool {
+	if !cfg.Enabled {
+		return false
+	}
+	rank := map[string]int{"low": 0, "medium": 1, "high": 2, "critical": 3}
+	minRisk, ok := rank[strings.ToLower(cfg.MinRisk)]
+	if !ok {
+		minRisk = rank["high"]
+	}
+	return rank[riskLevel] >= minRisk
+}
+
+// takeSnapshot captures workspace before command runs and returns the
+// snapshot ID, or "" if it failed (the command still runs).
+func takeSnapshot(logger *logging.Logger, cfg config.SnapshotConfig, workspace, command string) string {
+	store, err := snapshot.NewStore(cfg.Dir)
+	if err == nil {
+		var snap *snapshot.Snapshot
+		if snap, err = store.Create(workspace, command, int64(cfg.MaxSizeMB)<<20); err == nil {
+			if err := store.Prune(snap.Workspace, cfg.Keep); err != nil {
+				logger.Warn("snapshot prune failed", map[string]any{"error": err.Error()})
+			}
+			fmt.Fprintf(os.Stderr, "📸 Workspace snapshot %s (%s, %d files) - undo with: vectra-guard undo %s\n", snap.ID, snap.Kind, snap.Files, snap.ID)
+			logger.Info("workspace snapshot taken", map[string]any{
+				"snapshot":  snap.ID,
+				"kind":      snap.Kind,
+				"workspace": snap.Workspace,
+				"command":   command,
+			})
+			return snap.ID
+		}
+	}
+	logger.Warn("workspace snapshot failed", map[string]any{
+		"workspace": workspace,
+		"command":   command,
+		"error":     err.Error(),
+	})
+	return ""
+}
+
+// runUndo restores the snapshot taken before a command. target is a command
+// ID from a session, a snapshot ID, or empty for the workspace's latest snapshot.
+func runUndo(ctx context.Context, target string) error {
+	logger := logging.FromContext(ctx)
+	cfg := config.FromContext(ctx)
+
+	store, err := snapshot.NewStore(cfg.Snapshots.Dir)
+	if err != nil {
+		return err
+	}
+	snap, err := resolveUndoTarget(logger, cfg, store, target)
+	if err != nil {
+		return err
+	}
+
+	// The undo itself can be undone
+	safety, err := store.Create(snap.Workspace, "vectra-guard undo "+snap.ID, int64(cfg.Snapshots.MaxSizeMB)<<20)
+	if err != nil && !errors.Is(err, snapshot.ErrTooLarge) {
+		return fmt.Errorf("snapshot current state before undo: %w", err)
+	}
+
+	result, err := store.Restore(snap)
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", snap.ID, err)
+	}
+
+	fmt.Printf("Restored %s to snapshot %s taken %s before: %s\n",
+		snap.Workspace, snap.ID, snap.CreatedAt.Format("2006-01-02 15:04:05"), snap.Command)
+	fmt.Printf("  %d file(s) restored, %d file(s) created since were removed\n", result.Restored, result.Removed)
+	if result.Head != "" {
+		fmt.Printf("  ⚠️  HEAD moved since the snapshot (was %.12s, now %.12s); run `git reset --soft %.12s` to move it back\n",
+			snap.Head, result.Head, snap.Head)
+	}
+	if safety != nil {
+		fmt.Printf("  Previous state saved as %s (vectra-guard undo %s)\n", safety.ID, safety.ID)
+	}
+
+	logger.Info("workspace restored", map[string]any{
+		"snapshot":  snap.ID,
+		"workspace": snap.Workspace,
+		"restored":  result.Restored,
+		"removed":   result.Removed,
+	})
+	return nil
+}
+
+func resolveUndoTarget(logger *logging.Logger, cfg config.Config, store *snapshot.Store, target string) (*snapshot.Snapshot, error) {
+	switch {
+	case target == "":
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("get working directory: %w", err)
+		}
+		snaps, err := store.List(workspaceRoot(cfg, cwd))
+		if err != nil {
+			return nil, err
+		}
+		if len(snaps) == 0 {
+			return nil, fmt.Errorf("no snapshots for this workspace")
+		}
+		return snaps[0], nil
+	case strings.HasPrefix(target, "snap-"):
+		return store.Load(target)
+	}
+
+	cwd, _ := os.Getwd()
+	mgr, err := session.NewManager(cwd, logger)
+	if err != nil {
+		return nil, fmt.Errorf("create session manager: %w", err)
+	}
+	_, cmd, err := mgr.FindCommand(target)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.SnapshotID == "" {
+		return nil, fmt.Errorf("command %s (%s) ran without a snapshot", target, cmd.Command)
+	}
+	return store.Load(cmd.SnapshotID)
+}
+
+// runUndoList lists the snapshots of the current workspace.
+func runUndoList(ctx context.Context) error {
+	cfg := config.FromContext(ctx)
+	store, err := snapshot.NewStore(cfg.Snapshots.Dir)
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get working directory: %w", err)
+	}
+	snaps, err := store.List(workspaceRoot(cfg, cwd))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SNAPSHOT\tKIND\tTAKEN\tFILES\tBEFORE")
+	for _, snap := range snaps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", snap.ID, snap.Kind, snap.CreatedAt.Format("2006-01-02 15:04:05"), snap.Files, snap.Command)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+func TestShouldSnapshot(t *testing.T) {
+	cases := []struct {
+		cfg  config.SnapshotConfig
+		risk string
+		want bool
+	}{
+		{config.SnapshotConfig{Enabled: false, MinRisk: "low"}, "critical", false},
+		{config.SnapshotConfig{Enabled: true, MinRisk: "high"}, "medium", false},
+		{config.SnapshotConfig{Enabled: true, MinRisk: "high"}, "high", true},
+		{config.SnapshotConfig{Enabled: true, MinRisk: "High"}, "critical", true},
+		{config.SnapshotConfig{Enabled: true, MinRisk: "low"}, "low", true},
+		{config.SnapshotConfig{Enabled: true, MinRisk: "bogus"}, "medium", false},
+	}
+	for _, tc := range cases {
+		if got := shouldSnapshot(tc.cfg, tc.risk); got != tc.want {
+			t.Errorf("shouldSnapshot(%+v, %q) = %v, want %v", tc.cfg, tc.risk, got, tc.want)
+		}
+	}
+}
@@ -20,7 +20,7 @@ func runValidate(ctx context.Context, scriptPath string) error {
 		return fmt.Errorf("read script: %w", err)
 	}
 
-	findings := analyzer.AnalyzeScript(scriptPath, content, cfg.Policies)
+	findings := analyzer.AnalyzeScriptWithOptions(scriptPath, content, cfg.Policies, analyzer.OptionsFromConfig(cfg))
 	if len(findings) == 0 {
 		logger.Info("script validated successfully", map[string]any{"path": scriptPath})
 		return nil
@@ -18,10 +18,58 @@ type Finding struct {
 	Recommendation string `json:"recommendation"`
 }
 
+// Options carries configuration outside the policies section that detectors use.
+type Options struct {
+	ProductionIndicators config.ProductionIndicatorsConfig
+	// Workspace is the project root used to grade destructive paths; defaults to Context.WorkingDir
+	Workspace string
+	// Context is the resolved execution target (kube context, AWS profile, ...)
+	Context config.DetectionContext
+	// ProtectedPaths are globs commands may not delete, write or read
+	ProtectedPaths []config.ProtectedPath
+}
+
+// OptionsFromConfig builds analyzer options from the loaded configuration.
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{ProductionIndicators: cfg.ProductionIndicators, ProtectedPaths: cfg.ProtectedPaths}
+}
+
+// productionKeywords returns the configured production keywords, or the defaults.
+func (o Options) productionKeywords(policy config.PolicyConfig) []string {
+	keywords := o.ProductionIndicators.Keywords
+	if len(keywords) == 0 {
+		keywords = config.DefaultConfig().ProductionIndicators.Keywords
+	}
+	if policy.DetectProdEnv {
+		keywords = append(append([]string{}, keywords...), policy.ProdEnvPatterns...)
+	}
+	return keywords
+}
+
+// productionBranches returns the configured production branches, or the defaults.
+func (o Options) productionBranches() []string {
+	if len(o.ProductionIndicators.Branches) > 0 {
+		return o.ProductionIndicators.Branches
+	}
+	return config.DefaultConfig().ProductionIndicators.Branches
+}
+
 // AnalyzeScript scans the script content and returns findings sorted by line number.
 func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Finding {
+	return analyzeScript(path, content, policy, Options{}, 0)
+}
+
+// AnalyzeScriptWithOptions is AnalyzeScript with production indicators and
+// other non-policy configuration applied.
+func AnalyzeScriptWithOptions(path string, content []byte, policy config.PolicyConfig, opts Options) []Finding {
+	return analyzeScript(path, content, policy, opts, 0)
+}
+
+// analyzeScript implements AnalyzeScript; depth counts nested re-analysis of decoded payloads.
+func analyzeScript(path string, content []byte, policy config.PolicyConfig, opts Options, depth int) []Finding {
 	var findings []Finding
 	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	lines := strings.Split(string(content), "\n")
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -37,6 +85,10 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 		}
 
 		lower := strings.ToLower(trimmed)
+		lineStart := len(findings)
+
+		// Homoglyphs, zero-width and bidi characters make the displayed command differ from what runs
+		findings = append(findings, detectUnicodeTricks(trimmed, lineNum)...)
 		
 		// Smart Python command parsing: Extract shell commands from Python code
 		if isPythonCommand(trimmed) {
@@ -45,7 +97,7 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 				extractedCommands := extractPythonCommands(pythonCode)
 				for _, extractedCmd := range extractedCommands {
 					// Recursively analyze extracted commands
-					extractedFindings := analyzeExtractedCommand(extractedCmd, lineNum, policy)
+					extractedFindings := analyzeExtractedCommand(extractedCmd, lineNum, policy, opts)
 					findings = append(findings, extractedFindings...)
 				}
 			}
@@ -62,9 +114,15 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 			continue
 		}
 
+		// Recursive deletes graded by where the canonical target path lands
+		pathFindings, pathsHandled := detectPathDeletes(trimmed, lineNum, opts)
+		findings = append(findings, pathFindings...)
+		findings = append(findings, detectProtectedPaths(trimmed, lineNum, opts)...)
+
 		// Enhanced destructive file operations detection
 		// Check for ANY rm command targeting root or system directories
-		if strings.Contains(lower, "rm ") {
+		// (fallback when targets can't be resolved, e.g. rm -rf "$DIR"/*)
+		if !pathsHandled && strings.Contains(lower, "rm ") {
 			homeDeleteFound := false
 
 			// IMPORTANT: Check home directory deletion patterns FIRST (before root patterns)
@@ -157,7 +215,7 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 		}
 
 		// Destructive find operations (find / -delete)
-		if strings.Contains(lower, "find /") && strings.Contains(lower, "-delete") {
+		if !pathsHandled && strings.Contains(lower, "find /") && strings.Contains(lower, "-delete") {
 			findings = append(findings, Finding{
 				Severity:       "critical",
 				Code:           "DANGEROUS_DELETE_ROOT",
@@ -166,7 +224,7 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 				Recommendation: "BLOCKED: This command would destroy the system. Never use find / with -delete.",
 			})
 		}
-		if strings.Contains(lower, "find /") && (strings.Contains(lower, "-type f -delete") || strings.Contains(lower, "-type d -delete")) {
+		if !pathsHandled && strings.Contains(lower, "find /") && (strings.Contains(lower, "-type f -delete") || strings.Contains(lower, "-type d -delete")) {
 			findings = append(findings, Finding{
 				Severity:       "critical",
 				Code:           "DANGEROUS_DELETE_ROOT",
@@ -255,6 +313,9 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 			}
 		}
 
+		// Container escapes: privileged containers, host namespaces, host root or socket mounts
+		findings = append(findings, detectContainerEscape(trimmed, lineNum)...)
+
 		// Destructive Kubernetes operations
 		if strings.Contains(lower, "kubectl delete") &&
 			(strings.Contains(lower, "--all") || strings.Contains(lower, "namespace") ||
@@ -414,61 +475,15 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 			})
 		}
 
-		// Git operations monitoring
-		if policy.MonitorGitOps {
-			gitRiskyOps := map[string]struct {
-				severity string
-				desc     string
-				rec      string
-			}{
-				"git push --force":    {"high", "Force push detected - can overwrite remote history", "Use --force-with-lease instead or coordinate with team before force pushing."},
-				"git push -f":         {"high", "Force push detected - can overwrite remote history", "Use --force-with-lease instead or coordinate with team before force pushing."},
-				"git reset --hard":    {"medium", "Hard reset detected - will discard local changes", "Ensure you have backups or stash important changes first."},
-				"git clean -fd":       {"medium", "Git clean with force - will delete untracked files", "Review untracked files before cleaning. Consider using -n flag first for dry run."},
-				"git branch -D":       {"medium", "Force branch deletion detected", "Ensure branch is merged or no longer needed before force deleting."},
-				"git branch -d":       {"low", "Branch deletion detected", "Verify branch is fully merged before deletion."},
-				"git rebase":          {"low", "Git rebase detected - will rewrite commit history", "Only rebase local commits. Never rebase published commits."},
-				"git filter-branch":   {"high", "Git filter-branch - rewrites entire repository history", "Extremely dangerous. Coordinate with entire team and backup repository first."},
-				"git filter-repo":     {"high", "Git filter-repo - rewrites entire repository history", "Extremely dangerous. Coordinate with entire team and backup repository first."},
-				"git reflog expire":   {"high", "Reflog expiration - will permanently delete commit references", "Only use if you know what you're doing. Lost commits cannot be recovered."},
-				"git gc --aggressive": {"medium", "Aggressive garbage collection - may make recovery difficult", "Ensure no important dangling commits exist before running."},
-				"git update-ref -d":   {"high", "Direct ref manipulation detected", "Advanced operation. Ensure you understand git internals before proceeding."},
-			}
+		// Persistence and privilege escalation (cron, systemd, rc files, keys, setuid, sudoers, ...)
+		findings = append(findings, detectPersistence(trimmed, lineNum)...)
 
-			for pattern, info := range gitRiskyOps {
-				if strings.Contains(lower, pattern) {
-					severity := info.severity
-
-					// Elevate severity if production environment detected
-					if policy.DetectProdEnv {
-						for _, env := range policy.ProdEnvPatterns {
-							if strings.Contains(lower, env) {
-								if severity == "medium" {
-									severity = "high"
-								} else if severity == "high" {
-									severity = "critical"
-								}
-								info.desc += " in " + strings.ToUpper(env) + " environment"
-								break
-							}
-						}
-					}
+		// Data leaving the machine (uploads, scp/rsync, raw sockets, DNS)
+		findings = append(findings, detectExfiltration(trimmed, lineNum)...)
 
-					// Block force operations if configured
-					if policy.BlockForceGit && (strings.Contains(pattern, "--force") || strings.Contains(pattern, "-f")) {
-						severity = "critical"
-					}
-
-					findings = append(findings, Finding{
-						Severity:       severity,
-						Code:           "RISKY_GIT_OPERATION",
-						Description:    info.desc,
-						Line:           lineNum,
-						Recommendation: info.rec,
-					})
-					break
-				}
-			}
+		// Git operations monitoring (remote/branch aware, inspects the working tree when known)
+		if policy.MonitorGitOps {
+			findings = append(findings, detectGitOperations(trimmed, lineNum, policy, opts)...)
 		}
 
 		// SQL/NoSQL database command detection (refined to only flag destructive operations)
@@ -540,6 +555,12 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 			}
 		}
 
+		// Statement-level SQL analysis of -c/-e/--eval, heredocs and -f files
+		findings = append(findings, detectDestructiveSQL(trimmed, lineNum, lines[lineNum:], opts.productionKeywords(policy))...)
+
+		// Destructive HTTP API requests and inline credentials (curl, wget, httpie)
+		findings = append(findings, detectHTTPAPICalls(trimmed, lineNum, opts.productionKeywords(policy))...)
+
 		// Production/Staging environment warnings (general)
 		if policy.DetectProdEnv {
 			envPatterns := policy.ProdEnvPatterns
@@ -638,6 +659,13 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 				Recommendation: "BLOCK this operation. Provide sanitized config instead of exposing raw .env files.",
 			})
 		}
+
+		// Escalate cluster/account operations whose resolved target is production
+		findings = append(findings, applyCloudContext(trimmed, lineNum, opts.Context, opts.productionKeywords(policy), findings[lineStart:])...)
+
+		// De-obfuscation pass: decode encoded payloads and quoting tricks, then
+		// re-analyze what would actually run
+		findings = append(findings, analyzeObfuscation(trimmed, lineNum, policy, opts, depth, findings[lineStart:])...)
 	}
 
 	// Incorporate file extension heuristics if script extension implies something unexpected.
@@ -655,11 +683,11 @@ func AnalyzeScript(path string, content []byte, policy config.PolicyConfig) []Fi
 }
 
 // analyzeExtractedCommand analyzes a command extracted from Python code
-func analyzeExtractedCommand(cmd string, lineNum int, policy config.PolicyConfig) []Finding {
+func analyzeExtractedCommand(cmd string, lineNum int, policy config.PolicyConfig, opts Options) []Finding {
 	// Create a single-line "script" for analysis
 	content := []byte(cmd)
 	// Use a special path to indicate this is an extracted command
-	extractedFindings := AnalyzeScript("extracted-from-python", content, policy)
+	extractedFindings := AnalyzeScriptWithOptions("extracted-from-python", content, policy, opts)
 	
 	// Update line numbers to point to the original Python command
 	for i := range extractedFindings {
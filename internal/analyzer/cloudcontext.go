@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+// cloudOperation is a kubectl/helm/aws/gcloud/terraform invocation and the
+// target it resolves to.
+type cloudOperation struct {
+	tool        string
+	verb        string
+	destructive bool
+	target      config.DetectionContext // only the fields for this tool are set
+	codes       []string                // existing finding codes this operation escalates
+}
+
+var kubeGlobalValueFlags = []string{"-n", "--namespace", "--context", "--kube-context", "--kubeconfig", "--cluster", "--user", "-s", "--server", "-l", "--selector", "-o", "--output", "-f", "--filename"}
+
+// applyCloudContext escalates cluster- and account-level operations whose
+// resolved target (kube context/namespace, AWS profile, gcloud configuration
+// or project, terraform workspace) is production. Findings already reported
+// on the line for the same tool are raised to critical and name the target;
+// otherwise a PRODUCTION_CLOUD_TARGET finding is added.
+func applyCloudContext(line string, lineNum int, base config.DetectionContext, keywords []string, lineFindings []Finding) []Finding {
+	var findings []Finding
+	for _, cmd := range parseShellCommands(line) {
+		op, ok := parseCloudOperation(cmd, base)
+		if !ok {
+			continue
+		}
+		prod := op.target.ProductionTarget(keywords)
+		if prod == "" {
+			continue
+		}
+		targets := strings.Join(op.target.CloudTargets(), "; ")
+
+		escalated := false
+		for i := range lineFindings {
+			for _, code := range op.codes {
+				if lineFindings[i].Code == code {
+					lineFindings[i].Severity = "critical"
+					lineFindings[i].Description += " (target: " + targets + ")"
+					escalated = true
+				}
+			}
+		}
+		if escalated {
+			continue
+		}
+
+		severity := "high"
+		if op.destructive {
+			severity = "critical"
+		}
+		findings = append(findings, Finding{
+			Severity:       severity,
+			Code:           "PRODUCTION_CLOUD_TARGET",
+			Description:    op.tool + " " + op.verb + " against production " + prod + " (target: " + targets + ")",
+			Line:           lineNum,
+			Recommendation: "REQUIRE HUMAN APPROVAL. Verify the active context/profile/workspace before changing production resources.",
+		})
+	}
+	return findings
+}
+
+// parseCloudOperation recognizes mutating cloud CLI commands and resolves
+// their target from command flags, falling back to the base context.
+func parseCloudOperation(cmd shellCommand, base config.DetectionContext) (cloudOperation, bool) {
+	name, args := cmd.program()
+	op := cloudOperation{tool: name}
+
+	switch name {
+	case "kubectl", "oc":
+		operands := operandsSkipping(args, kubeGlobalValueFlags...)
+		if len(operands) == 0 {
+			return op, false
+		}
+		op.verb = operands[0]
+		switch op.verb {
+		case "delete", "drain":
+			op.destructive = true
+		case "apply", "create", "replace", "patch", "scale", "rollout", "edit", "set", "label", "annotate", "cordon", "taint", "autoscale", "expose":
+		default:
+			return op, false
+		}
+		op.target = kubeTarget(args, base)
+		op.codes = []string{"DESTRUCTIVE_K8S_OP"}
+	case "helm":
+		operands := operandsSkipping(args, kubeGlobalValueFlags...)
+		if len(operands) == 0 {
+			return op, false
+		}
+		op.verb = operands[0]
+		switch op.verb {
+		case "uninstall", "delete", "rollback":
+			op.destructive = true
+		case "install", "upgrade":
+		default:
+			return op, false
+		}
+		op.target = kubeTarget(args, base)
+		op.codes = []string{"DESTRUCTIVE_INFRA"}
+	case "aws":
+		operands := operandsSkipping(args, "--profile", "--region", "--output", "--query", "--endpoint-url")
+		if len(operands) < 2 {
+			return op, false
+		}
+		op.verb = operands[0] + " " + operands[1]
+		switch action := operands[1]; {
+		case operands[0] == "s3" && (action == "rm" || action == "rb"),
+			hasAnyPrefix(action, "delete", "terminate", "remove", "deregister", "destroy", "purge"):
+			op.destructive = true
+		case operands[0] == "s3" && (action == "cp" || action == "mv" || action == "sync"),
+			hasAnyPrefix(action, "create", "put", "update", "modify", "run", "start", "stop", "attach", "detach", "reboot", "deploy"):
+		default:
+			return op, false
+		}
+		op.target.AWSProfile = firstFlagOr(args, base.AWSProfile, "--profile")
+		op.codes = []string{"DESTRUCTIVE_CLOUD_STORAGE"}
+	case "gcloud", "gsutil":
+		operands := operandsSkipping(args, "--project", "--configuration", "--zone", "--region", "--format")
+		if len(operands) == 0 {
+			return op, false
+		}
+		destructive := name == "gsutil" && (operands[0] == "rm" || operands[0] == "rb")
+		mutating := false
+		for _, operand := range operands {
+			switch operand {
+			case "delete", "remove", "reset":
+				destructive = true
+			case "create", "update", "deploy", "set", "patch", "resize", "add-iam-policy-binding":
+				mutating = true
+			}
+		}
+		if !destructive && !mutating {
+			return op, false
+		}
+		op.destructive = destructive
+		op.verb = operands[0]
+		op.target.GCloudConfig = firstFlagOr(args, base.GCloudConfig, "--configuration")
+		op.target.GCloudProject = firstFlagOr(args, base.GCloudProject, "--project")
+		op.codes = []string{"DESTRUCTIVE_CLOUD_STORAGE"}
+	case "terraform", "tofu", "terragrunt":
+		operands := nonFlagArgs(args)
+		if len(operands) == 0 {
+			return op, false
+		}
+		op.verb = operands[0]
+		switch {
+		case op.verb == "destroy", op.verb == "state" && len(operands) > 1 && operands[1] == "rm":
+			op.destructive = true
+		case op.verb == "apply", op.verb == "import", op.verb == "taint":
+		default:
+			return op, false
+		}
+		op.target.TerraformWorkspace = base.TerraformWorkspace
+		op.codes = []string{"DESTRUCTIVE_INFRA"}
+	default:
+		return op, false
+	}
+	return op, true
+}
+
+func kubeTarget(args []string, base config.DetectionContext) config.DetectionContext {
+	return config.DetectionContext{
+		KubeContext:   firstFlagOr(args, base.KubeContext, "--context", "--kube-context"),
+		KubeNamespace: firstFlagOr(args, base.KubeNamespace, "-n", "--namespace"),
+	}
+}
+
+func firstFlagOr(args []string, fallback string, flags ...string) string {
+	if v, ok := flagValue(args, flags...); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
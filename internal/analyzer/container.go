@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"path"
+	"strings"
+)
+
+// containerBoolFlags are docker/podman run flags that take no value; any
+// other flag without `=value` consumes the next word.
+var containerBoolFlags = map[string]bool{
+	"--rm": true, "--privileged": true, "-d": true, "--detach": true, "-i": true, "--interactive": true,
+	"-t": true, "--tty": true, "--init": true, "--read-only": true, "-P": true, "--publish-all": true,
+	"--no-healthcheck": true, "--oom-kill-disable": true, "-q": true, "--quiet": true,
+}
+
+// Host paths that hand a container control of the host when bind-mounted
+var (
+	containerSocketPaths = []string{"docker.sock", "podman.sock", "containerd.sock", "crio.sock"}
+	sensitiveHostMounts  = []string{"/etc", "/root", "/home", "/proc", "/sys", "/dev", "/boot", "/var/run", "/run", "/var/lib/docker", "/lib/modules"}
+	hostRootMounts       = []string{"/host", "/hostfs", "/rootfs", "/mnt/host", "/proc/1/root"}
+)
+
+// containerEscape collects the host-access reasons found on one line.
+type containerEscape struct {
+	reasons  []string
+	severity string
+}
+
+func (e *containerEscape) add(severity, reason string) {
+	e.reasons = appendUnique(e.reasons, reason)
+	if severityRank(severity) > severityRank(e.severity) {
+		e.severity = severity
+	}
+}
+
+// detectContainerEscape reports commands that give a container (or the agent)
+// the host: privileged containers, host namespaces, host root or runtime
+// socket mounts, dangerous capabilities, nsenter into PID 1, chroot into a
+// mounted host root, and kubectl privileged/hostPath overrides.
+func detectContainerEscape(line string, lineNum int) []Finding {
+	escape := &containerEscape{}
+
+	for _, cmd := range parseShellCommands(line) {
+		name, args := cmd.program()
+		switch name {
+		case "docker", "podman", "nerdctl":
+			operands := nonFlagArgs(args)
+			if len(operands) == 0 {
+				continue
+			}
+			switch operands[0] {
+			case "run", "create", "exec":
+				checkContainerRunFlags(escape, containerFlags(args[indexOf(args, operands[0])+1:]))
+			case "container":
+				if len(operands) > 1 && (operands[1] == "run" || operands[1] == "create") {
+					checkContainerRunFlags(escape, containerFlags(args[indexOf(args, operands[1])+1:]))
+				}
+			}
+		case "kubectl", "oc":
+			checkKubectl(escape, args)
+		case "nsenter":
+			target, _ := flagValue(args, "-t", "--target")
+			if target == "1" {
+				escape.add("critical", "nsenter into host PID 1")
+			}
+			for _, arg := range args {
+				if strings.Contains(arg, "/proc/1/ns/") {
+					escape.add("critical", "nsenter into PID 1 namespaces")
+					break
+				}
+			}
+		case "chroot":
+			if operands := nonFlagArgs(args); len(operands) > 0 && isHostRootMount(operands[0]) {
+				escape.add("critical", "chroot into host root "+operands[0])
+			}
+		}
+
+		for _, arg := range cmd.Args {
+			if strings.Contains(arg, "/proc/1/root") {
+				escape.add("critical", "access to host filesystem via /proc/1/root")
+				break
+			}
+		}
+	}
+
+	if len(escape.reasons) == 0 {
+		return nil
+	}
+
+	rec := "Avoid sharing host namespaces, devices or sensitive paths with containers; require approval."
+	if escape.severity == "critical" {
+		rec = "BLOCK this command. It gives the container full control of the host. Run containers unprivileged with only the mounts they need."
+	}
+	return []Finding{{
+		Severity:       escape.severity,
+		Code:           "CONTAINER_ESCAPE",
+		Description:    "Container host access: " + strings.Join(escape.reasons, ", "),
+		Line:           lineNum,
+		Recommendation: rec,
+	}}
+}
+
+// containerFlag is one option before the image name of docker/podman run.
+type containerFlag struct {
+	name  string
+	value string
+}
+
+// containerFlags returns the options preceding the image name.
+func containerFlags(args []string) []containerFlag {
+	var flags []containerFlag
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "--" {
+			break
+		}
+		if eq := strings.Index(arg, "="); eq > 0 {
+			flags = append(flags, containerFlag{arg[:eq], arg[eq+1:]})
+			continue
+		}
+		if containerBoolFlags[arg] || isBundledBoolFlag(arg) {
+			flags = append(flags, containerFlag{name: arg})
+			continue
+		}
+		if i+1 < len(args) {
+			flags = append(flags, containerFlag{arg, args[i+1]})
+			i++
+		}
+	}
+	return flags
+}
+
+func checkContainerRunFlags(escape *containerEscape, flags []containerFlag) {
+	for _, f := range flags {
+		value := strings.ToLower(f.value)
+		switch f.name {
+		case "--privileged":
+			if value == "" || value == "true" {
+				escape.add("critical", "--privileged")
+			}
+		case "--pid":
+			if value == "host" {
+				escape.add("critical", "host PID namespace")
+			}
+		case "--net", "--network":
+			if value == "host" {
+				escape.add("high", "host network")
+			}
+		case "--ipc", "--uts", "--userns", "--cgroupns":
+			if value == "host" {
+				escape.add("high", "host "+strings.TrimPrefix(f.name, "--")+" namespace")
+			}
+		case "--cap-add":
+			for _, capability := range strings.Split(strings.ToUpper(f.value), ",") {
+				capability = strings.TrimPrefix(capability, "CAP_")
+				switch capability {
+				case "ALL", "SYS_ADMIN", "SYS_MODULE", "SYS_RAWIO":
+					escape.add("critical", "--cap-add="+capability)
+				case "SYS_PTRACE", "DAC_READ_SEARCH", "NET_ADMIN", "SYS_CHROOT":
+					escape.add("high", "--cap-add="+capability)
+				}
+			}
+		case "--security-opt":
+			if strings.Contains(value, "unconfined") || value == "label=disable" || value == "label:disable" {
+				escape.add("high", "--security-opt "+f.value)
+			}
+		case "--device":
+			escape.add("high", "host device "+f.value)
+		case "-v", "--volume":
+			checkHostMount(escape, strings.SplitN(f.value, ":", 2)[0])
+		case "--mount":
+			for _, part := range strings.Split(f.value, ",") {
+				if kv := strings.SplitN(part, "=", 2); len(kv) == 2 && (kv[0] == "source" || kv[0] == "src") {
+					checkHostMount(escape, kv[1])
+				}
+			}
+		}
+	}
+}
+
+func checkHostMount(escape *containerEscape, source string) {
+	if source == "" || !strings.HasPrefix(source, "/") {
+		// named volumes and relative paths
+		return
+	}
+	cleaned := path.Clean(source)
+	for _, socket := range containerSocketPaths {
+		if strings.HasSuffix(cleaned, socket) {
+			escape.add("critical", "container runtime socket mount "+source)
+			return
+		}
+	}
+	if cleaned == "/" {
+		escape.add("critical", "host root filesystem mount")
+		return
+	}
+	for _, sensitive := range sensitiveHostMounts {
+		if cleaned == sensitive || strings.HasPrefix(cleaned, sensitive+"/") {
+			escape.add("high", "sensitive host path mount "+source)
+			return
+		}
+	}
+}
+
+func checkKubectl(escape *containerEscape, args []string) {
+	operands := operandsSkipping(args, "-n", "--namespace", "--context", "--kubeconfig", "--cluster", "--user",
+		"-s", "--server", "--image", "-c", "--container", "--profile", "--target")
+	if len(operands) == 0 {
+		return
+	}
+	switch operands[0] {
+	case "debug":
+		for _, operand := range operands[1:] {
+			if strings.HasPrefix(operand, "node/") || strings.HasPrefix(operand, "nodes/") {
+				escape.add("critical", "kubectl debug on node (host filesystem at /host)")
+			}
+		}
+		if profile, ok := flagValue(args, "--profile"); ok && profile == "sysadmin" {
+			escape.add("high", "kubectl debug --profile=sysadmin")
+		}
+	case "run", "patch", "set", "create":
+		for _, arg := range args {
+			if arg == "--privileged" || arg == "--privileged=true" {
+				escape.add("critical", "privileged pod")
+			}
+		}
+		for _, flag := range []string{"--overrides", "-p", "--patch"} {
+			if v, ok := flagValue(args, flag); ok {
+				checkPodSpec(escape, v)
+			}
+		}
+	}
+}
+
+// checkPodSpec looks for privileged/host settings in an inline JSON pod spec or patch
+func checkPodSpec(escape *containerEscape, spec string) {
+	compact := strings.ToLower(strings.Join(strings.Fields(spec), ""))
+	if strings.Contains(compact, `"privileged":true`) {
+		escape.add("critical", "privileged pod override")
+	}
+	if strings.Contains(compact, `"hostpid":true`) {
+		escape.add("critical", "hostPID pod override")
+	}
+	if strings.Contains(compact, `"hostpath"`) {
+		escape.add("critical", "hostPath volume override")
+	}
+	if strings.Contains(compact, `"hostnetwork":true`) {
+		escape.add("high", "hostNetwork pod override")
+	}
+	if strings.Contains(compact, `"hostipc":true`) {
+		escape.add("high", "hostIPC pod override")
+	}
+}
+
+func isHostRootMount(dir string) bool {
+	cleaned := path.Clean(dir)
+	for _, root := range hostRootMounts {
+		if cleaned == root {
+			return true
+		}
+	}
+	return false
+}
+
+// isBundledBoolFlag matches short flag bundles like -it or -dit
+func isBundledBoolFlag(arg string) bool {
+	if len(arg) < 3 || strings.HasPrefix(arg, "--") {
+		return false
+	}
+	return strings.Trim(arg[1:], "ditP") == ""
+}
+
+func indexOf(args []string, value string) int {
+	for i, arg := range args {
+		if arg == value {
+			return i
+		}
+	}
+	return -1
+}
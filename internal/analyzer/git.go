@@ -0,0 +1,452 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+// gitGlobalValueFlags take the next word as their value (git -C dir, -c key=value)
+var gitGlobalValueFlags = map[string]bool{"-C": true, "-c": true, "--git-dir": true, "--work-tree": true, "--namespace": true}
+
+// gitOperation is a risky git command and how bad it is.
+type gitOperation struct {
+	severity string
+	desc     string
+	rec      string
+	branch   string // branch affected, used for production_indicators.branches
+	prodSev  string // severity when branch is a production branch ("" escalates one level)
+	force    bool   // subject to policies.block_force_git
+}
+
+// gitRepo runs read-only git queries in the directory a command will run in.
+// With no directory (static script analysis) every query reports ok=false.
+type gitRepo struct {
+	dir string
+}
+
+// detectGitOperations parses git commands on a line and reports
+// RISKY_GIT_OPERATION with the remote and branch they affect. When the
+// working directory is known, the repository is inspected for uncommitted
+// changes, untracked files and unmerged branches. Operations on a branch in
+// production_indicators.branches are escalated.
+func detectGitOperations(line string, lineNum int, policy config.PolicyConfig, opts Options) []Finding {
+	var findings []Finding
+	lower := strings.ToLower(line)
+	for _, cmd := range parseShellCommands(line) {
+		name, args := cmd.program()
+		if name != "git" {
+			continue
+		}
+		sub, subArgs, chdir := splitGitArgs(args)
+		if sub == "" {
+			continue
+		}
+
+		repo := gitRepo{dir: opts.Context.WorkingDir}
+		if repo.dir != "" && chdir != "" {
+			if filepath.IsAbs(chdir) {
+				repo.dir = chdir
+			} else {
+				repo.dir = filepath.Join(repo.dir, chdir)
+			}
+		}
+
+		prodBranches := opts.productionBranches()
+		op, ok := analyzeGitCommand(sub, subArgs, repo, prodBranches)
+		if !ok {
+			continue
+		}
+
+		if op.branch != "" && isProductionBranch(op.branch, prodBranches) {
+			if op.prodSev != "" {
+				op.severity = op.prodSev
+			} else {
+				op.severity = escalateSeverity(op.severity)
+			}
+			op.desc += " on production branch " + op.branch
+		} else if policy.DetectProdEnv {
+			for _, env := range policy.ProdEnvPatterns {
+				if strings.Contains(lower, env) {
+					op.severity = escalateSeverity(op.severity)
+					op.desc += " in " + strings.ToUpper(env) + " environment"
+					break
+				}
+			}
+		}
+
+		// Block force operations if configured
+		if policy.BlockForceGit && op.force {
+			op.severity = "critical"
+		}
+
+		findings = append(findings, Finding{
+			Severity:       op.severity,
+			Code:           "RISKY_GIT_OPERATION",
+			Description:    op.desc,
+			Line:           lineNum,
+			Recommendation: op.rec,
+		})
+	}
+	return findings
+}
+
+// splitGitArgs skips git's global options and returns the subcommand, its
+// arguments and the -C directory.
+func splitGitArgs(args []string) (sub string, subArgs []string, chdir string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return arg, args[i+1:], chdir
+		}
+		if gitGlobalValueFlags[arg] && i+1 < len(args) {
+			if arg == "-C" {
+				chdir = args[i+1]
+			}
+			i++
+		}
+	}
+	return "", nil, chdir
+}
+
+func analyzeGitCommand(sub string, args []string, repo gitRepo, prodBranches []string) (gitOperation, bool) {
+	switch sub {
+	case "push":
+		return analyzeGitPush(args, repo, prodBranches)
+	case "reset":
+		return analyzeGitReset(args, repo)
+	case "clean":
+		return analyzeGitClean(args, repo)
+	case "branch":
+		return analyzeGitBranch(args, repo, prodBranches)
+	case "filter-branch", "filter-repo":
+		return gitOperation{
+			severity: "high",
+			desc:     "Git " + sub + " - rewrites entire repository history",
+			rec:      "Extremely dangerous. Coordinate with entire team and backup repository first.",
+			branch:   repo.currentBranch(),
+			prodSev:  "critical",
+		}, true
+	case "commit", "merge", "am", "cherry-pick", "revert", "rebase":
+		if hasFlag(args, "--no-verify") || (sub == "commit" && hasFlag(args, "-n")) {
+			return gitOperation{
+				severity: "medium",
+				desc:     fmt.Sprintf("git %s --no-verify skips pre-commit and commit-msg hooks", sub),
+				rec:      "Fix what the hooks report instead of bypassing them.",
+				branch:   repo.currentBranch(),
+			}, true
+		}
+		if sub == "rebase" {
+			return gitOperation{
+				severity: "low",
+				desc:     "Git rebase detected - will rewrite commit history",
+				rec:      "Only rebase local commits. Never rebase published commits.",
+			}, true
+		}
+	case "reflog":
+		if len(args) > 0 && (args[0] == "expire" || args[0] == "delete") {
+			return gitOperation{
+				severity: "high",
+				desc:     "Reflog expiration - will permanently delete commit references",
+				rec:      "Only use if you know what you're doing. Lost commits cannot be recovered.",
+			}, true
+		}
+	case "gc":
+		if hasFlag(args, "--aggressive") || hasFlag(args, "--prune=now") {
+			return gitOperation{
+				severity: "medium",
+				desc:     "Aggressive garbage collection - may make recovery difficult",
+				rec:      "Ensure no important dangling commits exist before running.",
+			}, true
+		}
+	case "update-ref":
+		if hasFlag(args, "-d") {
+			return gitOperation{
+				severity: "high",
+				desc:     "Direct ref manipulation detected",
+				rec:      "Advanced operation. Ensure you understand git internals before proceeding.",
+			}, true
+		}
+	}
+	return gitOperation{}, false
+}
+
+// analyzeGitPush resolves the remote and branches of a push and reports force
+// pushes (--force, --force-with-lease, --mirror, +refspec), remote branch
+// deletions and --no-verify.
+func analyzeGitPush(args []string, repo gitRepo, prodBranches []string) (gitOperation, bool) {
+	operands := operandsSkipping(args, "-o", "--push-option", "--repo", "--receive-pack", "--exec")
+	remote := "origin"
+	if len(operands) > 0 {
+		remote = operands[0]
+	}
+
+	forced := hasFlag(args, "-f", "--force") || hasFlag(args, "--mirror")
+	lease := hasFlag(args, "--force-with-lease") || hasFlag(args, "--force-if-includes")
+	deleting := hasFlag(args, "-d", "--delete")
+
+	var branches []string
+	for _, spec := range operands[min(1, len(operands)):] {
+		if strings.HasPrefix(spec, "+") {
+			forced = true
+			spec = spec[1:]
+		}
+		if colon := strings.Index(spec, ":"); colon >= 0 {
+			if colon == 0 {
+				deleting = true
+			}
+			spec = spec[colon+1:]
+		}
+		branches = append(branches, strings.TrimPrefix(spec, "refs/heads/"))
+	}
+	if len(branches) == 0 {
+		if current := repo.currentBranch(); current != "" {
+			branches = []string{current}
+		}
+	}
+
+	target := remote
+	branch := ""
+	if len(branches) > 0 {
+		branch = branches[0]
+		for _, b := range branches {
+			if isProductionBranch(b, prodBranches) {
+				branch = b
+				break
+			}
+		}
+		target = remote + "/" + strings.Join(branches, ",")
+	}
+
+	switch {
+	case forced || lease:
+		op := gitOperation{
+			severity: "high",
+			desc:     "Force push to " + target + " - can overwrite remote history",
+			rec:      "Use --force-with-lease instead or coordinate with team before force pushing.",
+			branch:   branch,
+			prodSev:  "critical",
+			force:    true,
+		}
+		if lease && !forced {
+			op.severity = "medium"
+			op.desc = "Force push (with lease) to " + target + " - can overwrite remote history"
+			op.rec = "Confirm nobody else has pushed to the branch; the lease only protects refs you have fetched."
+		}
+		return op, true
+	case deleting:
+		return gitOperation{
+			severity: "high",
+			desc:     "Deleting remote branch " + target,
+			rec:      "Make sure the branch is merged or no longer needed; remote deletions are hard to undo.",
+			branch:   branch,
+			prodSev:  "critical",
+		}, true
+	case hasFlag(args, "--no-verify"):
+		return gitOperation{
+			severity: "medium",
+			desc:     "git push --no-verify to " + target + " skips pre-push hooks",
+			rec:      "Fix what the hooks report instead of bypassing them.",
+			branch:   branch,
+		}, true
+	}
+	return gitOperation{}, false
+}
+
+// analyzeGitReset reports `reset --hard`, counting the uncommitted changes it
+// would discard when the working tree can be inspected.
+func analyzeGitReset(args []string, repo gitRepo) (gitOperation, bool) {
+	if !hasFlag(args, "--hard") {
+		return gitOperation{}, false
+	}
+	op := gitOperation{
+		severity: "medium",
+		desc:     "Hard reset detected - will discard local changes",
+		rec:      "Ensure you have backups or stash important changes first.",
+		branch:   repo.currentBranch(),
+	}
+	if n, ok := repo.countLines("status", "--porcelain", "--untracked-files=no"); ok {
+		if n > 0 {
+			op.severity = "high"
+			op.desc = fmt.Sprintf("Hard reset discards uncommitted changes in %d file(s)", n)
+			op.rec = "Run `git stash` first, or commit the changes to a branch."
+		} else {
+			op.severity = "low"
+			op.desc = "Hard reset (working tree has no uncommitted changes)"
+		}
+	}
+	return op, true
+}
+
+// analyzeGitClean reports forced `git clean`, counting the files it would
+// remove when the working tree can be inspected.
+func analyzeGitClean(args []string, repo gitRepo) (gitOperation, bool) {
+	if !hasFlag(args, "-f", "--force") || hasFlag(args, "-n", "--dry-run") || hasFlag(args, "-i", "--interactive") {
+		return gitOperation{}, false
+	}
+	op := gitOperation{
+		severity: "medium",
+		desc:     "Git clean with force - will delete untracked files",
+		rec:      "Review untracked files before cleaning. Consider using -n flag first for dry run.",
+	}
+
+	if hasFlag(args, "-x") {
+		op.severity = "high"
+		op.desc = "Git clean -x with force - will delete untracked and ignored files (.env, local config, build caches)"
+	} else if hasFlag(args, "-X") {
+		op.desc = "Git clean -X with force - will delete ignored files (.env, local config, build caches)"
+	}
+
+	if n, ok := repo.countLines(gitCleanDryRun(args)...); ok {
+		if n == 0 {
+			op.severity = "low"
+		}
+		op.desc += fmt.Sprintf(" (%d path(s) would be removed)", n)
+	}
+	return op, true
+}
+
+// gitCleanDryRun turns `git clean` arguments into the equivalent `clean -n`.
+func gitCleanDryRun(args []string) []string {
+	dryRun := []string{"clean", "-n"}
+	for _, flag := range []string{"-d", "-x", "-X"} {
+		if hasFlag(args, flag) {
+			dryRun = append(dryRun, flag)
+		}
+	}
+	for _, pattern := range flagValues(args, "-e", "--exclude") {
+		dryRun = append(dryRun, "-e", pattern)
+	}
+	return append(append(dryRun, "--"), operandsSkipping(args, "-e", "--exclude")...)
+}
+
+// analyzeGitBranch reports branch deletion, checking whether force-deleted
+// branches are merged into HEAD.
+func analyzeGitBranch(args []string, repo gitRepo, prodBranches []string) (gitOperation, bool) {
+	forceDelete := hasFlag(args, "-D") || (hasFlag(args, "-d", "--delete") && hasFlag(args, "-f", "--force"))
+	if !forceDelete && !hasFlag(args, "-d", "--delete") {
+		return gitOperation{}, false
+	}
+	names := nonFlagArgs(args)
+
+	if !forceDelete || hasFlag(args, "-r", "--remotes") {
+		op := gitOperation{
+			severity: "low",
+			desc:     "Branch deletion detected",
+			rec:      "Verify branch is fully merged before deletion.",
+		}
+		if len(names) > 0 {
+			op.branch = names[0]
+			op.desc = "Branch deletion: " + strings.Join(names, ", ")
+		}
+		return op, true
+	}
+
+	op := gitOperation{
+		severity: "medium",
+		desc:     "Force branch deletion detected",
+		rec:      "Ensure branch is merged or no longer needed before force deleting.",
+		prodSev:  "critical",
+	}
+	var unmerged, merged []string
+	for _, name := range names {
+		if op.branch == "" || isProductionBranch(name, prodBranches) {
+			op.branch = name
+		}
+		isMerged, known := repo.isMerged(name)
+		switch {
+		case !known:
+		case isMerged:
+			merged = append(merged, name)
+		default:
+			unmerged = append(unmerged, name)
+		}
+	}
+	switch {
+	case len(unmerged) > 0:
+		op.severity = "high"
+		op.desc = "Force deleting unmerged branch " + strings.Join(unmerged, ", ") + " - its commits will only be reachable from the reflog"
+		op.rec = "Merge or push the branch first, or tag its tip before deleting."
+	case len(names) > 0 && len(merged) == len(names):
+		op.severity = "low"
+		op.desc = "Force deleting merged branch " + strings.Join(merged, ", ")
+	case len(names) > 0:
+		op.desc = "Force branch deletion: " + strings.Join(names, ", ")
+	}
+	return op, true
+}
+
+func (r gitRepo) run(args ...string) (string, int) {
+	if r.dir == "" {
+		return "", -1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", r.dir}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", exitErr.ExitCode()
+		}
+		return "", -1
+	}
+	return strings.TrimSpace(string(out)), 0
+}
+
+func (r gitRepo) currentBranch() string {
+	out, code := r.run("rev-parse", "--abbrev-ref", "HEAD")
+	if code != 0 || out == "HEAD" {
+		return ""
+	}
+	return out
+}
+
+func (r gitRepo) countLines(args ...string) (int, bool) {
+	out, code := r.run(args...)
+	if code != 0 {
+		return 0, false
+	}
+	if out == "" {
+		return 0, true
+	}
+	return strings.Count(out, "\n") + 1, true
+}
+
+// isMerged reports whether branch is an ancestor of HEAD; known is false when
+// the repository can't be inspected or the branch doesn't exist.
+func (r gitRepo) isMerged(branch string) (merged, known bool) {
+	_, code := r.run("merge-base", "--is-ancestor", "refs/heads/"+branch, "HEAD")
+	switch code {
+	case 0:
+		return true, true
+	case 1:
+		return false, true
+	}
+	return false, false
+}
+
+func isProductionBranch(branch string, branches []string) bool {
+	for _, prod := range branches {
+		if strings.EqualFold(branch, prod) {
+			return true
+		}
+	}
+	return false
+}
+
+// escalateSeverity raises medium to high and high to critical.
+func escalateSeverity(severity string) string {
+	switch severity {
+	case "medium":
+		return "high"
+	case "high":
+		return "critical"
+	}
+	return severity
+}
@@ -0,0 +1,231 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// httpRequest is what a curl/wget/httpie invocation sends.
+type httpRequest struct {
+	client  string
+	method  string
+	url     *url.URL
+	headers []string
+	user    string // -u / --user / -a value
+}
+
+// adminPathSegments mark API paths whose PUT/PATCH changes access or configuration
+var adminPathSegments = []string{"admin", "users", "roles", "permissions", "iam", "acl", "config", "settings", "policies", "members", "tokens", "keys"}
+
+// curlValueFlags take the next word as their value
+var curlValueFlags = []string{
+	"-X", "--request", "-H", "--header", "-d", "--data", "--data-binary", "--data-raw", "--data-urlencode", "--json",
+	"-F", "--form", "-T", "--upload-file", "-o", "--output", "-u", "--user", "-A", "--user-agent", "-e", "--referer",
+	"-b", "--cookie", "-c", "--cookie-jar", "-w", "--write-out", "-x", "--proxy", "--connect-timeout", "-m", "--max-time",
+	"--retry", "--cacert", "--cert", "--key", "-K", "--config", "--resolve",
+}
+
+var httpieMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true}
+
+// detectHTTPAPICalls parses curl, wget and httpie requests. DELETE and
+// PUT/PATCH to admin paths are reported as DESTRUCTIVE_API_CALL (critical or
+// high on production hosts), and literal bearer tokens, API keys and passwords
+// on the command line as INLINE_CREDENTIALS.
+func detectHTTPAPICalls(line string, lineNum int, prodKeywords []string) []Finding {
+	var findings []Finding
+	for _, cmd := range parseShellCommands(line) {
+		req, ok := parseHTTPRequest(cmd)
+		if !ok {
+			continue
+		}
+
+		if f, ok := destructiveAPIFinding(req, lineNum, prodKeywords); ok {
+			findings = append(findings, f)
+		}
+		if secret := inlineCredential(req); secret != "" {
+			findings = append(findings, Finding{
+				Severity:       "high",
+				Code:           "INLINE_CREDENTIALS",
+				Description:    fmt.Sprintf("%s passed inline to %s", secret, req.client),
+				Line:           lineNum,
+				Recommendation: "Read credentials from an environment variable or file (e.g. -H @headers.txt, --netrc) so they don't end up in shell history and process listings.",
+			})
+		}
+	}
+	return findings
+}
+
+func parseHTTPRequest(cmd shellCommand) (httpRequest, bool) {
+	name, args := cmd.program()
+	req := httpRequest{client: name, method: "GET"}
+	target := ""
+
+	switch name {
+	case "curl":
+		if operands := operandsSkipping(args, curlValueFlags...); len(operands) > 0 {
+			target = operands[0]
+		}
+		if len(flagValues(args, "-d", "--data", "--data-binary", "--data-raw", "--data-urlencode", "--json", "-F", "--form")) > 0 {
+			req.method = "POST"
+		}
+		if len(flagValues(args, "-T", "--upload-file")) > 0 {
+			req.method = "PUT"
+		}
+		if hasFlag(args, "-I", "--head") {
+			req.method = "HEAD"
+		}
+		if v, ok := flagValue(args, "-X", "--request"); ok {
+			req.method = strings.ToUpper(v)
+		}
+		req.headers = flagValues(args, "-H", "--header")
+		req.user, _ = flagValue(args, "-u", "--user")
+	case "wget":
+		target = firstURL(args)
+		if len(flagValues(args, "--post-data", "--post-file")) > 0 {
+			req.method = "POST"
+		}
+		if v, ok := flagValue(args, "--method"); ok {
+			req.method = strings.ToUpper(v)
+		}
+		req.headers = flagValues(args, "--header")
+		if user, ok := flagValue(args, "--user", "--http-user"); ok {
+			req.user = user
+			if password, ok := flagValue(args, "--password", "--http-password"); ok {
+				req.user += ":" + password
+			}
+		}
+	case "http", "https", "xh":
+		operands := operandsSkipping(args, "-a", "--auth", "-A", "--auth-type", "-o", "--output", "--session", "--verify", "--cert", "--cert-key")
+		explicitMethod := len(operands) > 0 && httpieMethods[operands[0]]
+		if explicitMethod {
+			req.method = operands[0]
+			operands = operands[1:]
+		}
+		if len(operands) == 0 {
+			return req, false
+		}
+		target = operands[0]
+		if !strings.Contains(target, "://") {
+			target = name + "://" + strings.TrimPrefix(target, ":")
+		}
+		for _, item := range operands[1:] {
+			// Header:value items; data items (=, :=, @) make the default method POST
+			if colon := strings.Index(item, ":"); colon > 0 && !strings.ContainsAny(item[:colon], "=@") &&
+				!strings.HasPrefix(item[colon:], ":=") {
+				req.headers = append(req.headers, item)
+			} else if !explicitMethod && strings.ContainsAny(item, "=@") {
+				req.method = "POST"
+			}
+		}
+		req.user, _ = flagValue(args, "-a", "--auth")
+	default:
+		return req, false
+	}
+
+	if target == "" {
+		return req, false
+	}
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return req, false
+	}
+	req.url = u
+	return req, true
+}
+
+func destructiveAPIFinding(req httpRequest, lineNum int, prodKeywords []string) (Finding, bool) {
+	host := req.url.Hostname()
+	if isLocalHost(host) {
+		return Finding{}, false
+	}
+
+	adminPath := isAdminPath(req.url.Path)
+	switch {
+	case req.method == "DELETE":
+	case (req.method == "PUT" || req.method == "PATCH") && adminPath:
+	default:
+		return Finding{}, false
+	}
+
+	severity := "medium"
+	desc := fmt.Sprintf("%s %s%s via %s", req.method, host, req.url.EscapedPath(), req.client)
+	rec := "Confirm the target resource and environment before sending destructive API requests."
+	if env := matchProductionKeyword(prodKeywords, host); env != "" {
+		severity = "high"
+		if req.method == "DELETE" {
+			severity = "critical"
+		}
+		desc += " against " + strings.ToUpper(env) + " host"
+		rec = "REQUIRE MANUAL APPROVAL for destructive requests to production APIs."
+	}
+
+	return Finding{
+		Severity:       severity,
+		Code:           "DESTRUCTIVE_API_CALL",
+		Description:    "Destructive HTTP request: " + desc,
+		Line:           lineNum,
+		Recommendation: rec,
+	}, true
+}
+
+// inlineCredential describes a literal secret on the command line ("" if none).
+// Values taken from variables or files ($TOKEN, @file) are not reported.
+func inlineCredential(req httpRequest) string {
+	for _, header := range req.headers {
+		colon := strings.Index(header, ":")
+		if colon < 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(header[:colon]))
+		value := strings.TrimSpace(header[colon+1:])
+		// the credential is the last word: "Bearer abc", "Basic $CREDS"
+		secret := value[strings.LastIndex(value, " ")+1:]
+		if secret == "" || isExpansionValue(secret) {
+			continue
+		}
+		switch {
+		case name == "authorization" && strings.HasPrefix(strings.ToLower(value), "bearer "):
+			return "Bearer token"
+		case name == "authorization":
+			return "Authorization header"
+		case name == "x-api-key" || name == "api-key" || name == "x-auth-token" || name == "private-token" || name == "cookie":
+			return "Credential header " + header[:colon]
+		}
+	}
+
+	if colon := strings.Index(req.user, ":"); colon >= 0 {
+		if password := req.user[colon+1:]; password != "" && !isExpansionValue(password) {
+			return "Password"
+		}
+	}
+	if req.url != nil {
+		if password, ok := req.url.User.Password(); ok && password != "" && !isExpansionValue(password) {
+			return "Password in URL"
+		}
+	}
+	return ""
+}
+
+func isExpansionValue(value string) bool {
+	return strings.HasPrefix(value, "$") || strings.HasPrefix(value, "@")
+}
+
+func isAdminPath(p string) bool {
+	for _, segment := range strings.Split(strings.ToLower(p), "/") {
+		for _, admin := range adminPathSegments {
+			if segment == admin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLocalHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1" || host == "0.0.0.0" ||
+		strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local")
+}
@@ -0,0 +1,344 @@
+package analyzer
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxImpactEntries bounds how many files a preview walks before giving up.
+const maxImpactEntries = 200000
+
+// Impact is a read-only preview of the files a command would touch.
+type Impact struct {
+	Command   string   // The command as written
+	Operation string   // delete, move, chmod, chown, chgrp
+	Paths     []string // Operands after glob expansion
+	Files     int      // Files and directories affected
+	Bytes     int64    // Total size of regular files
+	TopDirs   []DirImpact
+	Modified  []string // Git-tracked files with uncommitted changes among them
+	Truncated bool     // The walk stopped at maxImpactEntries
+	Filtered  bool     // find predicates the preview can't evaluate; counts are an upper bound
+}
+
+// DirImpact is the share of an Impact under one directory.
+type DirImpact struct {
+	Path  string
+	Files int
+	Bytes int64
+}
+
+// impactTarget is one operand to walk.
+type impactTarget struct {
+	path      string
+	recursive bool
+}
+
+// findFilter holds the find predicates the preview understands.
+type findFilter struct {
+	names    []string
+	inames   []string
+	fileType string
+	minDepth int
+	maxDepth int
+}
+
+// PreviewImpact expands the operands of rm, mv, chmod/chown -R, find -delete
+// and git clean on the command line and reports what they cover on disk.
+// It needs opts.Context.WorkingDir and returns nil without it.
+func PreviewImpact(line string, opts Options) []Impact {
+	cwd := opts.Context.WorkingDir
+	if cwd == "" {
+		return nil
+	}
+	resolver := newPathResolver(opts)
+	var impacts []Impact
+	for _, cmd := range parseShellCommands(line) {
+		name, args := cmd.program()
+		var targets []impactTarget
+		var filter *findFilter
+		impact := Impact{Command: strings.Join(cmd.Args, " ")}
+
+		switch name {
+		case "rm":
+			impact.Operation = "delete"
+			recursive := hasFlag(args, "-r", "-R", "--recursive")
+			targets = expandOperands(rmOperands(args), resolver, recursive)
+		case "mv":
+			impact.Operation = "move"
+			operands := nonFlagArgs(args)
+			if len(operands) < 2 {
+				continue
+			}
+			targets = expandOperands(operands[:len(operands)-1], resolver, true)
+		case "chmod", "chown", "chgrp":
+			if !hasFlag(args, "-R", "--recursive") {
+				continue
+			}
+			impact.Operation = name
+			operands := nonFlagArgs(args)
+			if len(operands) < 2 {
+				continue
+			}
+			targets = expandOperands(operands[1:], resolver, true)
+		case "find":
+			if !hasFlag(args, "-delete") && !findExecsRm(args) {
+				continue
+			}
+			impact.Operation = "delete"
+			targets = expandOperands(findStartPaths(args), resolver, true)
+			filter, impact.Filtered = parseFindFilter(args)
+		case "git":
+			sub, subArgs, chdir := splitGitArgs(args)
+			if sub != "clean" || !hasFlag(subArgs, "-f", "--force") || hasFlag(subArgs, "-n", "--dry-run") {
+				continue
+			}
+			impact.Operation = "delete"
+			dir := cwd
+			if chdir != "" {
+				dir = resolvePreviewPath(chdir, cwd)
+			}
+			targets = gitCleanTargets(gitRepo{dir: dir}, subArgs)
+		default:
+			continue
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		dirs := map[string]*DirImpact{}
+		for _, t := range targets {
+			impact.Paths = append(impact.Paths, t.path)
+			walkImpact(t, filter, &impact, dirs)
+		}
+		for _, d := range dirs {
+			impact.TopDirs = append(impact.TopDirs, *d)
+		}
+		sort.Slice(impact.TopDirs, func(i, j int) bool {
+			if impact.TopDirs[i].Bytes != impact.TopDirs[j].Bytes {
+				return impact.TopDirs[i].Bytes > impact.TopDirs[j].Bytes
+			}
+			return impact.TopDirs[i].Path < impact.TopDirs[j].Path
+		})
+		if len(impact.TopDirs) > 5 {
+			impact.TopDirs = impact.TopDirs[:5]
+		}
+		impact.Modified = modifiedTrackedFiles(cwd, impact.Paths)
+		impacts = append(impacts, impact)
+	}
+	return impacts
+}
+
+// expandOperands resolves ~ and $HOME and expands globs the way the shell
+// would. Operands built from other variables are skipped.
+func expandOperands(operands []string, r pathResolver, recursive bool) []impactTarget {
+	var targets []impactTarget
+	for _, operand := range operands {
+		p := operand
+		switch {
+		case p == "~" || strings.HasPrefix(p, "~/"):
+			p = r.home + p[1:]
+		case strings.HasPrefix(p, "$HOME"), strings.HasPrefix(p, "${HOME}"):
+			p = r.home + strings.TrimPrefix(strings.TrimPrefix(p, "${HOME}"), "$HOME")
+		}
+		if strings.ContainsAny(p, "$`") {
+			continue
+		}
+		p = resolvePreviewPath(p, r.cwd)
+		matches := []string{p}
+		if strings.ContainsAny(p, "*?[") {
+			matches, _ = filepath.Glob(p)
+		}
+		for _, m := range matches {
+			if _, err := os.Lstat(m); err == nil {
+				targets = append(targets, impactTarget{path: m, recursive: recursive})
+			}
+		}
+	}
+	return targets
+}
+
+func resolvePreviewPath(p, cwd string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(cwd, p)
+}
+
+// walkImpact adds the files under t to impact, grouped by the first
+// directory level below t.
+func walkImpact(t impactTarget, filter *findFilter, impact *Impact, dirs map[string]*DirImpact) {
+	root := t.path
+	info, err := os.Lstat(root)
+	if err != nil {
+		return
+	}
+	if info.IsDir() && !t.recursive {
+		return // rm without -r leaves directories alone
+	}
+	if !info.IsDir() {
+		if filter == nil || filter.matches(root, info, 0) {
+			addImpact(impact, dirs, filepath.Dir(root), info)
+		}
+		return
+	}
+
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if impact.Files >= maxImpactEntries {
+			impact.Truncated = true
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		depth := 0
+		if rel != "." {
+			depth = strings.Count(rel, string(filepath.Separator)) + 1
+		}
+		if filter == nil || filter.matches(p, info, depth) {
+			group := root
+			if depth > 1 {
+				group = filepath.Join(root, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+			}
+			addImpact(impact, dirs, group, info)
+		}
+		if filter != nil && filter.maxDepth >= 0 && depth >= filter.maxDepth && d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+func addImpact(impact *Impact, dirs map[string]*DirImpact, group string, info fs.FileInfo) {
+	impact.Files++
+	d, ok := dirs[group]
+	if !ok {
+		d = &DirImpact{Path: group}
+		dirs[group] = d
+	}
+	d.Files++
+	if info.Mode().IsRegular() {
+		impact.Bytes += info.Size()
+		d.Bytes += info.Size()
+	}
+}
+
+// parseFindFilter extracts -name, -iname, -type, -mindepth and -maxdepth.
+// approximate is set when other tests (e.g. -mtime) narrow the match further.
+func parseFindFilter(args []string) (filter *findFilter, approximate bool) {
+	filter = &findFilter{maxDepth: -1}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "!", "-o", "-or", "-not", "-empty":
+			approximate = true
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") || i+1 >= len(args) {
+			continue
+		}
+		value := args[i+1]
+		switch arg {
+		case "-name":
+			filter.names = append(filter.names, value)
+		case "-iname":
+			filter.inames = append(filter.inames, strings.ToLower(value))
+		case "-type":
+			filter.fileType = value
+		case "-mindepth":
+			filter.minDepth, _ = strconv.Atoi(value)
+		case "-maxdepth":
+			filter.maxDepth, _ = strconv.Atoi(value)
+		case "-path", "-ipath", "-regex", "-iregex", "-newer", "-mtime", "-mmin", "-atime", "-ctime", "-size", "-user", "-group", "-perm":
+			approximate = true
+		default:
+			continue
+		}
+		i++
+	}
+	return filter, approximate
+}
+
+func (f *findFilter) matches(p string, info fs.FileInfo, depth int) bool {
+	if depth < f.minDepth {
+		return false
+	}
+	switch f.fileType {
+	case "f":
+		if !info.Mode().IsRegular() {
+			return false
+		}
+	case "d":
+		if !info.IsDir() {
+			return false
+		}
+	case "l":
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return false
+		}
+	}
+	base := filepath.Base(p)
+	for _, pattern := range f.names {
+		if ok, _ := path.Match(pattern, base); !ok {
+			return false
+		}
+	}
+	for _, pattern := range f.inames {
+		if ok, _ := path.Match(pattern, strings.ToLower(base)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// gitCleanTargets lists what `git clean` would remove, using its dry run.
+func gitCleanTargets(repo gitRepo, args []string) []impactTarget {
+	out, code := repo.run(gitCleanDryRun(args)...)
+	if code != 0 || out == "" {
+		return nil
+	}
+	var targets []impactTarget
+	for _, line := range strings.Split(out, "\n") {
+		rel := strings.TrimPrefix(line, "Would remove ")
+		if rel == line {
+			continue
+		}
+		targets = append(targets, impactTarget{path: filepath.Join(repo.dir, rel), recursive: true})
+	}
+	return targets
+}
+
+// modifiedTrackedFiles returns the git-tracked files with uncommitted changes
+// that lie under any of paths.
+func modifiedTrackedFiles(cwd string, paths []string) []string {
+	repo := gitRepo{dir: cwd}
+	top, code := repo.run("rev-parse", "--show-toplevel")
+	if code != 0 {
+		return nil
+	}
+	out, code := repo.run("diff", "--name-only", "HEAD", "--")
+	if code != 0 || out == "" {
+		return nil
+	}
+	var modified []string
+	for _, rel := range strings.Split(out, "\n") {
+		abs := filepath.Join(top, rel)
+		for _, p := range paths {
+			if isWithin(canonicalPath(abs, false), canonicalPath(p, false)) {
+				modified = append(modified, abs)
+				break
+			}
+		}
+	}
+	return modified
+}
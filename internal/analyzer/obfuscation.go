@@ -0,0 +1,323 @@
+package analyzer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+// maxDecodeDepth bounds how many times decoded payloads are re-analyzed,
+// so nested encodings terminate.
+const maxDecodeDepth = 3
+
+// decodedPayload is a statically recoverable command hidden behind an encoding.
+type decodedPayload struct {
+	Technique string // base64, hex, printf
+	Content   string
+	Executed  bool // decoded content is handed to a shell or eval
+}
+
+var (
+	base64PipeRe = regexp.MustCompile(`(?:echo|printf)\s+(?:-[neE]+\s+)?['"]?([A-Za-z0-9+/]{4,}={0,2})['"]?\s*\|\s*base64\s+(?:-d|--decode|-D)\b`)
+	base64HereRe = regexp.MustCompile(`base64\s+(?:-d|--decode|-D)\s*<<<\s*['"]?([A-Za-z0-9+/]{4,}={0,2})['"]?`)
+	hexPipeRe    = regexp.MustCompile(`(?:echo|printf)\s+(?:-[neE]+\s+)?['"]?([0-9A-Fa-f]{4,})['"]?\s*\|\s*xxd\s+(?:-r\s+-p|-p\s+-r|-rp|-pr)\b`)
+	escapeLitRe  = regexp.MustCompile(`(?:printf|echo\s+-[neE]*e[neE]*)\s+(?:--\s+)?(['"])((?:\\x[0-9A-Fa-f]{1,2}|\\[0-7]{1,3}|[^'"])+)['"]`)
+
+	// Decoded content is executed when it feeds a shell, eval, or a command substitution in command position.
+	pipeToInterpreterRe = regexp.MustCompile(`\|\s*(?:sudo\s+)?(?:/\S*/)?(?:ba|z|da|k)?sh\b`)
+	evalRe              = regexp.MustCompile(`(?:^|[\s;&|(])eval\s`)
+	shellDashCRe        = regexp.MustCompile(`(?:^|[\s;&|(])(?:/\S*/)?(?:ba|z|da|k)?sh\s+-c\s+["']?(?:\$\(|` + "`" + `)`)
+	substInCmdPosRe     = regexp.MustCompile(`(?:^|[;&|]\s*)["']?(?:\$\(|` + "`" + `)`)
+
+	quotingTrickRe = regexp.MustCompile(`\$'|\$\{?IFS|\w(?:''|"")|(?:''|"")\w|\w['"]\w+['"]|\w\\\w`)
+)
+
+// analyzeObfuscation decodes encoded payloads and quoting tricks on a line and
+// re-analyzes the recovered commands. lineFindings are the findings already
+// reported for the raw line; only codes the raw line missed are added.
+func analyzeObfuscation(line string, lineNum int, policy config.PolicyConfig, opts Options, depth int, lineFindings []Finding) []Finding {
+	if depth >= maxDecodeDepth {
+		return nil
+	}
+
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, f := range lineFindings {
+		seen[f.Code] = true
+	}
+
+	for _, payload := range decodePayloads(line) {
+		sub := analyzeScript("decoded-payload", []byte(payload.Content), policy, opts, depth+1)
+		hidden := filterNewFindings(sub, seen)
+		for i := range hidden {
+			hidden[i].Line = lineNum
+			hidden[i].Description = fmt.Sprintf("Decoded %s payload: %s", payload.Technique, hidden[i].Description)
+		}
+		findings = append(findings, hidden...)
+
+		if payload.Executed {
+			findings = append(findings, Finding{
+				Severity:       escalateForHidden("high", sub),
+				Code:           "OBFUSCATED_EXECUTION",
+				Description:    fmt.Sprintf("Execution of %s-encoded content: %s", payload.Technique, truncatePayload(payload.Content)),
+				Line:           lineNum,
+				Recommendation: "Decode and review the payload before running it. Agents should not execute encoded commands.",
+			})
+			seen["OBFUSCATED_EXECUTION"] = true
+		}
+	}
+
+	if quotingTrickRe.MatchString(line) {
+		normalized := normalizeShellQuoting(line)
+		if normalized != line {
+			sub := analyzeScript("deobfuscated", []byte(normalized), policy, opts, depth+1)
+			hidden := filterNewFindings(sub, seen)
+			for i := range hidden {
+				hidden[i].Line = lineNum
+				hidden[i].Description = "Deobfuscated: " + hidden[i].Description
+			}
+			if len(hidden) > 0 {
+				findings = append(findings, hidden...)
+				if !seen["OBFUSCATED_EXECUTION"] {
+					findings = append(findings, Finding{
+						Severity:       escalateForHidden("high", hidden),
+						Code:           "OBFUSCATED_EXECUTION",
+						Description:    fmt.Sprintf("Quoting tricks hide the executed command: %s", truncatePayload(normalized)),
+						Line:           lineNum,
+						Recommendation: "Rewrite the command without escape sequences, split quotes, or IFS tricks so it can be reviewed.",
+					})
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// decodePayloads extracts statically determinable payloads from encoded literals.
+func decodePayloads(line string) []decodedPayload {
+	var payloads []decodedPayload
+	executed := isDecodedContentExecuted(line)
+
+	for _, re := range []*regexp.Regexp{base64PipeRe, base64HereRe} {
+		for _, m := range re.FindAllStringSubmatch(line, -1) {
+			if decoded, ok := decodeBase64(m[1]); ok {
+				payloads = append(payloads, decodedPayload{Technique: "base64", Content: decoded, Executed: executed})
+			}
+		}
+	}
+
+	for _, m := range hexPipeRe.FindAllStringSubmatch(line, -1) {
+		if decoded, err := hex.DecodeString(m[1]); err == nil && isPrintable(string(decoded)) {
+			payloads = append(payloads, decodedPayload{Technique: "hex", Content: string(decoded), Executed: executed})
+		}
+	}
+
+	for _, m := range escapeLitRe.FindAllStringSubmatch(line, -1) {
+		if !strings.Contains(m[2], `\`) {
+			continue
+		}
+		decoded := decodeEscapes(m[2])
+		if decoded != m[2] && isPrintable(decoded) {
+			payloads = append(payloads, decodedPayload{Technique: "printf", Content: decoded, Executed: executed})
+		}
+	}
+
+	return payloads
+}
+
+// isDecodedContentExecuted reports whether decoder output on this line reaches an interpreter.
+func isDecodedContentExecuted(line string) bool {
+	return pipeToInterpreterRe.MatchString(line) ||
+		evalRe.MatchString(line) ||
+		shellDashCRe.MatchString(line) ||
+		substInCmdPosRe.MatchString(strings.TrimSpace(line))
+}
+
+// normalizeShellQuoting performs the quote removal and ANSI-C expansion the
+// shell would do, and replaces $IFS with a space, so split tokens are joined.
+func normalizeShellQuoting(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '$' && i+1 < len(line) && line[i+1] == '\'':
+			end := findClosingQuote(line, i+2, '\'', true)
+			out.WriteString(decodeEscapes(line[i+2 : end]))
+			i = end
+		case c == '$' && strings.HasPrefix(line[i:], "${IFS}"):
+			out.WriteByte(' ')
+			i += len("${IFS}") - 1
+		case c == '$' && strings.HasPrefix(line[i:], "$IFS") && !isWordByte(byteAt(line, i+4)):
+			out.WriteByte(' ')
+			i += len("$IFS") - 1
+		case c == '\'':
+			end := findClosingQuote(line, i+1, '\'', false)
+			out.WriteString(line[i+1 : end])
+			i = end
+		case c == '"':
+			end := findClosingQuote(line, i+1, '"', true)
+			inner := strings.ReplaceAll(line[i+1:end], "${IFS}", " ")
+			inner = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\$`, `$`, "\\`", "`").Replace(inner)
+			out.WriteString(inner)
+			i = end
+		case c == '\\' && i+1 < len(line):
+			out.WriteByte(line[i+1])
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// findClosingQuote returns the index of the closing quote, or len(s) if unterminated.
+func findClosingQuote(s string, start int, quote byte, allowEscape bool) int {
+	for j := start; j < len(s); j++ {
+		if allowEscape && s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] == quote {
+			return j
+		}
+	}
+	return len(s)
+}
+
+// decodeEscapes expands printf/ANSI-C escape sequences (\xHH, \NNN, \uHHHH, \n, ...).
+func decodeEscapes(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'x':
+			j := i + 1
+			for j < len(s) && j < i+3 && isHexByte(s[j]) {
+				j++
+			}
+			if v, err := strconv.ParseUint(s[i+1:j], 16, 8); err == nil {
+				out.WriteByte(byte(v))
+				i = j - 1
+			} else {
+				out.WriteString(`\x`)
+			}
+		case 'u':
+			j := i + 1
+			for j < len(s) && j < i+5 && isHexByte(s[j]) {
+				j++
+			}
+			if v, err := strconv.ParseUint(s[i+1:j], 16, 32); err == nil {
+				out.WriteRune(rune(v))
+				i = j - 1
+			} else {
+				out.WriteString(`\u`)
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			if c == '0' {
+				j++ // printf allows a leading zero before up to three octal digits
+			}
+			k := j
+			for k < len(s) && k < j+3 && s[k] >= '0' && s[k] <= '7' {
+				k++
+			}
+			if v, err := strconv.ParseUint(s[j:k], 8, 8); err == nil {
+				out.WriteByte(byte(v))
+				i = k - 1
+			} else if c == '0' {
+				out.WriteByte(0)
+			}
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'a':
+			out.WriteByte('\a')
+		case 'e', 'E':
+			out.WriteByte(0x1b)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+func decodeBase64(s string) (string, bool) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding} {
+		if decoded, err := enc.DecodeString(s); err == nil && isPrintable(string(decoded)) {
+			return string(decoded), true
+		}
+	}
+	return "", false
+}
+
+// isPrintable rejects binary blobs so only plausible command text is re-analyzed.
+func isPrintable(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// filterNewFindings returns findings whose code is not yet in seen, marking them seen.
+func filterNewFindings(findings []Finding, seen map[string]bool) []Finding {
+	var fresh []Finding
+	for _, f := range findings {
+		if f.Code == "NON_STANDARD_EXTENSION" || seen[f.Code] {
+			continue
+		}
+		seen[f.Code] = true
+		fresh = append(fresh, f)
+	}
+	return fresh
+}
+
+// escalateForHidden raises base to critical when the hidden content is itself high risk.
+func escalateForHidden(base string, hidden []Finding) string {
+	for _, f := range hidden {
+		if f.Severity == "critical" || f.Severity == "high" {
+			return "critical"
+		}
+	}
+	return base
+}
+
+func truncatePayload(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 80 {
+		return s[:77] + "..."
+	}
+	return s
+}
+
+func isHexByte(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func byteAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return 0
+}
@@ -0,0 +1,348 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// workspaceBuildDirs are regenerated by builds; deleting them is routine.
+var workspaceBuildDirs = map[string]bool{
+	"node_modules": true, "dist": true, "build": true, "target": true, "out": true, ".cache": true,
+	"__pycache__": true, ".pytest_cache": true, ".mypy_cache": true, ".tox": true, ".venv": true, "venv": true,
+	".next": true, ".nuxt": true, ".turbo": true, ".parcel-cache": true, "coverage": true, ".gradle": true,
+	"obj": true, "tmp": true, ".terraform": true,
+}
+
+// systemDirs are graded as system paths even when $HOME points into them (root's /root).
+var systemDirs = map[string]bool{
+	"/bin": true, "/boot": true, "/dev": true, "/etc": true, "/lib": true, "/lib64": true, "/opt": true,
+	"/proc": true, "/root": true, "/sbin": true, "/srv": true, "/sys": true, "/usr": true, "/var": true,
+	"/System": true, "/Library": true, "/Applications": true,
+}
+
+// vcsDirs hold repository history.
+var vcsDirs = map[string]bool{".git": true, ".hg": true, ".svn": true, ".jj": true}
+
+// pathResolver turns shell path arguments into absolute, symlink-free paths.
+// cwd and workspace are empty when analyzing a script statically; relative
+// paths are then graded against the script's own directory.
+type pathResolver struct {
+	cwd       string
+	workspace string
+	home      string
+}
+
+// pathTarget is a resolved destructive-command operand.
+type pathTarget struct {
+	path     string // canonical absolute path, or a cleaned relative path when cwd is unknown
+	contents bool   // the operand was dir/* (everything inside path)
+	relative bool
+	home     bool // written as ~ or $HOME
+}
+
+func newPathResolver(opts Options) pathResolver {
+	r := pathResolver{cwd: opts.Context.WorkingDir, workspace: opts.Workspace}
+	if r.workspace == "" {
+		r.workspace = r.cwd
+	}
+	if home, ok := opts.Context.Environment["HOME"]; ok && home != "" {
+		r.home = home
+	} else if home, err := os.UserHomeDir(); err == nil {
+		r.home = home
+	}
+	r.home = canonicalPath(r.home, true)
+	r.workspace = canonicalPath(r.workspace, true)
+	return r
+}
+
+// resolve expands ~, $HOME and $PWD, makes arg absolute against the cwd and
+// resolves symlinks. The final component is only followed when the operand
+// ends in a slash or a contents glob, as rm itself would. ok is false for
+// operands that depend on other variables or command substitutions.
+func (r pathResolver) resolve(arg string) (pathTarget, bool) {
+	p := arg
+	target := pathTarget{home: p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "$HOME") || strings.HasPrefix(p, "${HOME}")}
+	switch {
+	case p == "~" || strings.HasPrefix(p, "~/"):
+		p = r.home + p[1:]
+	case strings.HasPrefix(p, "$HOME"), strings.HasPrefix(p, "${HOME}"):
+		p = r.home + strings.TrimPrefix(strings.TrimPrefix(p, "${HOME}"), "$HOME")
+	case strings.HasPrefix(p, "$PWD"), strings.HasPrefix(p, "${PWD}"):
+		if r.cwd == "" {
+			p = "." + strings.TrimPrefix(strings.TrimPrefix(p, "${PWD}"), "$PWD")
+		} else {
+			p = r.cwd + strings.TrimPrefix(strings.TrimPrefix(p, "${PWD}"), "$PWD")
+		}
+	}
+	if p == "" || strings.ContainsAny(p, "$`") || strings.HasPrefix(p, "~") {
+		return pathTarget{}, false
+	}
+
+	follow := strings.HasSuffix(p, "/")
+	parts := strings.Split(filepath.ToSlash(p), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			// Everything up to the first glob is the directory being emptied
+			if i == len(parts)-1 && (part == "*" || part == ".*") {
+				target.contents = true
+				p = strings.Join(parts[:i], "/")
+				if p == "" && strings.HasPrefix(arg, "/") {
+					p = "/"
+				}
+				follow = true
+			}
+			break
+		}
+	}
+	if p == "" {
+		p = "."
+	}
+
+	if !filepath.IsAbs(p) {
+		if r.cwd == "" {
+			target.path = filepath.Clean(p)
+			target.relative = true
+			return target, true
+		}
+		p = filepath.Join(r.cwd, p)
+	}
+	target.path = canonicalPath(filepath.Clean(p), follow)
+	return target, true
+}
+
+// canonicalPath resolves symlinks in the longest existing prefix of p.
+// The final component is resolved only when followFinal is set.
+func canonicalPath(p string, followFinal bool) string {
+	if p == "" {
+		return ""
+	}
+	dir, base := p, ""
+	if !followFinal {
+		dir, base = filepath.Dir(p), filepath.Base(p)
+		if p == "/" {
+			return p
+		}
+	}
+	var rest []string
+	for {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+		dir = parent
+	}
+	out := filepath.Join(append([]string{dir}, rest...)...)
+	if base != "" {
+		out = filepath.Join(out, base)
+	}
+	return out
+}
+
+// classify grades a destructive operation on target by where it lands:
+// workspace build dirs are low, other workspace content medium, the workspace
+// root and VCS directories high, and home, system or anything else outside
+// the workspace critical. ok is false when the location can't be judged
+// (a path under $HOME with no known workspace).
+func (r pathResolver) classify(target pathTarget) (severity, code, where string, ok bool) {
+	p := target.path
+	if target.relative {
+		switch {
+		case p == "..", strings.HasPrefix(p, "../"):
+			return "critical", "DANGEROUS_DELETE_ROOT", "outside the current directory", true
+		case p == ".":
+			return "high", "DESTRUCTIVE_WORKSPACE_DELETE", "the current directory", true
+		}
+		return r.classifyWorkspace(strings.Split(p, "/"))
+	}
+
+	switch {
+	case p == "/":
+		return "critical", "DANGEROUS_DELETE_ROOT", "the root directory", true
+	case systemDirs[p] && !target.home:
+		return "critical", "DANGEROUS_DELETE_ROOT", "system directory", true
+	case r.home != "" && isWithin(r.home, p), p == "/home", p == "/Users":
+		return "critical", "DANGEROUS_DELETE_HOME", "home directory", true
+	case r.workspace != "" && isWithin(p, r.workspace):
+		if p == r.workspace {
+			return "high", "DESTRUCTIVE_WORKSPACE_DELETE", "workspace root", true
+		}
+		rel, _ := filepath.Rel(r.workspace, p)
+		return r.classifyWorkspace(strings.Split(filepath.ToSlash(rel), "/"))
+	case r.home != "" && isWithin(p, r.home), isWithin(p, "/home"), isWithin(p, "/Users"):
+		if r.workspace == "" {
+			// Could be the script's own project; only the home directory itself is judged
+			return "", "", "", false
+		}
+		return "critical", "DANGEROUS_DELETE_HOME", "home directory outside the workspace", true
+	case isTempPath(p):
+		return "low", "DESTRUCTIVE_WORKSPACE_DELETE", "temporary directory", true
+	case r.workspace != "":
+		return "critical", "DANGEROUS_DELETE_ROOT", "outside the workspace", true
+	}
+	return "critical", "DANGEROUS_DELETE_ROOT", "system directory", true
+}
+
+func (r pathResolver) classifyWorkspace(components []string) (severity, code, where string, ok bool) {
+	for _, c := range components {
+		if vcsDirs[c] {
+			return "high", "DESTRUCTIVE_WORKSPACE_DELETE", "version control directory " + c, true
+		}
+	}
+	for _, c := range components {
+		if workspaceBuildDirs[c] {
+			return "low", "DESTRUCTIVE_WORKSPACE_DELETE", "build output " + c, true
+		}
+	}
+	return "medium", "DESTRUCTIVE_WORKSPACE_DELETE", "workspace", true
+}
+
+// detectPathDeletes resolves the operands of recursive rm and find -delete
+// and grades each by location. handled is false when the line has no such
+// command or an operand can't be resolved, so string-based rules still apply.
+func detectPathDeletes(line string, lineNum int, opts Options) (findings []Finding, handled bool) {
+	resolver := newPathResolver(opts)
+	for _, cmd := range parseShellCommands(line) {
+		name, args := cmd.program()
+		if !strings.Contains(line, name) {
+			// Quoted or escaped program names are left to the de-obfuscation pass
+			continue
+		}
+		var operands []string
+		contents, filtered := false, false
+		switch name {
+		case "rm":
+			if !hasFlag(args, "-r", "-R", "--recursive") {
+				continue
+			}
+			operands = rmOperands(args)
+		case "find":
+			if !hasFlag(args, "-delete") && !findExecsRm(args) {
+				continue
+			}
+			operands = findStartPaths(args)
+			contents = true
+			filtered = hasFlag(args, "-name", "-iname", "-path", "-ipath", "-regex", "-newer", "-mtime", "-mmin", "-size", "-user", "-empty")
+		default:
+			continue
+		}
+		if len(operands) == 0 {
+			continue
+		}
+
+		worst := -1
+		var worstFinding Finding
+		for _, operand := range operands {
+			target, ok := resolver.resolve(operand)
+			if !ok {
+				return nil, false
+			}
+			target.contents = target.contents || contents
+			severity, code, where, ok := resolver.classify(target)
+			if !ok {
+				continue
+			}
+			if filtered && severity == "high" && !strings.HasPrefix(where, "version control") {
+				// find -name ... -delete removes matching files, not the whole tree
+				severity = "medium"
+			}
+			if severityRank(severity) <= worst {
+				continue
+			}
+			worst = severityRank(severity)
+			what := target.path
+			if target.contents {
+				what = filepath.Join(target.path, "*")
+			}
+			worstFinding = Finding{
+				Severity:       severity,
+				Code:           code,
+				Description:    fmt.Sprintf("Recursive delete of %s (%s)", what, where),
+				Line:           lineNum,
+				Recommendation: deleteRecommendation(severity),
+			}
+		}
+		handled = true
+		if worst >= 0 {
+			findings = append(findings, worstFinding)
+		}
+	}
+	return findings, handled
+}
+
+func deleteRecommendation(severity string) string {
+	switch severity {
+	case "critical":
+		return "BLOCKED: This deletes data outside the workspace. Scope the path to the project directory."
+	case "high":
+		return "This deletes the workspace or its version history. Double-check the path before running."
+	case "medium":
+		return "Make sure the files are committed or backed up before deleting them."
+	}
+	return "Build output and temporary files can be regenerated."
+}
+
+func rmOperands(args []string) []string {
+	var operands []string
+	endOfFlags := false
+	for _, arg := range args {
+		if !endOfFlags && arg == "--" {
+			endOfFlags = true
+			continue
+		}
+		if !endOfFlags && strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		operands = append(operands, arg)
+	}
+	return operands
+}
+
+// findStartPaths returns the starting points of a find command ("." if none).
+func findStartPaths(args []string) []string {
+	var paths []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || arg == "(" || arg == "!" {
+			break
+		}
+		paths = append(paths, arg)
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	return paths
+}
+
+func findExecsRm(args []string) bool {
+	for i, arg := range args {
+		if (arg == "-exec" || arg == "-execdir") && i+1 < len(args) && filepath.Base(args[i+1]) == "rm" {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin reports whether p is dir or inside it.
+func isWithin(p, dir string) bool {
+	if dir == "/" {
+		return strings.HasPrefix(p, "/")
+	}
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// isTempPath reports whether p is strictly inside a temporary directory.
+func isTempPath(p string) bool {
+	for _, tmp := range []string{os.TempDir(), "/tmp", "/var/tmp", "/private/tmp"} {
+		tmp = canonicalPath(filepath.Clean(tmp), true)
+		if p != tmp && isWithin(p, tmp) {
+			return true
+		}
+	}
+	return false
+}
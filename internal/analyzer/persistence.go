@@ -0,0 +1,205 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// persistenceRule describes one persistence or privilege-escalation technique.
+type persistenceRule struct {
+	code           string
+	severity       string
+	description    string
+	recommendation string
+}
+
+var (
+	ruleCrontab = persistenceRule{"CRONTAB_MODIFICATION", "high",
+		"Scheduled task installation via cron",
+		"Agents should not install scheduled tasks. Review the cron entry and add it manually if needed."}
+	ruleSystemd = persistenceRule{"SYSTEMD_PERSISTENCE", "high",
+		"Systemd unit or timer creation/enablement",
+		"Services and timers survive the session. Require human review before installing or enabling units."}
+	ruleShellRC = persistenceRule{"SHELL_RC_MODIFICATION", "high",
+		"Write to shell startup file",
+		"Startup files run on every new shell. Show the diff to a human instead of appending automatically."}
+	ruleAuthorizedKeys = persistenceRule{"SSH_AUTHORIZED_KEYS", "critical",
+		"Modification of SSH authorized_keys",
+		"BLOCK this command. Adding SSH keys grants persistent remote access."}
+	ruleSetuid = persistenceRule{"SETUID_SETCAP", "critical",
+		"Setuid/setgid bit or file capability assignment",
+		"BLOCK this command. Setuid binaries and file capabilities are privilege escalation vectors."}
+	ruleSudoers = persistenceRule{"SUDOERS_MODIFICATION", "critical",
+		"Modification of sudoers configuration",
+		"BLOCK this command. Sudoers changes grant persistent root access."}
+	ruleLDPreload = persistenceRule{"LD_PRELOAD_INJECTION", "critical",
+		"Shared library injection via LD_PRELOAD / ld.so.preload",
+		"BLOCK this command. Library preloading hijacks every process that loads it."}
+	ruleGitHook = persistenceRule{"GIT_HOOK_INSTALL", "high",
+		"Git hook installation or hooks path change",
+		"Git hooks run automatically on commit/checkout. Review hook contents before installing."}
+)
+
+var (
+	shellRCFiles = []string{
+		".bashrc", ".bash_profile", ".bash_login", ".profile", ".zshrc", ".zprofile",
+		".zshenv", ".zlogin", ".config/fish/config.fish", "/etc/profile", "/etc/bash.bashrc",
+		"/etc/zsh/zshrc", "/etc/environment",
+	}
+	cronPaths    = []string{"/etc/cron", "/var/spool/cron", "/etc/anacrontab"}
+	systemdPaths = []string{"/etc/systemd/", "/lib/systemd/system", "/usr/lib/systemd/", ".config/systemd/user"}
+	setuidModeRe = regexp.MustCompile(`^[0-7]?[2-7][0-7]{3}$`)
+)
+
+// detectPersistence reports crontab edits, systemd units, shell rc writes,
+// authorized_keys changes, setuid/setcap, sudoers edits, LD_PRELOAD injection
+// and git hook installation. Each technique is reported once per line.
+func detectPersistence(line string, lineNum int) []Finding {
+	hits := make(map[string]persistenceRule)
+	var order []string
+	hit := func(rule persistenceRule) {
+		if _, ok := hits[rule.code]; !ok {
+			hits[rule.code] = rule
+			order = append(order, rule.code)
+		}
+	}
+
+	for _, cmd := range parseShellCommands(line) {
+		name, args := cmd.program()
+		operands := nonFlagArgs(args)
+
+		for _, target := range cmd.writeTargets() {
+			lower := strings.ToLower(target)
+			switch {
+			case pathHasAny(lower, cronPaths):
+				hit(ruleCrontab)
+			case pathHasAny(lower, systemdPaths) || strings.HasSuffix(lower, ".service") || strings.HasSuffix(lower, ".timer"):
+				hit(ruleSystemd)
+			case pathHasSuffixAny(lower, shellRCFiles) || strings.HasPrefix(lower, "/etc/profile.d/"):
+				hit(ruleShellRC)
+			case strings.HasSuffix(lower, "authorized_keys") || strings.HasSuffix(lower, "authorized_keys2"):
+				hit(ruleAuthorizedKeys)
+			case strings.HasPrefix(lower, "/etc/sudoers"):
+				hit(ruleSudoers)
+			case lower == "/etc/ld.so.preload":
+				hit(ruleLDPreload)
+			case strings.Contains(lower, ".git/hooks/"):
+				hit(ruleGitHook)
+			}
+		}
+
+		switch name {
+		case "crontab":
+			// crontab -l only lists; anything else installs or edits the table
+			if !hasFlag(args, "-l") {
+				hit(ruleCrontab)
+			}
+		case "systemctl":
+			if len(operands) > 0 && (operands[0] == "enable" || operands[0] == "link" || operands[0] == "edit" || operands[0] == "preset") {
+				hit(ruleSystemd)
+			}
+		case "systemd-run":
+			if hasFlagPrefix(args, "--on-") || hasFlag(args, "--unit") {
+				hit(ruleSystemd)
+			}
+		case "ssh-copy-id":
+			hit(ruleAuthorizedKeys)
+		case "chmod":
+			if len(operands) > 0 && isSetuidMode(operands[0]) {
+				hit(ruleSetuid)
+			}
+		case "setcap":
+			hit(ruleSetuid)
+		case "visudo":
+			hit(ruleSudoers)
+		case "usermod":
+			if hasFlag(args, "-G", "--groups") && containsAnyField(operands, "sudo", "wheel", "admin", "root") {
+				hit(ruleSudoers)
+			}
+		case "git":
+			if len(operands) >= 2 && operands[0] == "config" && containsAnyField(operands, "core.hookspath") {
+				hit(ruleGitHook)
+			}
+		}
+
+		if name == "chmod" || name == "ln" || name == "cp" || name == "install" {
+			for _, operand := range operands {
+				if strings.Contains(operand, ".git/hooks/") && !strings.HasSuffix(operand, ".sample") {
+					hit(ruleGitHook)
+				}
+			}
+		}
+
+		for _, assignment := range cmd.assignments() {
+			upper := strings.ToUpper(assignment)
+			if strings.HasPrefix(upper, "LD_PRELOAD=") || strings.HasPrefix(upper, "DYLD_INSERT_LIBRARIES=") {
+				hit(ruleLDPreload)
+			}
+		}
+		if name == "export" {
+			for _, operand := range operands {
+				upper := strings.ToUpper(operand)
+				if strings.HasPrefix(upper, "LD_PRELOAD=") || strings.HasPrefix(upper, "DYLD_INSERT_LIBRARIES=") {
+					hit(ruleLDPreload)
+				}
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, code := range order {
+		rule := hits[code]
+		findings = append(findings, Finding{
+			Severity:       rule.severity,
+			Code:           rule.code,
+			Description:    rule.description,
+			Line:           lineNum,
+			Recommendation: rule.recommendation,
+		})
+	}
+	return findings
+}
+
+// isSetuidMode reports chmod modes that set the setuid or setgid bit.
+func isSetuidMode(mode string) bool {
+	if setuidModeRe.MatchString(mode) {
+		special := mode[len(mode)-4] - '0'
+		return special&0o6 != 0
+	}
+	for _, clause := range strings.Split(mode, ",") {
+		if i := strings.IndexAny(clause, "+="); i >= 0 && strings.Contains(clause[i+1:], "s") {
+			return true
+		}
+	}
+	return false
+}
+
+func pathHasAny(path string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(path, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathHasSuffixAny(path string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if path == suffix || strings.HasSuffix(path, "/"+strings.TrimPrefix(suffix, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyField(fields []string, values ...string) bool {
+	for _, field := range fields {
+		lower := strings.ToLower(field)
+		for _, value := range values {
+			if lower == value || strings.Contains(lower, ","+value) || strings.HasPrefix(lower, value+",") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PipeToShell describes a `curl URL | sh` style pipeline.
+type PipeToShell struct {
+	Fetcher     string   // curl or wget
+	URL         string   // script location
+	Interpreter string   // sh, bash, zsh, ...
+	ScriptArgs  []string // arguments passed to the script (after `-s --`)
+}
+
+var (
+	pipeToShellRe = regexp.MustCompile(`^\s*(curl|wget)\s+(.*?)\s*\|\s*(?:/\S*/)?(sh|bash|zsh|dash|ksh)\b(.*)$`)
+	urlRe         = regexp.MustCompile(`https?://[^\s'"|;&]+`)
+)
+
+// ParsePipeToShell recognizes a single fetch-and-run pipeline. Pipelines with
+// sudo, extra stages, or no literal URL are not rewritten and return false.
+func ParsePipeToShell(command string) (PipeToShell, bool) {
+	m := pipeToShellRe.FindStringSubmatch(command)
+	if m == nil || strings.Contains(m[2], "|") || strings.Contains(m[2], "sudo") {
+		return PipeToShell{}, false
+	}
+
+	url := urlRe.FindString(m[2])
+	if url == "" {
+		return PipeToShell{}, false
+	}
+
+	rest := strings.TrimSpace(m[4])
+	if strings.ContainsAny(rest, "|;&><`$") {
+		return PipeToShell{}, false
+	}
+
+	var scriptArgs []string
+	fields := strings.Fields(rest)
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == "-s" && len(scriptArgs) == 0:
+			continue
+		case fields[i] == "--" && len(scriptArgs) == 0:
+			continue
+		default:
+			scriptArgs = append(scriptArgs, strings.Trim(fields[i], `"'`))
+		}
+	}
+
+	return PipeToShell{
+		Fetcher:     m[1],
+		URL:         url,
+		Interpreter: m[3],
+		ScriptArgs:  scriptArgs,
+	}, true
+}
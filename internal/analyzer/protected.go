@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+// readingPrograms print or load the files named by their operands.
+var readingPrograms = map[string]bool{
+	"cat": true, "tac": true, "less": true, "more": true, "head": true, "tail": true, "nl": true,
+	"base64": true, "xxd": true, "od": true, "hexdump": true, "strings": true, "source": true, ".": true,
+	"diff": true, "cmp": true, "jq": true, "yq": true, "zcat": true, "bat": true,
+}
+
+// pathAccess is one path a command touches and how.
+type pathAccess struct {
+	operand string
+	access  config.ProtectionMode // ProtectNoDelete, ProtectNoWrite or ProtectNoRead for delete, write and read
+	tree    bool                  // the whole directory is removed, not just the entry
+}
+
+// detectProtectedPaths reports commands that delete, write or read paths
+// covered by the protected_paths policy.
+func detectProtectedPaths(line string, lineNum int, opts Options) []Finding {
+	if len(opts.ProtectedPaths) == 0 {
+		return nil
+	}
+	resolver := newPathResolver(opts)
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, cmd := range parseShellCommands(line) {
+		for _, acc := range commandPathAccesses(cmd) {
+			target, ok := resolver.resolve(acc.operand)
+			if !ok {
+				continue
+			}
+			pp, ok := config.ProtectionFor(opts.ProtectedPaths, target.path, resolver.workspace, resolver.home)
+			if !ok || !pp.Mode.Denies(acc.access) {
+				if acc.access != config.ProtectNoDelete || (!acc.tree && !target.contents) {
+					continue
+				}
+				// Removing a directory removes the protected paths inside it
+				if pp, ok = resolver.protectedUnder(opts.ProtectedPaths, target.path); !ok {
+					continue
+				}
+			}
+			key := pp.Path + "\x00" + string(acc.access)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, Finding{
+				Severity:       "critical",
+				Code:           "PROTECTED_PATH",
+				Description:    fmt.Sprintf("Command would %s %s, protected by %q (%s)", accessVerb(acc.access), acc.operand, pp.Path, pp.Mode),
+				Line:           lineNum,
+				Recommendation: "BLOCKED: This path is listed in protected_paths. Change it by hand or update the policy with review.",
+			})
+		}
+	}
+	return findings
+}
+
+// commandPathAccesses lists the operands cmd deletes, writes and reads.
+func commandPathAccesses(cmd shellCommand) []pathAccess {
+	var out []pathAccess
+	add := func(access config.ProtectionMode, tree bool, operands ...string) {
+		for _, op := range operands {
+			out = append(out, pathAccess{operand: op, access: access, tree: tree})
+		}
+	}
+
+	for _, target := range cmd.writeTargets() {
+		add(config.ProtectNoWrite, false, target)
+	}
+	for _, r := range cmd.Redirects {
+		if r.Op == "<" {
+			add(config.ProtectNoRead, false, r.Target)
+		}
+	}
+
+	name, args := cmd.program()
+	operands := nonFlagArgs(args)
+	switch name {
+	case "rm":
+		add(config.ProtectNoDelete, hasFlag(args, "-r", "-R", "--recursive"), rmOperands(args)...)
+	case "rmdir", "unlink", "shred":
+		add(config.ProtectNoDelete, false, rmOperands(args)...)
+	case "find":
+		if hasFlag(args, "-delete") || findExecsRm(args) {
+			add(config.ProtectNoDelete, true, findStartPaths(args)...)
+		}
+	case "mv":
+		if len(operands) > 1 {
+			add(config.ProtectNoDelete, false, operands[:len(operands)-1]...)
+		}
+	case "cp", "rsync", "scp":
+		if len(operands) > 1 {
+			add(config.ProtectNoRead, false, operands[:len(operands)-1]...)
+		}
+		if name == "rsync" && hasFlagPrefix(args, "--delete") && len(operands) > 0 {
+			add(config.ProtectNoDelete, true, operands[len(operands)-1])
+		}
+	case "chmod", "chown", "chgrp":
+		// The first operand is the mode or owner
+		if len(operands) > 1 {
+			add(config.ProtectNoWrite, false, operands[1:]...)
+		}
+	case "grep", "egrep", "fgrep", "rg":
+		if hasFlag(args, "-e", "-f", "--regexp", "--file") {
+			add(config.ProtectNoRead, false, operands...)
+		} else if len(operands) > 1 {
+			add(config.ProtectNoRead, false, operands[1:]...)
+		}
+	case "awk", "sed":
+		if len(operands) > 1 {
+			add(config.ProtectNoRead, false, operands[1:]...)
+		}
+	default:
+		if readingPrograms[name] {
+			add(config.ProtectNoRead, false, operands...)
+		}
+	}
+	return out
+}
+
+// protectedUnder returns a protected path with no-delete or stronger that lies
+// inside dir, so removing dir would remove it.
+func (r pathResolver) protectedUnder(paths []config.ProtectedPath, dir string) (config.ProtectedPath, bool) {
+	for _, pp := range paths {
+		if !pp.Mode.Denies(config.ProtectNoDelete) {
+			continue
+		}
+		pattern := filepath.ToSlash(strings.TrimSuffix(pp.Path, "/"))
+		if !strings.Contains(pattern, "/") {
+			// Base-name patterns: only entries directly in dir are checked
+			if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+				return pp, true
+			}
+			continue
+		}
+		if anchor := r.patternAnchor(pattern); anchor != "" && (dir == "." || isWithin(anchor, dir)) {
+			return pp, true
+		}
+	}
+	return config.ProtectedPath{}, false
+}
+
+// patternAnchor is the literal directory a pattern starts in (migrations/**
+// is anchored at <workspace>/migrations).
+func (r pathResolver) patternAnchor(pattern string) string {
+	var literal []string
+	for _, part := range strings.Split(strings.TrimPrefix(pattern, "./"), "/") {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		literal = append(literal, part)
+	}
+	anchor := strings.Join(literal, "/")
+	switch {
+	case anchor == "":
+		return ""
+	case strings.HasPrefix(anchor, "~/"):
+		return filepath.Join(r.home, anchor[2:])
+	case strings.HasPrefix(anchor, "/"):
+		return filepath.Clean(anchor)
+	case r.workspace != "":
+		return filepath.Join(r.workspace, anchor)
+	}
+	return filepath.Clean(anchor)
+}
+
+func accessVerb(access config.ProtectionMode) string {
+	switch access {
+	case config.ProtectNoDelete:
+		return "delete"
+	case config.ProtectNoRead:
+		return "read"
+	}
+	return "modify"
+}
@@ -0,0 +1,357 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// shellRedirect is an output or input redirection such as `> file` or `2>> log`.
+type shellRedirect struct {
+	Op     string // >, >>, <, <<, <<<, 2>, &>, ...
+	Target string
+}
+
+// shellCommand is one simple command of a list or pipeline, with quotes
+// removed from its words and redirections split out.
+type shellCommand struct {
+	Args      []string
+	Redirects []shellRedirect
+	Piped     bool // stdin is the previous command's stdout
+}
+
+// parseShellCommands splits a line on |, ||, &&, ; and & and tokenizes each
+// simple command the way the shell would (quote removal, backslash escapes,
+// ANSI-C quoting). Expansions are left as literal text.
+func parseShellCommands(line string) []shellCommand {
+	var commands []shellCommand
+	var current shellCommand
+	var word strings.Builder
+	inWord := false
+	pendingRedirect := ""
+
+	flushWord := func() {
+		if !inWord {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		inWord = false
+		if pendingRedirect != "" {
+			current.Redirects = append(current.Redirects, shellRedirect{Op: pendingRedirect, Target: w})
+			pendingRedirect = ""
+			return
+		}
+		current.Args = append(current.Args, w)
+	}
+	flushCommand := func() {
+		flushWord()
+		if len(current.Args) > 0 || len(current.Redirects) > 0 {
+			commands = append(commands, current)
+		}
+		current = shellCommand{}
+		pendingRedirect = ""
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			word.WriteByte(line[i+1])
+			inWord = true
+			i++
+		case c == '$' && i+1 < len(line) && line[i+1] == '\'':
+			end := findClosingQuote(line, i+2, '\'', true)
+			word.WriteString(decodeEscapes(line[i+2 : end]))
+			inWord = true
+			i = end
+		case c == '$' && i+1 < len(line) && line[i+1] == '(', c == '`':
+			// Command substitutions stay inside the word, separators and all
+			end := substitutionEnd(line, i)
+			word.WriteString(line[i:end])
+			inWord = true
+			i = end - 1
+		case c == '\'':
+			end := findClosingQuote(line, i+1, '\'', false)
+			word.WriteString(line[i+1 : end])
+			inWord = true
+			i = end
+		case c == '"':
+			end := findClosingQuote(line, i+1, '"', true)
+			word.WriteString(strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\$`, `$`).Replace(line[i+1 : end]))
+			inWord = true
+			i = end
+		case c == ' ' || c == '\t' || c == '\n':
+			flushWord()
+		case c == '|' || c == ';' || c == '&':
+			if c == '&' && i+1 < len(line) && line[i+1] == '>' {
+				flushWord()
+				op := "&>"
+				i++
+				if i+1 < len(line) && line[i+1] == '>' {
+					op = "&>>"
+					i++
+				}
+				pendingRedirect = op
+				continue
+			}
+			flushCommand()
+			if i+1 < len(line) && (line[i+1] == c) {
+				i++
+			} else if c == '|' {
+				current.Piped = true
+			}
+		case c == '>' || c == '<':
+			// A bare fd number directly before the operator belongs to it (2>, 1>>)
+			op := ""
+			if inWord && word.Len() == 1 && word.String()[0] >= '0' && word.String()[0] <= '9' {
+				op = word.String()
+				word.Reset()
+				inWord = false
+			} else {
+				flushWord()
+			}
+			op += string(c)
+			for i+1 < len(line) && (line[i+1] == c || (c == '>' && line[i+1] == '&') || (c == '>' && line[i+1] == '|')) {
+				op += string(line[i+1])
+				i++
+			}
+			pendingRedirect = op
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flushCommand()
+	return commands
+}
+
+// substitutionEnd returns the index just past the $(...) or `...` starting at start.
+func substitutionEnd(line string, start int) int {
+	if line[start] == '`' {
+		end := findClosingQuote(line, start+1, '`', true)
+		if end < len(line) {
+			end++
+		}
+		return end
+	}
+	depth := 0
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '\'':
+			i = findClosingQuote(line, i+1, '\'', false)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(line)
+}
+
+// commandWrappers run their arguments as a command.
+var commandWrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "time": true,
+	"exec": true, "command": true, "nice": true, "ionice": true, "builtin": true,
+}
+
+// program returns the invoked program's base name and its arguments, skipping
+// VAR=value assignments and wrappers such as sudo, env and nohup.
+func (c shellCommand) program() (string, []string) {
+	args := c.Args
+	for len(args) > 0 {
+		head := args[0]
+		if isAssignment(head) {
+			args = args[1:]
+			continue
+		}
+		name := filepath.Base(head)
+		if !commandWrappers[name] {
+			return name, args[1:]
+		}
+		args = args[1:]
+		// Skip wrapper flags (sudo -u user, env -i, nice -n 10)
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			flag := args[0]
+			args = args[1:]
+			if wrapperFlagTakesValue(name, flag) && len(args) > 0 {
+				args = args[1:]
+			}
+		}
+	}
+	return "", nil
+}
+
+// assignments returns the VAR=value words preceding the program (including after env).
+func (c shellCommand) assignments() []string {
+	var vars []string
+	for _, arg := range c.Args {
+		if isAssignment(arg) {
+			vars = append(vars, arg)
+			continue
+		}
+		if !commandWrappers[filepath.Base(arg)] && !strings.HasPrefix(arg, "-") {
+			break
+		}
+	}
+	return vars
+}
+
+// writeTargets returns the paths this command writes to: output redirections
+// plus destinations of common file-writing programs.
+func (c shellCommand) writeTargets() []string {
+	var targets []string
+	for _, r := range c.Redirects {
+		if strings.Contains(r.Op, ">") {
+			targets = append(targets, r.Target)
+		}
+	}
+
+	name, args := c.program()
+	operands := nonFlagArgs(args)
+	switch name {
+	case "tee", "touch", "truncate", "vi", "vim", "nvim", "nano", "emacs", "ed", "shred":
+		targets = append(targets, operands...)
+	case "cp", "mv", "install", "ln", "rsync", "scp":
+		if len(operands) > 0 {
+			targets = append(targets, operands[len(operands)-1])
+		}
+	case "sed", "perl":
+		if hasFlagPrefix(args, "-i") || hasFlagPrefix(args, "--in-place") || hasFlagPrefix(args, "-pi") {
+			targets = append(targets, operands...)
+		}
+	case "dd":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "of=") {
+				targets = append(targets, strings.TrimPrefix(arg, "of="))
+			}
+		}
+	}
+	return targets
+}
+
+func wrapperFlagTakesValue(wrapper, flag string) bool {
+	switch wrapper {
+	case "sudo", "doas":
+		return flag == "-u" || flag == "-g"
+	case "nice":
+		return flag == "-n"
+	case "ionice":
+		return flag == "-c" || flag == "-n"
+	}
+	return false
+}
+
+func isAssignment(word string) bool {
+	eq := strings.Index(word, "=")
+	if eq <= 0 {
+		return false
+	}
+	for i := 0; i < eq; i++ {
+		if !isWordByte(word[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func nonFlagArgs(args []string) []string {
+	var operands []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			operands = append(operands, arg)
+		}
+	}
+	return operands
+}
+
+// operandsSkipping returns non-flag arguments, also skipping the value of
+// any of valueFlags given as a separate word (-i key, -P 22).
+func operandsSkipping(args []string, valueFlags ...string) []string {
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			operands = append(operands, arg)
+			continue
+		}
+		for _, flag := range valueFlags {
+			if arg == flag {
+				i++
+				break
+			}
+		}
+	}
+	return operands
+}
+
+func hasFlagPrefix(args []string, prefix string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether any of the flags appears, including inside bundled
+// short flags (-rf matches -f).
+func hasFlag(args []string, flags ...string) bool {
+	for _, arg := range args {
+		for _, flag := range flags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return true
+			}
+			if len(flag) == 2 && flag[0] == '-' && flag[1] != '-' &&
+				strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg[1:], rune(flag[1])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flagValue returns the value of a flag given as `--flag value`, `--flag=value` or `-fvalue`.
+func flagValue(args []string, flags ...string) (string, bool) {
+	for i, arg := range args {
+		for _, flag := range flags {
+			switch {
+			case arg == flag && i+1 < len(args):
+				return args[i+1], true
+			case strings.HasPrefix(arg, flag+"="):
+				return strings.TrimPrefix(arg, flag+"="), true
+			case len(flag) == 2 && flag[1] != '-' && strings.HasPrefix(arg, flag) && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+				return arg[2:], true
+			}
+		}
+	}
+	return "", false
+}
+
+// flagValues returns every value of a repeatable flag (-H a -H b).
+func flagValues(args []string, flags ...string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+	flags:
+		for _, flag := range flags {
+			switch {
+			case arg == flag && i+1 < len(args):
+				values = append(values, args[i+1])
+				i++
+			case strings.HasPrefix(arg, flag+"="):
+				values = append(values, arg[len(flag)+1:])
+			case len(flag) == 2 && flag[1] != '-' && strings.HasPrefix(arg, flag) && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+				values = append(values, arg[2:])
+			default:
+				continue
+			}
+			break flags
+		}
+	}
+	return values
+}
@@ -0,0 +1,430 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxSQLFileBytes bounds how much of a -f/.read file is analyzed.
+const maxSQLFileBytes = 1 << 20
+
+// sqlInvocation is SQL (or MongoDB JavaScript) handed to a database CLI.
+type sqlInvocation struct {
+	client   string
+	database string
+	host     string
+	scripts  []string
+	mongo    bool
+}
+
+// sqlIssue is one destructive statement found in an invocation.
+type sqlIssue struct {
+	severity  string
+	statement string
+}
+
+var (
+	mongoDropDatabaseRe = regexp.MustCompile(`\bdb\.dropDatabase\s*\(`)
+	mongoDropRe         = regexp.MustCompile(`\bdb\.(?:getCollection\(\s*['"]([\w.-]+)['"]\s*\)|([\w-]+))\.drop\s*\(`)
+	mongoUnfilteredRe   = regexp.MustCompile(`\bdb\.(?:getCollection\(\s*['"]([\w.-]+)['"]\s*\)|([\w-]+))\.(deleteMany|remove|updateMany|update)\s*\(\s*\{\s*\}`)
+)
+
+// detectDestructiveSQL extracts SQL passed to psql, mysql, sqlite3, mongosh and
+// friends (inline, from a heredoc in the following lines, or from -f files), tokenizes it,
+// and reports DROP/TRUNCATE, ALTER ... DROP, DELETE/UPDATE without WHERE and
+// GRANT ... TO PUBLIC, naming the target database and host. Severity is
+// raised when the host, database or connection string matches a production keyword.
+func detectDestructiveSQL(line string, lineNum int, following []string, prodKeywords []string) []Finding {
+	commands := parseShellCommands(line)
+	var findings []Finding
+
+	for i := range commands {
+		inv, ok := extractSQLInvocation(commands, i, following)
+		if !ok || len(inv.scripts) == 0 {
+			continue
+		}
+
+		var issues []sqlIssue
+		for _, script := range inv.scripts {
+			if inv.mongo {
+				issues = append(issues, analyzeMongoScript(script)...)
+			} else {
+				issues = append(issues, analyzeSQL(script)...)
+			}
+		}
+		if len(issues) == 0 {
+			continue
+		}
+
+		severity := "low"
+		var statements []string
+		for _, issue := range issues {
+			statements = appendUnique(statements, issue.statement)
+			if severityRank(issue.severity) > severityRank(severity) {
+				severity = issue.severity
+			}
+		}
+
+		target := sqlTargetDescription(inv)
+		prodMatch := matchProductionKeyword(prodKeywords, inv.host, inv.database)
+		desc := fmt.Sprintf("Destructive SQL via %s: %s", inv.client, strings.Join(statements, "; "))
+		if target != "" {
+			desc += " (" + target + ")"
+		}
+		rec := "Back up the affected data and run destructive statements manually inside a transaction."
+		if prodMatch != "" {
+			severity = "critical"
+			desc += " on " + strings.ToUpper(prodMatch) + " database"
+			rec += " REQUIRE MANUAL APPROVAL for production databases."
+		}
+
+		findings = append(findings, Finding{
+			Severity:       severity,
+			Code:           "DESTRUCTIVE_SQL",
+			Description:    desc,
+			Line:           lineNum,
+			Recommendation: rec,
+		})
+	}
+	return findings
+}
+
+// extractSQLInvocation recognizes database clients and collects the SQL they run.
+func extractSQLInvocation(commands []shellCommand, i int, following []string) (sqlInvocation, bool) {
+	cmd := commands[i]
+	name, args := cmd.program()
+	inv := sqlInvocation{client: name}
+
+	var files []string
+	switch name {
+	case "psql":
+		if v, ok := flagValue(args, "-c", "--command"); ok {
+			inv.scripts = append(inv.scripts, v)
+		}
+		if v, ok := flagValue(args, "-f", "--file"); ok {
+			files = append(files, v)
+		}
+		inv.database, _ = flagValue(args, "-d", "--dbname")
+		inv.host, _ = flagValue(args, "-h", "--host")
+		operands := operandsSkipping(args, "-c", "--command", "-f", "--file", "-d", "--dbname", "-h", "--host",
+			"-U", "--username", "-p", "--port", "-v", "--set", "-o", "--output")
+		if inv.database == "" && len(operands) > 0 {
+			inv.database = operands[0]
+		}
+	case "mysql", "mariadb":
+		if v, ok := flagValue(args, "-e", "--execute"); ok {
+			inv.scripts = append(inv.scripts, v)
+		}
+		inv.database, _ = flagValue(args, "-D", "--database")
+		inv.host, _ = flagValue(args, "-h", "--host")
+		operands := operandsSkipping(args, "-e", "--execute", "-D", "--database", "-h", "--host", "-u", "--user", "-P", "--port")
+		if inv.database == "" && len(operands) > 0 {
+			inv.database = operands[0]
+		}
+	case "sqlite3", "sqlite":
+		operands := operandsSkipping(args, "-cmd", "-init", "-separator", "-newline", "-nullvalue")
+		if len(operands) > 0 {
+			inv.database = operands[0]
+		}
+		for _, sql := range operands[min(1, len(operands)):] {
+			if strings.HasPrefix(sql, ".read ") {
+				files = append(files, strings.TrimSpace(strings.TrimPrefix(sql, ".read ")))
+				continue
+			}
+			inv.scripts = append(inv.scripts, sql)
+		}
+	case "mongosh", "mongo":
+		inv.mongo = true
+		if v, ok := flagValue(args, "--eval"); ok {
+			inv.scripts = append(inv.scripts, v)
+		}
+		inv.host, _ = flagValue(args, "--host")
+		for _, operand := range operandsSkipping(args, "--eval", "--host", "--port", "-u", "--username", "-p", "--password", "--file", "-f") {
+			if strings.HasSuffix(operand, ".js") {
+				files = append(files, operand)
+			} else if inv.database == "" {
+				inv.database = operand
+			}
+		}
+		if v, ok := flagValue(args, "--file", "-f"); ok {
+			files = append(files, v)
+		}
+	case "clickhouse-client":
+		if v, ok := flagValue(args, "--query", "-q"); ok {
+			inv.scripts = append(inv.scripts, v)
+		}
+		inv.database, _ = flagValue(args, "--database", "-d")
+		inv.host, _ = flagValue(args, "--host", "-h")
+	case "sqlcmd":
+		if v, ok := flagValue(args, "-Q", "-q"); ok {
+			inv.scripts = append(inv.scripts, v)
+		}
+		if v, ok := flagValue(args, "-i"); ok {
+			files = append(files, v)
+		}
+		inv.database, _ = flagValue(args, "-d")
+		inv.host, _ = flagValue(args, "-S")
+	default:
+		return inv, false
+	}
+
+	// Connection URIs carry host and database: postgres://user@host:5432/app
+	for _, candidate := range []string{inv.database, inv.host} {
+		if !strings.Contains(candidate, "://") {
+			continue
+		}
+		if u, err := url.Parse(candidate); err == nil {
+			inv.host = u.Hostname()
+			inv.database = strings.TrimPrefix(u.Path, "/")
+		}
+	}
+
+	// Input redirection, heredocs, here-strings and `echo "..." |`
+	for _, r := range cmd.Redirects {
+		switch r.Op {
+		case "<", "0<":
+			files = append(files, r.Target)
+		case "<<<":
+			inv.scripts = append(inv.scripts, r.Target)
+		case "<<":
+			// <<-EOF strips leading tabs from the body
+			delimiter := strings.TrimPrefix(r.Target, "-")
+			inv.scripts = append(inv.scripts, heredocBody(following, delimiter, delimiter != r.Target))
+		}
+	}
+	if cmd.Piped && i > 0 {
+		if prevName, prevArgs := commands[i-1].program(); prevName == "echo" || prevName == "printf" {
+			inv.scripts = append(inv.scripts, strings.Join(nonFlagArgs(prevArgs), " "))
+		}
+	}
+
+	for _, file := range files {
+		if content, ok := readSQLFile(file); ok {
+			inv.scripts = append(inv.scripts, content)
+		}
+	}
+	return inv, true
+}
+
+// heredocBody returns the lines up to the heredoc delimiter
+func heredocBody(following []string, delimiter string, stripTabs bool) string {
+	var body []string
+	for _, line := range following {
+		line = strings.TrimRight(line, "\r")
+		if stripTabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if strings.TrimSpace(line) == delimiter {
+			break
+		}
+		body = append(body, line)
+	}
+	return strings.Join(body, "\n")
+}
+
+func readSQLFile(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxSQLFileBytes {
+		return "", false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// analyzeSQL tokenizes a SQL script and reports destructive statements.
+func analyzeSQL(script string) []sqlIssue {
+	var issues []sqlIssue
+	for _, stmt := range splitSQLStatements(script) {
+		if len(stmt) == 0 {
+			continue
+		}
+		switch strings.ToUpper(stmt[0]) {
+		case "DROP":
+			kind, name := sqlObject(stmt[1:])
+			severity := "high"
+			if kind == "DATABASE" || kind == "SCHEMA" {
+				severity = "critical"
+			}
+			issues = append(issues, sqlIssue{severity, strings.TrimSpace("DROP " + kind + " " + name)})
+		case "TRUNCATE":
+			_, name := sqlObject(stmt[1:])
+			issues = append(issues, sqlIssue{"high", "TRUNCATE " + name})
+		case "ALTER":
+			kind, name := sqlObject(stmt[1:])
+			if sqlHasKeyword(stmt[2:], "DROP") {
+				issues = append(issues, sqlIssue{"high", fmt.Sprintf("ALTER %s %s ... DROP", kind, name)})
+			}
+		case "DELETE":
+			if !sqlHasKeyword(stmt, "WHERE") {
+				_, name := sqlObject(stmt[1:])
+				issues = append(issues, sqlIssue{"high", "DELETE FROM " + name + " without WHERE"})
+			}
+		case "UPDATE":
+			if !sqlHasKeyword(stmt, "WHERE") && len(stmt) > 1 {
+				issues = append(issues, sqlIssue{"high", "UPDATE " + stmt[1] + " without WHERE"})
+			}
+		case "GRANT":
+			for j := 1; j+1 < len(stmt); j++ {
+				if strings.EqualFold(stmt[j], "TO") && strings.EqualFold(stmt[j+1], "PUBLIC") {
+					issues = append(issues, sqlIssue{"high", "GRANT ... TO PUBLIC"})
+					break
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// sqlObject returns the object kind and name following DROP/TRUNCATE/ALTER/DELETE,
+// skipping FROM, TABLE, IF [NOT] EXISTS and similar modifiers.
+func sqlObject(tokens []string) (string, string) {
+	kind := ""
+	for _, tok := range tokens {
+		switch upper := strings.ToUpper(tok); upper {
+		case "FROM", "IF", "EXISTS", "NOT", "ONLY", "TEMPORARY", "TEMP", "MATERIALIZED", "UNIQUE", "CONCURRENTLY":
+			continue
+		case "TABLE", "DATABASE", "SCHEMA", "INDEX", "VIEW", "USER", "ROLE", "SEQUENCE", "FUNCTION", "PROCEDURE",
+			"TRIGGER", "TYPE", "EXTENSION", "KEYSPACE", "COLLECTION":
+			if kind == "" {
+				kind = upper
+				continue
+			}
+			return kind, tok
+		default:
+			return kind, tok
+		}
+	}
+	return kind, ""
+}
+
+func sqlHasKeyword(tokens []string, keyword string) bool {
+	for _, tok := range tokens {
+		if strings.EqualFold(tok, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSQLStatements tokenizes SQL into statements of words, dropping string
+// literals, comments and punctuation other than the statement separator.
+func splitSQLStatements(script string) [][]string {
+	var statements [][]string
+	var current []string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			current = append(current, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			flush()
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			flush()
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+		case c == '\'':
+			flush()
+			// string literal: '' is an escaped quote
+			for i++; i < len(script); i++ {
+				if script[i] == '\'' {
+					if i+1 < len(script) && script[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			current = append(current, "'?'")
+		case c == '"' || c == '`':
+			// quoted identifier
+			flush()
+			end := strings.IndexByte(script[i+1:], c)
+			if end < 0 {
+				end = len(script) - i - 1
+			}
+			current = append(current, script[i+1:i+1+end])
+			i += end + 1
+		case c == ';':
+			flush()
+			if len(current) > 0 {
+				statements = append(statements, current)
+			}
+			current = nil
+		case isWordByte(c) || c == '.' || c == '$':
+			word.WriteByte(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+// analyzeMongoScript reports dropDatabase, collection drops and unfiltered deletes/updates.
+func analyzeMongoScript(script string) []sqlIssue {
+	var issues []sqlIssue
+	if mongoDropDatabaseRe.MatchString(script) {
+		issues = append(issues, sqlIssue{"critical", "db.dropDatabase()"})
+	}
+	for _, m := range mongoDropRe.FindAllStringSubmatch(script, -1) {
+		issues = append(issues, sqlIssue{"high", "drop collection " + m[1] + m[2]})
+	}
+	for _, m := range mongoUnfilteredRe.FindAllStringSubmatch(script, -1) {
+		issues = append(issues, sqlIssue{"high", fmt.Sprintf("%s.%s({}) without filter", m[1]+m[2], m[3])})
+	}
+	return issues
+}
+
+func sqlTargetDescription(inv sqlInvocation) string {
+	var parts []string
+	if inv.database != "" {
+		parts = append(parts, "database "+inv.database)
+	}
+	if inv.host != "" {
+		parts = append(parts, "host "+inv.host)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchProductionKeyword returns the production keyword naming one of the
+// values, matched as a whole token (prod-db.example.com matches "prod",
+// product-catalog does not).
+func matchProductionKeyword(keywords []string, values ...string) string {
+	for _, value := range values {
+		tokens := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+		})
+		for _, tok := range tokens {
+			for _, keyword := range keywords {
+				keyword = strings.ToLower(keyword)
+				if keyword != "" && strings.HasPrefix(tok, keyword) && strings.Trim(tok[len(keyword):], "0123456789") == "" {
+					return keyword
+				}
+			}
+		}
+	}
+	return ""
+}
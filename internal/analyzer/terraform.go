@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// TerraformChange is a resource the plan deletes or replaces.
+type TerraformChange struct {
+	Address string
+	Type    string
+	Replace bool // delete+create rather than a plain delete
+}
+
+// terraformPlan is the subset of `terraform show -json` output we need.
+type terraformPlan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Mode    string `json:"mode"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// ParseTerraformPlan returns the managed resources a `terraform show -json`
+// plan deletes or replaces.
+func ParseTerraformPlan(data []byte) ([]TerraformChange, error) {
+	var plan terraformPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("parse terraform plan: %w", err)
+	}
+
+	var changes []TerraformChange
+	for _, rc := range plan.ResourceChanges {
+		if rc.Mode == "data" {
+			continue
+		}
+		deletes, creates := false, false
+		for _, action := range rc.Change.Actions {
+			switch action {
+			case "delete":
+				deletes = true
+			case "create":
+				creates = true
+			}
+		}
+		if deletes {
+			changes = append(changes, TerraformChange{Address: rc.Address, Type: rc.Type, Replace: creates})
+		}
+	}
+	return changes, nil
+}
+
+// AnalyzeTerraformPlan reports deletes and replacements in a plan. Resources
+// whose type matches one of sensitivePatterns (globs such as "*_db_instance")
+// each get a critical finding; the rest are summarized in one medium finding.
+func AnalyzeTerraformPlan(changes []TerraformChange, sensitivePatterns []string) []Finding {
+	var findings []Finding
+	var others []string
+	for _, change := range changes {
+		action := "deletes"
+		if change.Replace {
+			action = "replaces"
+		}
+		if !matchesResourceType(change.Type, sensitivePatterns) {
+			others = append(others, change.Address)
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity:       "critical",
+			Code:           "TERRAFORM_PLAN_DESTROY",
+			Description:    fmt.Sprintf("Terraform plan %s %s", action, change.Address),
+			Recommendation: "REQUIRE MANUAL APPROVAL. Check for prevent_destroy, backups or a moved block before applying.",
+		})
+	}
+
+	if len(others) > 0 {
+		shown := others
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		desc := fmt.Sprintf("Terraform plan deletes or replaces %d resource(s): %s", len(others), strings.Join(shown, ", "))
+		if len(others) > len(shown) {
+			desc += fmt.Sprintf(" and %d more", len(others)-len(shown))
+		}
+		findings = append(findings, Finding{
+			Severity:       "medium",
+			Code:           "TERRAFORM_PLAN_DESTROY",
+			Description:    desc,
+			Recommendation: "Review the plan's delete/replace actions before applying.",
+		})
+	}
+	return findings
+}
+
+func matchesResourceType(resourceType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, resourceType); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// confusables maps non-ASCII characters that render like ASCII to the ASCII
+// character a reader would see. Covers the Cyrillic and Greek look-alikes,
+// typographic dashes/slashes and exotic spaces that LLM output tends to carry.
+var confusables = map[rune]rune{
+	// Cyrillic lowercase
+	'а': 'a', 'с': 'c', 'е': 'e', 'о': 'o', 'р': 'p', 'х': 'x', 'у': 'y',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'һ': 'h', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w',
+	// Cyrillic uppercase
+	'А': 'A', 'В': 'B', 'С': 'C', 'Е': 'E', 'Н': 'H', 'І': 'I', 'Ј': 'J', 'К': 'K',
+	'М': 'M', 'О': 'O', 'Р': 'P', 'Ѕ': 'S', 'Т': 'T', 'Х': 'X', 'У': 'Y',
+	// Greek
+	'ο': 'o', 'ν': 'v', 'ρ': 'p', 'ι': 'i', 'κ': 'k', 'α': 'a',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K',
+	'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	// Punctuation that changes shell meaning while looking identical
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '−': '-',
+	'∕': '/', '⁄': '/', '⧸': '/',
+	'‘': '\'', '’': '\'', '“': '"', '”': '"',
+	'\u00A0': ' ', '\u2000': ' ', '\u2001': ' ', '\u2002': ' ', '\u2003': ' ',
+	'\u2004': ' ', '\u2005': ' ', '\u2006': ' ', '\u2007': ' ', '\u2008': ' ',
+	'\u2009': ' ', '\u200A': ' ', '\u202F': ' ', '\u205F': ' ', '\u3000': ' ',
+}
+
+// isInvisibleRune reports zero-width characters that render as nothing.
+func isInvisibleRune(r rune) bool {
+	switch r {
+	case '\u200B', '\u200C', '\u200D', '\u2060', '\uFEFF', '\u180E', '\u00AD', '\u034F':
+		return true
+	}
+	return false
+}
+
+// isBidiControl reports characters that reorder how surrounding text is displayed.
+func isBidiControl(r rune) bool {
+	switch {
+	case r >= '\u202A' && r <= '\u202E':
+		return true
+	case r >= '\u2066' && r <= '\u2069':
+		return true
+	case r == '\u200E', r == '\u200F', r == '\u061C':
+		return true
+	}
+	return false
+}
+
+// confusableOf returns the ASCII look-alike for r, including fullwidth forms.
+func confusableOf(r rune) (rune, bool) {
+	if ascii, ok := confusables[r]; ok {
+		return ascii, true
+	}
+	if r >= '！' && r <= '～' {
+		return r - 0xFEE0, true
+	}
+	return 0, false
+}
+
+// detectUnicodeTricks reports bidi controls, invisible characters and ASCII
+// look-alikes that make a command display differently from what runs.
+// Look-alikes only count when a word would read as plain ASCII (so Cyrillic
+// or Greek prose is left alone) and punctuation look-alikes only outside quotes.
+func detectUnicodeTricks(line string, lineNum int) []Finding {
+	var bidi, invisible, lookalike []string
+	for _, r := range line {
+		switch {
+		case r < 0x80:
+		case isBidiControl(r):
+			bidi = appendUnique(bidi, fmt.Sprintf("U+%04X", r))
+		case isInvisibleRune(r):
+			invisible = appendUnique(invisible, fmt.Sprintf("U+%04X", r))
+		}
+	}
+
+	var quote rune
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		readsAsASCII := true
+		var found []rune
+		for _, r := range word {
+			if r == '\'' || r == '"' {
+				if quote == 0 {
+					quote = r
+				} else if quote == r {
+					quote = 0
+				}
+				continue
+			}
+			if r < 0x80 || isBidiControl(r) || isInvisibleRune(r) {
+				continue
+			}
+			ascii, ok := confusableOf(r)
+			switch {
+			case !ok:
+				readsAsASCII = false
+			case unicode.IsLetter(ascii) || unicode.IsDigit(ascii):
+				found = append(found, r)
+			case quote == 0:
+				lookalike = appendUnique(lookalike, fmt.Sprintf("%q looks like %q (U+%04X)", r, ascii, r))
+			}
+		}
+		if readsAsASCII {
+			for _, r := range found {
+				ascii, _ := confusableOf(r)
+				lookalike = appendUnique(lookalike, fmt.Sprintf("%q looks like %q (U+%04X)", r, ascii, r))
+			}
+		}
+	}
+
+	var findings []Finding
+	if len(bidi) > 0 {
+		findings = append(findings, Finding{
+			Severity:       "critical",
+			Code:           "UNICODE_SPOOFING",
+			Description:    "Bidirectional control characters reorder the displayed command: " + strings.Join(bidi, ", "),
+			Line:           lineNum,
+			Recommendation: "BLOCK this command. Review the escaped form; what you see is not what runs.",
+		})
+	}
+	if len(invisible) > 0 {
+		findings = append(findings, Finding{
+			Severity:       "high",
+			Code:           "UNICODE_SPOOFING",
+			Description:    "Invisible zero-width characters in command: " + strings.Join(invisible, ", "),
+			Line:           lineNum,
+			Recommendation: "Retype the command; hidden characters change which files or programs are targeted.",
+		})
+	}
+	if len(lookalike) > 0 {
+		findings = append(findings, Finding{
+			Severity:       "high",
+			Code:           "UNICODE_SPOOFING",
+			Description:    "Look-alike characters in command: " + strings.Join(lookalike, ", "),
+			Line:           lineNum,
+			Recommendation: "Replace look-alike characters with plain ASCII before running. Commands pasted from LLM output often carry them.",
+		})
+	}
+	return findings
+}
+
+// HasDeceptiveUnicode reports whether s contains bidi controls, invisible
+// characters or ASCII look-alikes.
+func HasDeceptiveUnicode(s string) bool {
+	for _, r := range s {
+		if r < 0x80 {
+			continue
+		}
+		if isBidiControl(r) || isInvisibleRune(r) {
+			return true
+		}
+		if _, ok := confusableOf(r); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EscapeUnicode renders every non-ASCII or non-printable rune as \u{XXXX},
+// so the exact bytes of a command are visible in a terminal.
+func EscapeUnicode(s string) string {
+	var out strings.Builder
+	for _, r := range s {
+		if r < 0x80 && (unicode.IsPrint(r) || r == ' ') {
+			out.WriteRune(r)
+			continue
+		}
+		fmt.Fprintf(&out, `\u{%04X}`, r)
+	}
+	return out.String()
+}
+
+// NormalizeUnicode returns what a reader would believe s says: look-alikes are
+// mapped to ASCII and invisible and bidi characters are dropped.
+func NormalizeUnicode(s string) string {
+	var out strings.Builder
+	for _, r := range s {
+		if isBidiControl(r) || isInvisibleRune(r) {
+			continue
+		}
+		if ascii, ok := confusableOf(r); ok {
+			out.WriteRune(ascii)
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+func appendUnique(list []string, item string) []string {
+	for _, existing := range list {
+		if existing == item {
+			return list
+		}
+	}
+	return append(list, item)
+}
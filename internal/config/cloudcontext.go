@@ -0,0 +1,304 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveCloudTargets fills in the Kubernetes context/namespace, AWS profile,
+// gcloud configuration/project and terraform workspace the command will act
+// on. Flags on the command (--context, -n, --profile, --configuration,
+// --project) take precedence over environment variables, which take
+// precedence over local config files. Only tools present in args are resolved.
+func (c *DetectionContext) ResolveCloudTargets(args []string) {
+	tools := make(map[string]bool)
+	for _, arg := range args {
+		tools[filepath.Base(arg)] = true
+	}
+
+	if tools["kubectl"] || tools["helm"] || tools["oc"] {
+		c.resolveKube(args)
+	}
+	if tools["aws"] {
+		c.AWSProfile = firstNonEmpty(cloudFlag(args, "--profile"), c.getenv("AWS_PROFILE"), c.getenv("AWS_DEFAULT_PROFILE"), "default")
+	}
+	if tools["gcloud"] || tools["gsutil"] || tools["bq"] {
+		c.resolveGCloud(args)
+	}
+	if tools["terraform"] || tools["tofu"] || tools["terragrunt"] {
+		c.TerraformWorkspace = firstNonEmpty(c.getenv("TF_WORKSPACE"), c.readTrimmed(filepath.Join(c.dir(), ".terraform", "environment")), "default")
+	}
+}
+
+// CloudTargets describes the resolved targets for display, e.g. in the approval prompt.
+func (c DetectionContext) CloudTargets() []string {
+	var targets []string
+	if c.KubeContext != "" {
+		target := "kube context " + c.KubeContext
+		if c.KubeNamespace != "" {
+			target += ", namespace " + c.KubeNamespace
+		}
+		targets = append(targets, target)
+	}
+	if c.AWSProfile != "" {
+		targets = append(targets, "AWS profile "+c.AWSProfile)
+	}
+	if c.GCloudConfig != "" || c.GCloudProject != "" {
+		target := "gcloud configuration " + firstNonEmpty(c.GCloudConfig, "default")
+		if c.GCloudProject != "" {
+			target += ", project " + c.GCloudProject
+		}
+		targets = append(targets, target)
+	}
+	if c.TerraformWorkspace != "" {
+		targets = append(targets, "terraform workspace "+c.TerraformWorkspace)
+	}
+	return targets
+}
+
+// ProductionTarget returns the first resolved target whose name matches a
+// production keyword, formatted for display ("" if none).
+func (c DetectionContext) ProductionTarget(keywords []string) string {
+	candidates := []struct{ kind, value string }{
+		{"kube context", c.KubeContext},
+		{"kube namespace", c.KubeNamespace},
+		{"AWS profile", c.AWSProfile},
+		{"gcloud configuration", c.GCloudConfig},
+		{"gcloud project", c.GCloudProject},
+		{"terraform workspace", c.TerraformWorkspace},
+	}
+	for _, candidate := range candidates {
+		if candidate.value == "" {
+			continue
+		}
+		for _, keyword := range keywords {
+			if hasKeywordToken(candidate.value, keyword) {
+				return fmt.Sprintf("%s %s", candidate.kind, candidate.value)
+			}
+		}
+	}
+	return ""
+}
+
+func (c *DetectionContext) resolveKube(args []string) {
+	c.KubeContext = cloudFlag(args, "--context", "--kube-context")
+	c.KubeNamespace = cloudFlag(args, "-n", "--namespace")
+
+	kubeconfig := cloudFlag(args, "--kubeconfig")
+	var files []string
+	if kubeconfig != "" {
+		files = []string{kubeconfig}
+	} else if env := c.getenv("KUBECONFIG"); env != "" {
+		files = filepath.SplitList(env)
+	} else if home := c.home(); home != "" {
+		files = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	// The first file that sets current-context wins, as with kubectl
+	if c.KubeContext == "" {
+		for _, file := range files {
+			if current := kubeconfigCurrentContext(file); current != "" {
+				c.KubeContext = current
+				break
+			}
+		}
+	}
+	if c.KubeNamespace == "" && c.KubeContext != "" {
+		for _, file := range files {
+			if ns := kubeconfigContextNamespace(file, c.KubeContext); ns != "" {
+				c.KubeNamespace = ns
+				break
+			}
+		}
+	}
+	if c.KubeNamespace == "" && c.KubeContext != "" {
+		c.KubeNamespace = "default"
+	}
+}
+
+func (c *DetectionContext) resolveGCloud(args []string) {
+	c.GCloudConfig = firstNonEmpty(cloudFlag(args, "--configuration"), c.getenv("CLOUDSDK_ACTIVE_CONFIG_NAME"))
+	gcloudDir := firstNonEmpty(c.getenv("CLOUDSDK_CONFIG"), filepath.Join(c.home(), ".config", "gcloud"))
+	if c.GCloudConfig == "" {
+		c.GCloudConfig = firstNonEmpty(c.readTrimmed(filepath.Join(gcloudDir, "active_config")), "default")
+	}
+	c.GCloudProject = firstNonEmpty(cloudFlag(args, "--project"), c.getenv("CLOUDSDK_CORE_PROJECT"),
+		iniValue(filepath.Join(gcloudDir, "configurations", "config_"+c.GCloudConfig), "core", "project"))
+}
+
+// kubeconfigCurrentContext reads `current-context:` from a kubeconfig file
+func kubeconfigCurrentContext(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "current-context:") {
+			return trimYAMLValue(strings.TrimPrefix(line, "current-context:"))
+		}
+	}
+	return ""
+}
+
+// kubeconfigContextNamespace finds the namespace of the named context in the
+// `contexts:` list of a kubeconfig file.
+func kubeconfigContextNamespace(path, contextName string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inContexts := false
+	name, namespace := "", ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			// top-level key: entering or leaving the contexts list
+			if inContexts && name == contextName {
+				return namespace
+			}
+			inContexts = strings.HasPrefix(line, "contexts:")
+			name, namespace = "", ""
+			continue
+		}
+		if !inContexts {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			// new list item
+			if name == contextName {
+				return namespace
+			}
+			name, namespace = "", ""
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "- "))
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "name:"):
+			name = trimYAMLValue(strings.TrimPrefix(trimmed, "name:"))
+		case strings.HasPrefix(trimmed, "namespace:"):
+			namespace = trimYAMLValue(strings.TrimPrefix(trimmed, "namespace:"))
+		}
+	}
+	if inContexts && name == contextName {
+		return namespace
+	}
+	return ""
+}
+
+// iniValue reads key from [section] of an INI file (gcloud configurations)
+func iniValue(path, section, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// cloudFlag returns the value of a flag in `--flag value` or `--flag=value` form
+func cloudFlag(args []string, flags ...string) string {
+	for i, arg := range args {
+		for _, flag := range flags {
+			if arg == flag && i+1 < len(args) {
+				return args[i+1]
+			}
+			if strings.HasPrefix(arg, flag+"=") {
+				return strings.TrimPrefix(arg, flag+"=")
+			}
+		}
+	}
+	return ""
+}
+
+func (c DetectionContext) getenv(key string) string {
+	if c.Environment != nil {
+		if value, ok := c.Environment[key]; ok {
+			return value
+		}
+	}
+	return os.Getenv(key)
+}
+
+func (c DetectionContext) home() string {
+	if home := c.getenv("HOME"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+func (c DetectionContext) dir() string {
+	if c.WorkingDir != "" {
+		return c.WorkingDir
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+func (c DetectionContext) readTrimmed(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// hasKeywordToken matches keyword against the alphanumeric tokens of value, so
+// "gke_corp_prod-1" and "prod2" match "prod" but "product" does not.
+func hasKeywordToken(value, keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	if keyword == "" {
+		return false
+	}
+	tokens := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	for _, tok := range tokens {
+		if strings.HasPrefix(tok, keyword) && strings.Trim(tok[len(keyword):], "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func trimYAMLValue(value string) string {
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
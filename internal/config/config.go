@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +19,12 @@ type Config struct {
 	GuardLevel           GuardLevelConfig         `yaml:"guard_level" toml:"guard_level" json:"guard_level"`
 	ProductionIndicators ProductionIndicatorsConfig `yaml:"production_indicators" toml:"production_indicators" json:"production_indicators"`
 	Sandbox              SandboxConfig            `yaml:"sandbox" toml:"sandbox" json:"sandbox"`
+	PipeToShell          PipeToShellConfig        `yaml:"pipe_to_shell" toml:"pipe_to_shell" json:"pipe_to_shell"`
+	Terraform            TerraformConfig          `yaml:"terraform" toml:"terraform" json:"terraform"`
+	ProtectedPaths       []ProtectedPath          `yaml:"protected_paths" toml:"protected_paths" json:"protected_paths"`
+	Snapshots            SnapshotConfig           `yaml:"snapshots" toml:"snapshots" json:"snapshots"`
+	Timeouts             TimeoutConfig            `yaml:"timeouts" toml:"timeouts" json:"timeouts"`
+	Tracing              TracingConfig            `yaml:"tracing" toml:"tracing" json:"tracing"`
 }
 
 // LoggingConfig controls output formatting.
@@ -73,6 +80,52 @@ type ProductionIndicatorsConfig struct {
 	Keywords []string `yaml:"keywords" toml:"keywords" json:"keywords"`
 }
 
+// PipeToShellConfig controls how `curl ... | sh` pipelines are handled by exec.
+// When enabled, the script is downloaded and analyzed before anything runs.
+type PipeToShellConfig struct {
+	Enabled      bool     `yaml:"enabled" toml:"enabled" json:"enabled"`
+	MaxSizeKB    int      `yaml:"max_size_kb" toml:"max_size_kb" json:"max_size_kb"`       // Refuse larger downloads
+	PinnedHashes []string `yaml:"pinned_hashes" toml:"pinned_hashes" json:"pinned_hashes"` // sha256 of known-good scripts (run without prompting)
+}
+
+// TerraformConfig controls analysis of terraform plans. When enabled, exec runs
+// `terraform show -json` on the saved plan passed to `terraform apply` and
+// reports resources the plan deletes or replaces.
+type TerraformConfig struct {
+	Enabled            bool     `yaml:"enabled" toml:"enabled" json:"enabled"`
+	SensitiveResources []string `yaml:"sensitive_resources" toml:"sensitive_resources" json:"sensitive_resources"` // Resource type globs whose delete/replace is critical
+}
+
+// SnapshotConfig controls workspace snapshots taken before risky commands run
+// on the host. `vectra-guard undo` restores them.
+type SnapshotConfig struct {
+	Enabled   bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+	MinRisk   string `yaml:"min_risk" toml:"min_risk" json:"min_risk"`          // Snapshot before host commands at or above this risk
+	MaxSizeMB int    `yaml:"max_size_mb" toml:"max_size_mb" json:"max_size_mb"` // Skip archive snapshots of larger non-git workspaces
+	Keep      int    `yaml:"keep" toml:"keep" json:"keep"`                      // Snapshots kept per workspace
+	Dir       string `yaml:"dir" toml:"dir" json:"dir"`                         // Defaults to ~/.vectra-guard/snapshots
+}
+
+// TimeoutConfig overrides sandbox.timeout by risk level and by command. All
+// values are in seconds; zero falls back to the next broader setting.
+type TimeoutConfig struct {
+	KillGrace int            `yaml:"kill_grace" toml:"kill_grace" json:"kill_grace"` // From SIGTERM to SIGKILL once a command times out
+	Low       int            `yaml:"low" toml:"low" json:"low"`
+	Medium    int            `yaml:"medium" toml:"medium" json:"medium"`
+	High      int            `yaml:"high" toml:"high" json:"high"`
+	Critical  int            `yaml:"critical" toml:"critical" json:"critical"`
+	Commands  map[string]int `yaml:"commands" toml:"commands" json:"commands"` // Command prefix, e.g. "npm install", to timeout; the longest match wins
+}
+
+// TracingConfig controls tracing of the programs a sandboxed command runs.
+// Each one is analyzed and recorded in the session as it starts.
+type TracingConfig struct {
+	Enabled        bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+	Mode           string `yaml:"mode" toml:"mode" json:"mode"`                                     // auto (ptrace where the runtime allows it) or poll (/proc)
+	PollIntervalMS int    `yaml:"poll_interval_ms" toml:"poll_interval_ms" json:"poll_interval_ms"` // How often /proc is polled
+	OnCritical     string `yaml:"on_critical" toml:"on_critical" json:"on_critical"`                // kill the sandbox, or record only
+}
+
 // SandboxMode determines when to use sandboxing
 type SandboxMode string
 
@@ -100,6 +153,7 @@ const (
 	SandboxRuntimeAuto       SandboxRuntime = "auto"       // Auto-detect best runtime
 	SandboxRuntimeBubblewrap SandboxRuntime = "bubblewrap" // Use bubblewrap (fast)
 	SandboxRuntimeNamespace  SandboxRuntime = "namespace"  // Use custom namespaces
+	SandboxRuntimeLandlock   SandboxRuntime = "landlock"   // Use Landlock (no namespaces needed)
 	SandboxRuntimeDocker     SandboxRuntime = "docker"     // Use Docker (compatible)
 	SandboxRuntimePodman     SandboxRuntime = "podman"     // Use Podman
 )
@@ -112,7 +166,7 @@ type SandboxConfig struct {
 	SecurityLevel  SandboxSecurityLevel `yaml:"security_level" toml:"security_level" json:"security_level"`
 	
 	// Runtime configuration
-	Runtime        string `yaml:"runtime" toml:"runtime" json:"runtime"` // auto, bubblewrap, namespace, docker, podman
+	Runtime        string `yaml:"runtime" toml:"runtime" json:"runtime"` // auto, bubblewrap, namespace, landlock, docker, podman
 	Image          string `yaml:"image" toml:"image" json:"image"`       // Docker/Podman image
 	Timeout        int    `yaml:"timeout" toml:"timeout" json:"timeout"` // seconds
 	
@@ -132,12 +186,20 @@ type SandboxConfig struct {
 	// Filesystem configuration
 	ReadOnlyPaths  []string `yaml:"read_only_paths" toml:"read_only_paths" json:"read_only_paths"`   // Read-only filesystem paths
 	WorkspaceDir   string   `yaml:"workspace_dir" toml:"workspace_dir" json:"workspace_dir"`         // Workspace directory
+	WorkspaceMode  string   `yaml:"workspace_mode" toml:"workspace_mode" json:"workspace_mode"`      // bind, overlay (copy-on-write, changes reviewed)
+	HashFileChanges bool    `yaml:"hash_file_changes" toml:"hash_file_changes" json:"hash_file_changes"` // Hash contents for the file change report
 	
 	// Security profiles
 	SeccompProfile string   `yaml:"seccomp_profile" toml:"seccomp_profile" json:"seccomp_profile"` // strict, moderate, minimal, none
 	CapabilitySet  string   `yaml:"capability_set" toml:"capability_set" json:"capability_set"`    // none, minimal, normal
 	UseOverlayFS   bool     `yaml:"use_overlayfs" toml:"use_overlayfs" json:"use_overlayfs"`       // Use OverlayFS (namespace only)
 	
+	// Resource limits (override the security level's defaults)
+	MemoryLimit    string `yaml:"memory_limit" toml:"memory_limit" json:"memory_limit"` // e.g. "512m"
+	CPULimit       string `yaml:"cpu_limit" toml:"cpu_limit" json:"cpu_limit"`          // CPUs, e.g. "0.5"
+	PidsLimit      int    `yaml:"pids_limit" toml:"pids_limit" json:"pids_limit"`       // Max processes
+	IOLimit        string `yaml:"io_limit" toml:"io_limit" json:"io_limit"`             // Disk bytes/s, e.g. "50m"
+	
 	// Environment
 	EnvWhitelist   []string `yaml:"env_whitelist" toml:"env_whitelist" json:"env_whitelist"`
 	
@@ -196,7 +258,7 @@ func DefaultConfig() Config {
 			Enabled:         true,
 			Mode:            SandboxModeAuto,
 			SecurityLevel:   SandboxSecurityBalanced,
-			Runtime:         "auto", // auto, bubblewrap, namespace, docker, podman
+			Runtime:         "auto", // auto, bubblewrap, namespace, landlock, docker, podman
 			Image:           "ubuntu:22.04",
 			Timeout:         300, // 5 minutes
 			AutoDetectEnv:   true, // Auto-detect dev/CI
@@ -208,6 +270,7 @@ func DefaultConfig() Config {
 			AllowNetwork:    false, // Block network by default
 			ReadOnlyPaths:   []string{}, // Will use defaults if empty
 			WorkspaceDir:    "", // Will use current directory by default
+			WorkspaceMode:   "bind",
 			SeccompProfile:  "moderate", // strict, moderate, minimal, none
 			CapabilitySet:   "minimal",  // none, minimal, normal
 			UseOverlayFS:    true, // Use OverlayFS for /tmp
@@ -221,6 +284,46 @@ func DefaultConfig() Config {
 			ShowRuntimeInfo: false, // Don't spam user by default
 			TrustStorePath:  "",
 		},
+		PipeToShell: PipeToShellConfig{
+			Enabled:      false, // Opt-in: rewrite pipelines to fetch, analyze, then run
+			MaxSizeKB:    1024,
+			PinnedHashes: []string{},
+		},
+		Terraform: TerraformConfig{
+			Enabled: true,
+			SensitiveResources: []string{
+				// Databases and data stores
+				"*_db_instance", "*_db_cluster", "*_rds_cluster*", "*_dynamodb_table", "*_elasticache_*",
+				"*_sql_database*", "*_sql_server", "*_spanner_*", "*_bigtable_*", "*_redshift_cluster",
+				"*_docdb_*", "*_cosmosdb_*",
+				// Buckets and volumes
+				"*_s3_bucket", "*_storage_bucket", "*_storage_account", "*_efs_file_system", "*_ebs_volume",
+				// Identity and secrets
+				"*_iam_*", "*_kms_key", "*_kms_crypto_key", "*_secretsmanager_secret", "*_secret_manager_secret", "*_key_vault",
+			},
+		},
+		ProtectedPaths: []ProtectedPath{
+			// Agents must not rewrite their own policy
+			{Path: "vectra-guard.yaml", Mode: ProtectNoWrite},
+			{Path: "vectra-guard.toml", Mode: ProtectNoWrite},
+			{Path: "~/.config/vectra-guard", Mode: ProtectNoWrite},
+		},
+		Snapshots: SnapshotConfig{
+			Enabled:   false, // Opt-in: costs a git commit or a tarball per risky command
+			MinRisk:   "high",
+			MaxSizeMB: 500,
+			Keep:      20,
+		},
+		Timeouts: TimeoutConfig{
+			KillGrace: 10,
+			Commands:  map[string]int{},
+		},
+		Tracing: TracingConfig{
+			Enabled:        false, // Opt-in: ptrace slows fork-heavy commands down
+			Mode:           "auto",
+			PollIntervalMS: 50,
+			OnCritical:     "kill",
+		},
 	}
 }
 
@@ -371,12 +474,27 @@ func merge(dst *Config, src Config) {
 	if src.Sandbox.WorkspaceDir != "" {
 		dst.Sandbox.WorkspaceDir = src.Sandbox.WorkspaceDir
 	}
+	if src.Sandbox.WorkspaceMode != "" {
+		dst.Sandbox.WorkspaceMode = src.Sandbox.WorkspaceMode
+	}
 	if src.Sandbox.SeccompProfile != "" {
 		dst.Sandbox.SeccompProfile = src.Sandbox.SeccompProfile
 	}
 	if src.Sandbox.CapabilitySet != "" {
 		dst.Sandbox.CapabilitySet = src.Sandbox.CapabilitySet
 	}
+	if src.Sandbox.MemoryLimit != "" {
+		dst.Sandbox.MemoryLimit = src.Sandbox.MemoryLimit
+	}
+	if src.Sandbox.CPULimit != "" {
+		dst.Sandbox.CPULimit = src.Sandbox.CPULimit
+	}
+	if src.Sandbox.PidsLimit > 0 {
+		dst.Sandbox.PidsLimit = src.Sandbox.PidsLimit
+	}
+	if src.Sandbox.IOLimit != "" {
+		dst.Sandbox.IOLimit = src.Sandbox.IOLimit
+	}
 	if src.Sandbox.TrustStorePath != "" {
 		dst.Sandbox.TrustStorePath = src.Sandbox.TrustStorePath
 	}
@@ -399,9 +517,78 @@ func merge(dst *Config, src Config) {
 	dst.Sandbox.EnableCache = src.Sandbox.EnableCache
 	dst.Sandbox.AllowNetwork = src.Sandbox.AllowNetwork
 	dst.Sandbox.UseOverlayFS = src.Sandbox.UseOverlayFS
+	dst.Sandbox.HashFileChanges = src.Sandbox.HashFileChanges
 	dst.Sandbox.EnableMetrics = src.Sandbox.EnableMetrics
 	dst.Sandbox.LogOutput = src.Sandbox.LogOutput
 	dst.Sandbox.ShowRuntimeInfo = src.Sandbox.ShowRuntimeInfo
+
+	// Merge pipe-to-shell handling
+	dst.PipeToShell.Enabled = src.PipeToShell.Enabled
+	if src.PipeToShell.MaxSizeKB > 0 {
+		dst.PipeToShell.MaxSizeKB = src.PipeToShell.MaxSizeKB
+	}
+	if len(src.PipeToShell.PinnedHashes) > 0 {
+		dst.PipeToShell.PinnedHashes = src.PipeToShell.PinnedHashes
+	}
+
+	// Merge terraform plan analysis
+	dst.Terraform.Enabled = src.Terraform.Enabled
+	if len(src.Terraform.SensitiveResources) > 0 {
+		dst.Terraform.SensitiveResources = src.Terraform.SensitiveResources
+	}
+
+	// Merge snapshot settings
+	dst.Snapshots.Enabled = src.Snapshots.Enabled
+	if src.Snapshots.MinRisk != "" {
+		dst.Snapshots.MinRisk = src.Snapshots.MinRisk
+	}
+	if src.Snapshots.MaxSizeMB > 0 {
+		dst.Snapshots.MaxSizeMB = src.Snapshots.MaxSizeMB
+	}
+	if src.Snapshots.Keep > 0 {
+		dst.Snapshots.Keep = src.Snapshots.Keep
+	}
+	if src.Snapshots.Dir != "" {
+		dst.Snapshots.Dir = src.Snapshots.Dir
+	}
+
+	// Merge timeouts; per-command timeouts accumulate
+	if src.Timeouts.KillGrace > 0 {
+		dst.Timeouts.KillGrace = src.Timeouts.KillGrace
+	}
+	if src.Timeouts.Low > 0 {
+		dst.Timeouts.Low = src.Timeouts.Low
+	}
+	if src.Timeouts.Medium > 0 {
+		dst.Timeouts.Medium = src.Timeouts.Medium
+	}
+	if src.Timeouts.High > 0 {
+		dst.Timeouts.High = src.Timeouts.High
+	}
+	if src.Timeouts.Critical > 0 {
+		dst.Timeouts.Critical = src.Timeouts.Critical
+	}
+	for prefix, seconds := range src.Timeouts.Commands {
+		if dst.Timeouts.Commands == nil {
+			dst.Timeouts.Commands = map[string]int{}
+		}
+		dst.Timeouts.Commands[prefix] = seconds
+	}
+
+	// Merge tracing
+	dst.Tracing.Enabled = src.Tracing.Enabled
+	if src.Tracing.Mode != "" {
+		dst.Tracing.Mode = src.Tracing.Mode
+	}
+	if src.Tracing.PollIntervalMS > 0 {
+		dst.Tracing.PollIntervalMS = src.Tracing.PollIntervalMS
+	}
+	if src.Tracing.OnCritical != "" {
+		dst.Tracing.OnCritical = src.Tracing.OnCritical
+	}
+
+	// Protected paths accumulate so project config can't drop user-level protections
+	dst.ProtectedPaths = append(dst.ProtectedPaths, src.ProtectedPaths...)
 }
 
 func exists(path string) bool {
@@ -438,6 +625,28 @@ func decodeYAML(data []byte) (Config, error) {
 			case "sandbox":
 				mode = "sandbox"
 				listTarget = nil
+			case "pipe_to_shell":
+				mode = "pipe_to_shell"
+				listTarget = nil
+			case "terraform":
+				mode = "terraform"
+				listTarget = nil
+			case "protected_paths":
+				mode = "protected_paths"
+				listTarget = nil
+			case "snapshots":
+				mode = "snapshots"
+				listTarget = nil
+			case "timeouts":
+				mode = "timeouts"
+				listTarget = nil
+			case "tracing":
+				mode = "tracing"
+				listTarget = nil
+			case "commands":
+				if mode == "timeouts" {
+					mode = "timeout_commands"
+				}
 			case "allowlist":
 				if mode == "policies" {
 					listTarget = &cfg.Policies.Allowlist
@@ -458,11 +667,30 @@ func decodeYAML(data []byte) (Config, error) {
 				if mode == "production_indicators" {
 					listTarget = &cfg.ProductionIndicators.Keywords
 				}
+			case "pinned_hashes":
+				if mode == "pipe_to_shell" {
+					listTarget = &cfg.PipeToShell.PinnedHashes
+				}
+			case "sensitive_resources":
+				if mode == "terraform" {
+					listTarget = &cfg.Terraform.SensitiveResources
+				}
 			}
 			continue
 		}
 
 		if strings.HasPrefix(line, "- ") {
+			if mode == "protected_paths" {
+				// `- path: glob` starts an entry (mode on the next line); a bare `- glob` is no-write
+				item := strings.TrimSpace(strings.TrimPrefix(line, "- "))
+				entry := ProtectedPath{Path: item, Mode: ProtectNoWrite}
+				if strings.HasPrefix(item, "path:") {
+					entry.Path = strings.TrimSpace(strings.TrimPrefix(item, "path:"))
+				}
+				entry.Path = strings.Trim(entry.Path, `"'`)
+				cfg.ProtectedPaths = append(cfg.ProtectedPaths, entry)
+				continue
+			}
 			if listTarget != nil {
 				item := strings.TrimSpace(strings.TrimPrefix(line, "- "))
 				item = strings.Trim(item, `"'`)
@@ -519,6 +747,10 @@ func decodeYAML(data []byte) (Config, error) {
 					cfg.Sandbox.Runtime = value
 				case "image":
 					cfg.Sandbox.Image = value
+				case "timeout":
+					if n, err := yamlInt(value); err == nil {
+						cfg.Sandbox.Timeout = n
+					}
 				case "network_mode":
 					cfg.Sandbox.NetworkMode = value
 				case "enable_cache":
@@ -531,6 +763,91 @@ func decodeYAML(data []byte) (Config, error) {
 					cfg.Sandbox.TrustStorePath = value
 				case "seccomp_profile":
 					cfg.Sandbox.SeccompProfile = value
+				case "workspace_mode":
+					cfg.Sandbox.WorkspaceMode = value
+				case "hash_file_changes":
+					cfg.Sandbox.HashFileChanges = value == "true"
+				case "memory_limit":
+					cfg.Sandbox.MemoryLimit = value
+				case "cpu_limit":
+					cfg.Sandbox.CPULimit = value
+				case "pids_limit":
+					if n, err := strconv.Atoi(value); err == nil {
+						cfg.Sandbox.PidsLimit = n
+					}
+				case "io_limit":
+					cfg.Sandbox.IOLimit = value
+				}
+			case "pipe_to_shell":
+				switch key {
+				case "enabled":
+					cfg.PipeToShell.Enabled = value == "true"
+				case "max_size_kb":
+					if n, err := strconv.Atoi(value); err == nil {
+						cfg.PipeToShell.MaxSizeKB = n
+					}
+				}
+			case "terraform":
+				if key == "enabled" {
+					cfg.Terraform.Enabled = value == "true"
+				}
+			case "snapshots":
+				switch key {
+				case "enabled":
+					cfg.Snapshots.Enabled = value == "true"
+				case "min_risk":
+					cfg.Snapshots.MinRisk = value
+				case "max_size_mb":
+					if n, err := strconv.Atoi(value); err == nil {
+						cfg.Snapshots.MaxSizeMB = n
+					}
+				case "keep":
+					if n, err := strconv.Atoi(value); err == nil {
+						cfg.Snapshots.Keep = n
+					}
+				case "dir":
+					cfg.Snapshots.Dir = value
+				}
+			case "timeouts", "timeout_commands":
+				n, err := yamlInt(value)
+				if err != nil {
+					continue
+				}
+				switch key {
+				case "kill_grace":
+					cfg.Timeouts.KillGrace = n
+				case "low":
+					cfg.Timeouts.Low = n
+				case "medium":
+					cfg.Timeouts.Medium = n
+				case "high":
+					cfg.Timeouts.High = n
+				case "critical":
+					cfg.Timeouts.Critical = n
+				default:
+					if mode == "timeout_commands" {
+						if cfg.Timeouts.Commands == nil {
+							cfg.Timeouts.Commands = map[string]int{}
+						}
+						cfg.Timeouts.Commands[strings.Trim(key, `"'`)] = n
+					}
+				}
+			case "tracing":
+				switch key {
+				case "enabled":
+					cfg.Tracing.Enabled = value == "true"
+				case "mode":
+					cfg.Tracing.Mode = value
+				case "poll_interval_ms":
+					if n, err := yamlInt(value); err == nil {
+						cfg.Tracing.PollIntervalMS = n
+					}
+				case "on_critical":
+					cfg.Tracing.OnCritical = value
+				}
+			case "protected_paths":
+				if key == "mode" && len(cfg.ProtectedPaths) > 0 {
+					cfg.ProtectedPaths[len(cfg.ProtectedPaths)-1].Mode = ProtectionMode(value)
 				}
 			}
 		}
@@ -539,6 +856,15 @@ func decodeYAML(data []byte) (Config, error) {
 	return cfg, scanner.Err()
 }
 
+// yamlInt parses an integer value, ignoring a trailing comment such as
+// "600  # 10 minutes"
+func yamlInt(value string) (int, error) {
+	if i := strings.Index(value, "#"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return strconv.Atoi(value)
+}
+
 func decodeTOML(data []byte) (Config, error) {
 	var cfg Config
 	scanner := bufio.NewScanner(strings.NewReader(string(data)))
@@ -625,6 +951,14 @@ type DetectionContext struct {
 	GitBranch    string
 	WorkingDir   string
 	Environment  map[string]string
+
+	// Cloud targets resolved by ResolveCloudTargets
+	KubeContext        string
+	KubeNamespace      string
+	AWSProfile         string
+	GCloudConfig       string
+	GCloudProject      string
+	TerraformWorkspace string
 }
 
 // DetectGuardLevel analyzes the context and returns the appropriate guard level
@@ -659,6 +993,11 @@ func DetectGuardLevel(cfg Config, ctx DetectionContext) GuardLevel {
 		}
 	}
 	
+	// Check resolved cluster/account/workspace names
+	if ctx.ProductionTarget(indicators.Keywords) != "" {
+		return GuardLevelHigh // Production cloud target = high
+	}
+	
 	// Check command string for production indicators
 	if ctx.Command != "" {
 		cmdLower := strings.ToLower(ctx.Command)
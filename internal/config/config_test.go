@@ -839,3 +839,335 @@ func writeFile(t *testing.T, path string, body string) {
 		t.Fatalf("write %s: %v", path, err)
 	}
 }
+
+func TestPipeToShellParsing(t *testing.T) {
+	yaml := `
+pipe_to_shell:
+  enabled: true
+  max_size_kb: 256
+  pinned_hashes:
+    - 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+`
+	cfg, err := decodeYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("decode yaml: %v", err)
+	}
+	if !cfg.PipeToShell.Enabled {
+		t.Error("expected pipe_to_shell to be enabled")
+	}
+	if cfg.PipeToShell.MaxSizeKB != 256 {
+		t.Errorf("expected max_size_kb 256, got %d", cfg.PipeToShell.MaxSizeKB)
+	}
+	if len(cfg.PipeToShell.PinnedHashes) != 1 {
+		t.Errorf("expected one pinned hash, got %v", cfg.PipeToShell.PinnedHashes)
+	}
+
+	if DefaultConfig().PipeToShell.Enabled {
+		t.Error("expected pipe_to_shell to be opt-in")
+	}
+}
+
+func TestResolveCloudTargets(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	kubeYAML := `apiVersion: v1
+clusters:
+- name: eks-prod
+contexts:
+- context:
+    cluster: eks-dev
+    user: dev
+  name: dev
+- context:
+    cluster: eks-prod
+    namespace: payments
+    user: admin
+  name: prod-eu
+current-context: prod-eu
+kind: Config
+`
+	if err := os.WriteFile(kubeconfig, []byte(kubeYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gcloudDir := filepath.Join(dir, "gcloud")
+	if err := os.MkdirAll(filepath.Join(gcloudDir, "configurations"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(gcloudDir, "active_config"), []byte("work\n"), 0o644)
+	os.WriteFile(filepath.Join(gcloudDir, "configurations", "config_work"), []byte("[core]\naccount = me@example.com\nproject = shop-prod\n"), 0o644)
+	if err := os.MkdirAll(filepath.Join(dir, ".terraform"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, ".terraform", "environment"), []byte("staging"), 0o644)
+
+	env := map[string]string{
+		"HOME":            dir,
+		"KUBECONFIG":      kubeconfig,
+		"AWS_PROFILE":     "billing",
+		"CLOUDSDK_CONFIG": gcloudDir,
+		"TF_WORKSPACE":    "",
+	}
+
+	ctx := DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"kubectl", "delete", "pod", "x"})
+	if ctx.KubeContext != "prod-eu" || ctx.KubeNamespace != "payments" {
+		t.Errorf("kube target = %q/%q, want prod-eu/payments", ctx.KubeContext, ctx.KubeNamespace)
+	}
+	if ctx.AWSProfile != "" {
+		t.Errorf("AWS profile should only be resolved for aws commands, got %q", ctx.AWSProfile)
+	}
+
+	ctx = DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"kubectl", "--context", "dev", "-n", "web", "apply", "-f", "x.yaml"})
+	if ctx.KubeContext != "dev" || ctx.KubeNamespace != "web" {
+		t.Errorf("flags should override kubeconfig, got %q/%q", ctx.KubeContext, ctx.KubeNamespace)
+	}
+
+	ctx = DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"kubectl", "--context=dev", "get", "pods"})
+	if ctx.KubeNamespace != "default" {
+		t.Errorf("context without namespace should resolve to default, got %q", ctx.KubeNamespace)
+	}
+
+	ctx = DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"aws", "s3", "ls"})
+	if ctx.AWSProfile != "billing" {
+		t.Errorf("AWS profile = %q, want billing", ctx.AWSProfile)
+	}
+
+	ctx = DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"gcloud", "compute", "instances", "list"})
+	if ctx.GCloudConfig != "work" || ctx.GCloudProject != "shop-prod" {
+		t.Errorf("gcloud target = %q/%q, want work/shop-prod", ctx.GCloudConfig, ctx.GCloudProject)
+	}
+
+	ctx = DetectionContext{WorkingDir: dir, Environment: env}
+	ctx.ResolveCloudTargets([]string{"terraform", "apply"})
+	if ctx.TerraformWorkspace != "staging" {
+		t.Errorf("terraform workspace = %q, want staging", ctx.TerraformWorkspace)
+	}
+}
+
+func TestProductionTarget(t *testing.T) {
+	keywords := DefaultConfig().ProductionIndicators.Keywords
+
+	tests := []struct {
+		ctx  DetectionContext
+		want string
+	}{
+		{DetectionContext{KubeContext: "gke_corp_prod-1", KubeNamespace: "default"}, "kube context gke_corp_prod-1"},
+		{DetectionContext{KubeContext: "dev", KubeNamespace: "production"}, "kube namespace production"},
+		{DetectionContext{AWSProfile: "prod2"}, "AWS profile prod2"},
+		{DetectionContext{GCloudProject: "product-analytics"}, ""},
+		{DetectionContext{TerraformWorkspace: "default"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.ctx.ProductionTarget(keywords); got != tt.want {
+			t.Errorf("ProductionTarget(%+v) = %q, want %q", tt.ctx, got, tt.want)
+		}
+	}
+}
+
+func TestDetectGuardLevelProductionCloudTarget(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GuardLevel.Level = GuardLevelAuto
+
+	ctx := DetectionContext{Command: "kubectl get pods", KubeContext: "prod-eu", KubeNamespace: "default"}
+	if level := DetectGuardLevel(cfg, ctx); level != GuardLevelHigh {
+		t.Errorf("expected high guard level for production kube context, got %s", level)
+	}
+}
+
+func TestTerraformParsing(t *testing.T) {
+	body := `
+terraform:
+  enabled: false
+  sensitive_resources:
+    - "*_db_instance"
+    - aws_route53_zone
+`
+	cfg, err := decodeYAML([]byte(body))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if cfg.Terraform.Enabled {
+		t.Error("expected terraform plan analysis to be disabled")
+	}
+	if len(cfg.Terraform.SensitiveResources) != 2 || cfg.Terraform.SensitiveResources[1] != "aws_route53_zone" {
+		t.Errorf("unexpected sensitive_resources: %v", cfg.Terraform.SensitiveResources)
+	}
+
+	defaults := DefaultConfig().Terraform
+	if !defaults.Enabled || len(defaults.SensitiveResources) == 0 {
+		t.Error("expected terraform plan analysis enabled with default sensitive resources")
+	}
+}
+
+func TestProtectedPathsParsing(t *testing.T) {
+	body := `
+protected_paths:
+  - path: "migrations/**"
+    mode: no-delete
+  - path: infra/prod
+    mode: no-read
+  - .github/workflows
+`
+	cfg, err := decodeYAML([]byte(body))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []ProtectedPath{
+		{Path: "migrations/**", Mode: ProtectNoDelete},
+		{Path: "infra/prod", Mode: ProtectNoRead},
+		{Path: ".github/workflows", Mode: ProtectNoWrite},
+	}
+	if len(cfg.ProtectedPaths) != len(want) {
+		t.Fatalf("protected_paths = %v, want %v", cfg.ProtectedPaths, want)
+	}
+	for i := range want {
+		if cfg.ProtectedPaths[i] != want[i] {
+			t.Errorf("protected_paths[%d] = %v, want %v", i, cfg.ProtectedPaths[i], want[i])
+		}
+	}
+
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	if len(merged.ProtectedPaths) != len(DefaultConfig().ProtectedPaths)+len(want) {
+		t.Errorf("expected project protected paths to add to the defaults, got %v", merged.ProtectedPaths)
+	}
+}
+
+func TestProtectionFor(t *testing.T) {
+	paths := []ProtectedPath{
+		{Path: "migrations/**", Mode: ProtectNoDelete},
+		{Path: "infra/prod", Mode: ProtectNoRead},
+		{Path: "vectra-guard.yaml", Mode: ProtectNoWrite},
+		{Path: "~/.ssh", Mode: ProtectNoRead},
+	}
+	tests := []struct {
+		path string
+		mode ProtectionMode // "" for unprotected
+	}{
+		{"/ws/migrations/001_init.sql", ProtectNoDelete},
+		{"/ws/migrations", ProtectNoDelete},
+		{"/ws/migrations-old/x.sql", ""},
+		{"/ws/infra/prod/main.tf", ProtectNoRead},
+		{"/ws/infra/staging/main.tf", ""},
+		{"/ws/vectra-guard.yaml", ProtectNoWrite},
+		{"/ws/sub/vectra-guard.yaml", ProtectNoWrite},
+		{"/home/dev/.ssh/id_rsa", ProtectNoRead},
+		{"/other/migrations/x.sql", ""},
+		{"migrations/002.sql", ProtectNoDelete},
+	}
+	for _, tt := range tests {
+		pp, ok := ProtectionFor(paths, tt.path, "/ws", "/home/dev")
+		if got := map[bool]ProtectionMode{true: pp.Mode}[ok]; got != tt.mode {
+			t.Errorf("ProtectionFor(%s) = %q, want %q", tt.path, got, tt.mode)
+		}
+	}
+
+	if !ProtectNoRead.Denies(ProtectNoWrite) || !ProtectNoWrite.Denies(ProtectNoDelete) || ProtectNoDelete.Denies(ProtectNoWrite) || ProtectNoWrite.Denies(ProtectNoRead) {
+		t.Error("protection modes should imply the weaker ones only")
+	}
+}
+
+func TestSnapshotParsing(t *testing.T) {
+	body := `
+snapshots:
+  enabled: true
+  min_risk: medium
+  max_size_mb: 100
+  keep: 5
+`
+	cfg, err := decodeYAML([]byte(body))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	want := SnapshotConfig{Enabled: true, MinRisk: "medium", MaxSizeMB: 100, Keep: 5}
+	if merged.Snapshots != want {
+		t.Errorf("snapshots = %+v, want %+v", merged.Snapshots, want)
+	}
+}
+
+func TestWorkspaceModeParsing(t *testing.T) {
+	if got := DefaultConfig().Sandbox.WorkspaceMode; got != "bind" {
+		t.Errorf("default workspace_mode = %q, want bind", got)
+	}
+	cfg, err := decodeYAML([]byte("sandbox:\n  workspace_mode: overlay\n"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	if merged.Sandbox.WorkspaceMode != "overlay" {
+		t.Errorf("workspace_mode = %q, want overlay", merged.Sandbox.WorkspaceMode)
+	}
+}
+
+func TestResourceLimitParsing(t *testing.T) {
+	cfg, err := decodeYAML([]byte("sandbox:\n  memory_limit: 768m\n  cpu_limit: \"1.5\"\n  pids_limit: 200\n  io_limit: 20m\n"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	sb := merged.Sandbox
+	if sb.MemoryLimit != "768m" || sb.CPULimit != "1.5" || sb.PidsLimit != 200 || sb.IOLimit != "20m" {
+		t.Errorf("limits = %q %q %d %q", sb.MemoryLimit, sb.CPULimit, sb.PidsLimit, sb.IOLimit)
+	}
+}
+
+func TestTimeoutParsing(t *testing.T) {
+	yaml := `sandbox:
+  timeout: 600  # 10 minutes
+timeouts:
+  kill_grace: 5
+  critical: 30
+  commands:
+    "npm install": 900
+    terraform apply: 1800
+`
+	cfg, err := decodeYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	if merged.Sandbox.Timeout != 600 {
+		t.Errorf("sandbox timeout = %d, want 600", merged.Sandbox.Timeout)
+	}
+	tc := merged.Timeouts
+	if tc.KillGrace != 5 || tc.Critical != 30 || tc.High != 0 {
+		t.Errorf("timeouts = %+v", tc)
+	}
+	if tc.Commands["npm install"] != 900 || tc.Commands["terraform apply"] != 1800 {
+		t.Errorf("command timeouts = %v", tc.Commands)
+	}
+}
+
+func TestTracingParsing(t *testing.T) {
+	yaml := `tracing:
+  enabled: true
+  mode: poll
+  poll_interval_ms: 20
+  on_critical: record
+`
+	cfg, err := decodeYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	merged := DefaultConfig()
+	merge(&merged, cfg)
+	want := TracingConfig{Enabled: true, Mode: "poll", PollIntervalMS: 20, OnCritical: "record"}
+	if merged.Tracing != want {
+		t.Errorf("tracing = %+v, want %+v", merged.Tracing, want)
+	}
+
+	merged = DefaultConfig()
+	merge(&merged, Config{})
+	if merged.Tracing.Enabled || merged.Tracing.Mode != "auto" || merged.Tracing.PollIntervalMS != 50 || merged.Tracing.OnCritical != "kill" {
+		t.Errorf("default tracing = %+v", merged.Tracing)
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProtectionMode is what a protected path forbids. Each mode implies the
+// weaker ones: no-read also forbids writes and deletes, no-write forbids deletes.
+type ProtectionMode string
+
+const (
+	ProtectNoDelete ProtectionMode = "no-delete" // Contents may change, but the files can't be removed or renamed
+	ProtectNoWrite  ProtectionMode = "no-write"  // Read-only
+	ProtectNoRead   ProtectionMode = "no-read"   // Hidden entirely
+)
+
+// ProtectedPath declares files agents must not touch. Path is a glob relative
+// to the workspace root (or absolute, or starting with ~/). `**` matches any
+// number of directories, and a pattern without a slash matches the base name
+// at any depth, as in .gitignore. A matching directory protects everything under it.
+type ProtectedPath struct {
+	Path string         `yaml:"path" toml:"path" json:"path"`
+	Mode ProtectionMode `yaml:"mode" toml:"mode" json:"mode"`
+}
+
+func (m ProtectionMode) rank() int {
+	switch m {
+	case ProtectNoDelete:
+		return 1
+	case ProtectNoRead:
+		return 3
+	}
+	return 2 // no-write, and the default for an unrecognized mode
+}
+
+// Denies reports whether the mode forbids access, which is one of
+// ProtectNoDelete (deleting), ProtectNoWrite (writing) or ProtectNoRead (reading).
+func (m ProtectionMode) Denies(access ProtectionMode) bool {
+	return m.rank() >= access.rank()
+}
+
+// Matches reports whether p, or one of its parent directories, matches the
+// pattern. rel is p relative to the workspace ("" when p is outside it).
+func (pp ProtectedPath) Matches(p, rel, home string) bool {
+	pattern := filepath.ToSlash(pp.Path)
+	target := filepath.ToSlash(rel)
+	switch {
+	case strings.HasPrefix(pattern, "~/") && home != "":
+		pattern = filepath.ToSlash(home) + pattern[1:]
+		target = filepath.ToSlash(p)
+	case strings.HasPrefix(pattern, "/"):
+		target = filepath.ToSlash(p)
+	}
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+	if target == "" || target == "." || pattern == "" {
+		return false
+	}
+
+	for {
+		if matchProtectedGlob(pattern, target) {
+			return true
+		}
+		parent := path.Dir(target)
+		if parent == target || parent == "." || parent == "/" {
+			return false
+		}
+		target = parent
+	}
+}
+
+// matchProtectedGlob matches name against a slash-separated glob with `**`.
+func matchProtectedGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ProtectionFor returns the strictest mode among the protected paths matching
+// p (an absolute path) given the workspace root. ok is false when none match.
+func ProtectionFor(paths []ProtectedPath, p, workspace, home string) (ProtectedPath, bool) {
+	rel := ""
+	if !filepath.IsAbs(p) {
+		rel = p
+	} else if workspace != "" {
+		if r, err := filepath.Rel(workspace, p); err == nil && r != ".." && !strings.HasPrefix(r, "../") {
+			rel = r
+		}
+	}
+
+	var best ProtectedPath
+	found := false
+	for _, pp := range paths {
+		if !pp.Matches(p, rel, home) {
+			continue
+		}
+		if !found || pp.Mode.rank() > best.Mode.rank() {
+			best, found = pp, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,94 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FetchedScript is a remote script saved to disk so it can be reviewed before it runs
+type FetchedScript struct {
+	URL     string
+	Path    string
+	SHA256  string
+	Size    int64
+	Content []byte
+}
+
+// FetchScript downloads a script for the pipe-to-shell rewrite. The download
+// obeys the sandbox network policy: if sandboxed commands would get no
+// network, neither does the fetch.
+func (e *Executor) FetchScript(ctx context.Context, url string, maxBytes int64) (*FetchedScript, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return nil, fmt.Errorf("unsupported URL scheme: %s", url)
+	}
+
+	policy := e.buildSandboxConfig(ExecutionDecision{Mode: ExecutionModeSandbox})
+	if policy.NetworkMode == "none" {
+		return nil, fmt.Errorf("network access disabled by sandbox policy (security level %s)", e.config.Sandbox.SecurityLevel)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download script: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download script: unexpected status %s", resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+	if int64(len(content)) > maxBytes {
+		return nil, fmt.Errorf("script exceeds %d bytes", maxBytes)
+	}
+
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	dir := filepath.Join(e.cacheDir(), "scripts")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create script directory: %w", err)
+	}
+	path := filepath.Join(dir, digest+".sh")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return nil, fmt.Errorf("save script: %w", err)
+	}
+
+	return &FetchedScript{
+		URL:     url,
+		Path:    path,
+		SHA256:  digest,
+		Size:    int64(len(content)),
+		Content: content,
+	}, nil
+}
+
+// cacheDir returns the vectra-guard cache directory (bound into bubblewrap and namespace sandboxes)
+func (e *Executor) cacheDir() string {
+	if e.config.Sandbox.CacheDir != "" {
+		return e.config.Sandbox.CacheDir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "vectra-guard-cache")
+	}
+	return filepath.Join(homeDir, ".cache", "vectra-guard")
+}
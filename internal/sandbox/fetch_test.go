@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/logging"
+)
+
+func TestFetchScript(t *testing.T) {
+	body := "#!/bin/sh\necho installing\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	newExecutor := func(level config.SandboxSecurityLevel) *Executor {
+		cfg := config.DefaultConfig()
+		cfg.Sandbox.SecurityLevel = level
+		cfg.Sandbox.CacheDir = tmpDir
+		cfg.Sandbox.TrustStorePath = tmpDir + "/trust.json"
+		executor, err := NewExecutor(cfg, logging.NewLogger("text", os.Stderr))
+		if err != nil {
+			t.Fatalf("NewExecutor: %v", err)
+		}
+		return executor
+	}
+
+	t.Run("saves script with digest", func(t *testing.T) {
+		script, err := newExecutor(config.SandboxSecurityBalanced).FetchScript(context.Background(), server.URL+"/install.sh", 1024)
+		if err != nil {
+			t.Fatalf("FetchScript: %v", err)
+		}
+		if script.SHA256 != hashCommand(body) {
+			t.Errorf("unexpected digest %s", script.SHA256)
+		}
+		saved, err := os.ReadFile(script.Path)
+		if err != nil || string(saved) != body {
+			t.Errorf("saved copy mismatch: %q, %v", saved, err)
+		}
+		if !strings.HasPrefix(script.Path, tmpDir) {
+			t.Errorf("expected script under cache dir, got %s", script.Path)
+		}
+	})
+
+	t.Run("refuses oversized scripts", func(t *testing.T) {
+		if _, err := newExecutor(config.SandboxSecurityBalanced).FetchScript(context.Background(), server.URL, 4); err == nil {
+			t.Error("expected size limit error")
+		}
+	})
+
+	t.Run("obeys network policy", func(t *testing.T) {
+		if _, err := newExecutor(config.SandboxSecurityParanoid).FetchScript(context.Background(), server.URL, 1024); err == nil {
+			t.Error("expected paranoid sandbox to block the download")
+		}
+	})
+
+	t.Run("rejects non-http schemes", func(t *testing.T) {
+		if _, err := newExecutor(config.SandboxSecurityBalanced).FetchScript(context.Background(), "file:///etc/passwd", 1024); err == nil {
+			t.Error("expected scheme error")
+		}
+	})
+}
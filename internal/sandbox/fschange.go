@@ -0,0 +1,209 @@
+package sandbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxStateEntries bounds how many paths a workspace state records
+const maxStateEntries = 200000
+
+// stateSkipDirs churn on every git command and are not worth reporting
+var stateSkipDirs = map[string]bool{".git/objects": true, ".git/logs": true}
+
+// FileState is the metadata recorded for one path
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+	Mode    fs.FileMode
+	Hash    string // sha256 of regular files, when hashing is enabled
+}
+
+// WorkspaceState maps workspace-relative paths to their metadata
+type WorkspaceState struct {
+	Root      string
+	Files     map[string]FileState
+	Truncated bool // Stopped at maxStateEntries
+}
+
+// FileChange is one difference between two workspace states
+type FileChange struct {
+	Path      string // Absolute path
+	Operation string // create, modify, delete, chmod
+	Size      int64
+	OldMode   fs.FileMode
+	NewMode   fs.FileMode
+	RiskLevel string
+	Reason    string
+}
+
+// FileChangeReport lists what a sandboxed command did to the workspace
+type FileChangeReport struct {
+	Workspace string
+	Changes   []FileChange
+	Truncated bool // A state was incomplete; changes past the limit are missing
+}
+
+// CaptureWorkspace records the metadata of everything under root, and content
+// hashes of regular files when hash is set.
+func CaptureWorkspace(root string, hash bool) (WorkspaceState, error) {
+	state := WorkspaceState{Root: root, Files: map[string]FileState{}}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		if d.IsDir() && stateSkipDirs[filepath.ToSlash(rel)] {
+			return filepath.SkipDir
+		}
+		if len(state.Files) >= maxStateEntries {
+			state.Truncated = true
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fileState := FileState{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode()}
+		if hash && info.Mode().IsRegular() {
+			fileState.Hash = hashFile(p)
+		}
+		state.Files[rel] = fileState
+		return nil
+	})
+	return state, err
+}
+
+func hashFile(p string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DiffWorkspace compares two states of the same workspace. Directories are
+// reported only when created, deleted or chmodded; contents of a deleted
+// directory are reported individually.
+func DiffWorkspace(before, after WorkspaceState) FileChangeReport {
+	report := FileChangeReport{Workspace: after.Root, Truncated: before.Truncated || after.Truncated}
+	add := func(rel, op string, old, cur FileState) {
+		change := FileChange{Path: filepath.Join(after.Root, rel), Operation: op, Size: cur.Size, OldMode: old.Mode, NewMode: cur.Mode}
+		change.RiskLevel, change.Reason = fileChangeRisk(rel, change)
+		report.Changes = append(report.Changes, change)
+	}
+
+	for rel, cur := range after.Files {
+		old, existed := before.Files[rel]
+		switch {
+		case !existed:
+			add(rel, "create", FileState{}, cur)
+		case old.Mode.Type() != cur.Mode.Type():
+			add(rel, "modify", old, cur)
+		case cur.Mode.IsRegular() && contentChanged(old, cur):
+			add(rel, "modify", old, cur)
+		case permBits(old.Mode) != permBits(cur.Mode):
+			add(rel, "chmod", old, cur)
+		}
+	}
+	for rel, old := range before.Files {
+		if _, ok := after.Files[rel]; !ok {
+			add(rel, "delete", old, FileState{})
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool { return report.Changes[i].Path < report.Changes[j].Path })
+	return report
+}
+
+// permBits is the permission part of a mode, including setuid, setgid and sticky
+func permBits(mode fs.FileMode) fs.FileMode {
+	return mode & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+}
+
+func contentChanged(old, cur FileState) bool {
+	if old.Hash != "" && cur.Hash != "" {
+		return old.Hash != cur.Hash
+	}
+	return old.Size != cur.Size || !old.ModTime.Equal(cur.ModTime)
+}
+
+// sensitiveWorkspacePaths are files whose change can run code or leak
+// credentials later
+var sensitiveWorkspacePaths = []string{
+	".git/hooks/", ".git/config", ".github/workflows/", ".gitlab-ci.yml", ".env",
+	".npmrc", ".pypirc", ".netrc", ".ssh/", "authorized_keys", "id_rsa", "id_ed25519",
+	".bashrc", ".zshrc", ".profile", ".bash_profile",
+}
+
+// fileChangeRisk grades a change: setuid/setgid bits are critical; sensitive
+// paths and world-writable files are high; other deletions and new
+// executables are medium.
+func fileChangeRisk(rel string, change FileChange) (string, string) {
+	sensitive := isSensitiveWorkspacePath(filepath.ToSlash(rel))
+	gained := change.NewMode &^ change.OldMode
+	if change.Operation == "delete" {
+		if sensitive {
+			return "high", "sensitive file deleted"
+		}
+		return "medium", "deleted"
+	}
+
+	switch {
+	case gained&(fs.ModeSetuid|fs.ModeSetgid) != 0:
+		return "critical", "setuid/setgid bit set"
+	case sensitive:
+		return "high", "sensitive file changed"
+	case gained&0o002 != 0 && !change.NewMode.IsDir():
+		return "high", "made world-writable"
+	case change.NewMode.IsRegular() && gained&0o111 != 0:
+		if change.Operation == "create" {
+			return "medium", "executable created"
+		}
+		return "medium", "executable bit added"
+	}
+	return "low", ""
+}
+
+func isSensitiveWorkspacePath(rel string) bool {
+	base := path.Base(rel)
+	for _, pattern := range sensitiveWorkspacePaths {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.Contains("/"+rel+"/", "/"+pattern) {
+				return true
+			}
+		} else if base == pattern || rel == pattern {
+			return true
+		}
+	}
+	return strings.HasPrefix(base, ".env")
+}
+
+// Counts returns the number of changes per operation.
+func (r FileChangeReport) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, change := range r.Changes {
+		counts[change.Operation]++
+	}
+	return counts
+}
+
+// Summary is a one-line description of the report.
+func (r FileChangeReport) Summary() string {
+	counts := r.Counts()
+	return fmt.Sprintf("%d created, %d modified, %d deleted, %d permission change(s)",
+		counts["create"], counts["modify"], counts["delete"], counts["chmod"])
+}
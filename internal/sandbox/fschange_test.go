@@ -0,0 +1,93 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, ws, "edit.txt", "before\n")
+	writeTestFile(t, ws, "gone.txt", "bye\n")
+	writeTestFile(t, ws, "script.sh", "echo hi\n")
+	writeTestFile(t, ws, ".git/objects/ab/cdef", "blob")
+	writeTestFile(t, ws, ".env", "TOKEN=x\n")
+
+	before, err := CaptureWorkspace(ws, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := before.Files[".git/objects/ab/cdef"]; ok {
+		t.Error("expected .git/objects to be skipped")
+	}
+
+	writeTestFile(t, ws, "edit.txt", "after, longer\n")
+	os.Remove(filepath.Join(ws, "gone.txt"))
+	os.Chmod(filepath.Join(ws, "script.sh"), 0o755)
+	writeTestFile(t, ws, "bin/tool", "#!/bin/sh\n")
+	os.Chmod(filepath.Join(ws, "bin/tool"), os.ModeSetuid|0o755)
+	os.Remove(filepath.Join(ws, ".env"))
+	writeTestFile(t, ws, ".git/objects/ab/0123", "blob")
+
+	after, err := CaptureWorkspace(ws, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := DiffWorkspace(before, after)
+
+	want := map[string][2]string{
+		".env":      {"delete", "high"},
+		"bin":       {"create", "low"},
+		"bin/tool":  {"create", "critical"},
+		"edit.txt":  {"modify", "low"},
+		"gone.txt":  {"delete", "medium"},
+		"script.sh": {"chmod", "medium"},
+	}
+	if len(report.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(report.Changes), len(want), report.Changes)
+	}
+	for _, change := range report.Changes {
+		rel, _ := filepath.Rel(ws, change.Path)
+		w, ok := want[rel]
+		if !ok {
+			t.Errorf("unexpected change %s %s", change.Operation, rel)
+			continue
+		}
+		if change.Operation != w[0] || change.RiskLevel != w[1] {
+			t.Errorf("%s: got %s/%s, want %s/%s", rel, change.Operation, change.RiskLevel, w[0], w[1])
+		}
+	}
+	if got := report.Summary(); got != "2 created, 1 modified, 2 deleted, 1 permission change(s)" {
+		t.Errorf("summary = %q", got)
+	}
+}
+
+func TestDiffWorkspaceHash(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, ws, "same-size.txt", "aaaa")
+	stamp := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(ws, "same-size.txt"), stamp, stamp)
+
+	before, _ := CaptureWorkspace(ws, true)
+	writeTestFile(t, ws, "same-size.txt", "bbbb")
+	os.Chtimes(filepath.Join(ws, "same-size.txt"), stamp, stamp)
+
+	// Metadata alone misses a same-size rewrite with the mtime restored
+	afterMeta, _ := CaptureWorkspace(ws, false)
+	beforeMeta := before
+	beforeMeta.Files = map[string]FileState{}
+	for rel, state := range before.Files {
+		state.Hash = ""
+		beforeMeta.Files[rel] = state
+	}
+	if report := DiffWorkspace(beforeMeta, afterMeta); len(report.Changes) != 0 {
+		t.Errorf("expected metadata-only diff to miss the change, got %+v", report.Changes)
+	}
+
+	after, _ := CaptureWorkspace(ws, true)
+	if report := DiffWorkspace(before, after); len(report.Changes) != 1 || report.Changes[0].Operation != "modify" {
+		t.Errorf("expected hashing to catch the change, got %+v", report.Changes)
+	}
+}
@@ -0,0 +1,127 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+)
+
+// ResourceUsage is what a cgroup-limited sandboxed command used
+type ResourceUsage struct {
+	MemoryPeak  int64 // Bytes, 0 if the kernel doesn't track it
+	MemoryLimit int64 // Bytes, 0 if unlimited
+	OOMKills    int   // Processes killed for exceeding the memory limit
+	PidsPeak    int
+	CPUTime     time.Duration
+}
+
+// runLimited calls run with a fresh cgroup that enforces the memory, CPU,
+// process and disk I/O limits. Without a delegated cgroup v2 subtree, run
+// gets an empty path and the command is unbounded.
+func (e *Executor) runLimited(cfg SandboxConfig, run func(cgroupPath string) error) error {
+	e.resourceUsage = nil
+	limits, err := cgroupLimits(cfg)
+	if err != nil {
+		return err
+	}
+	if limits == (namespace.CgroupLimits{IOPath: limits.IOPath}) {
+		return run("")
+	}
+
+	name := fmt.Sprintf("exec-%d-%d", os.Getpid(), time.Now().UnixNano())
+	cg, err := namespace.CreateCgroup(name, limits)
+	if err != nil {
+		// The security level always sets limits; only warn about ones the user asked for
+		fields := map[string]any{"error": err.Error()}
+		if e.limitsConfigured() {
+			e.logger.Warn("resource limits not applied", fields)
+		} else {
+			e.logger.Debug("resource limits not applied", fields)
+		}
+		return run("")
+	}
+	defer cg.Remove()
+	if len(cg.Missing) > 0 {
+		e.logger.Warn("some resource limits not applied", map[string]any{"limits": cg.Missing})
+	}
+
+	err = run(cg.Path)
+
+	usage := cg.Usage()
+	e.resourceUsage = &ResourceUsage{
+		MemoryPeak:  usage.MemoryPeak,
+		MemoryLimit: limits.MemoryMax,
+		OOMKills:    usage.OOMKills,
+		PidsPeak:    usage.PidsPeak,
+		CPUTime:     usage.CPUTime,
+	}
+	e.logger.Info("sandbox resource usage", map[string]any{
+		"memory_peak": usage.MemoryPeak,
+		"oom_kills":   usage.OOMKills,
+		"pids_peak":   usage.PidsPeak,
+		"cpu_time":    usage.CPUTime.String(),
+	})
+	return err
+}
+
+// limitsConfigured reports whether any limit was set in the config file
+func (e *Executor) limitsConfigured() bool {
+	cfg := e.config.Sandbox
+	return cfg.MemoryLimit != "" || cfg.CPULimit != "" || cfg.PidsLimit > 0 || cfg.IOLimit != ""
+}
+
+// ResourceUsage returns what the last sandboxed command used, or nil if it
+// didn't run in a cgroup.
+func (e *Executor) ResourceUsage() *ResourceUsage {
+	return e.resourceUsage
+}
+
+// cgroupLimits converts the Docker-style limit strings
+func cgroupLimits(cfg SandboxConfig) (namespace.CgroupLimits, error) {
+	limits := namespace.CgroupLimits{PidsMax: cfg.PidsLimit, IOPath: cfg.WorkDir}
+	var err error
+	if cfg.MemoryLimit != "" {
+		if limits.MemoryMax, err = parseByteSize(cfg.MemoryLimit); err != nil {
+			return limits, fmt.Errorf("invalid memory limit: %w", err)
+		}
+	}
+	if cfg.CPULimit != "" {
+		if limits.CPUs, err = strconv.ParseFloat(cfg.CPULimit, 64); err != nil || limits.CPUs <= 0 {
+			return limits, fmt.Errorf("invalid cpu limit %q", cfg.CPULimit)
+		}
+	}
+	if cfg.IOLimit != "" {
+		if limits.IOBPS, err = parseByteSize(cfg.IOLimit); err != nil {
+			return limits, fmt.Errorf("invalid io limit: %w", err)
+		}
+	}
+	return limits, nil
+}
+
+// parseByteSize parses sizes such as "512m", "1g" or "1048576"
+func parseByteSize(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "b")
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,43 @@
+package sandbox
+
+import (
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512m", 512 << 20},
+		{"1g", 1 << 30},
+		{"1.5G", 3 << 29},
+		{"64k", 64 << 10},
+		{"100mb", 100 << 20},
+		{"4096", 4096},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "lots", "-1m", "0"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestCgroupLimits(t *testing.T) {
+	limits, err := cgroupLimits(SandboxConfig{MemoryLimit: "256m", CPULimit: "0.25", PidsLimit: 50, IOLimit: "50m", WorkDir: "/ws"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.MemoryMax != 256<<20 || limits.CPUs != 0.25 || limits.PidsMax != 50 || limits.IOBPS != 50<<20 || limits.IOPath != "/ws" {
+		t.Errorf("cgroupLimits() = %+v", limits)
+	}
+	if _, err := cgroupLimits(SandboxConfig{CPULimit: "fast"}); err == nil {
+		t.Error("cgroupLimits() accepted an invalid cpu limit")
+	}
+}
@@ -1,11 +1,13 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // BubblewrapConfig holds configuration for bubblewrap sandbox
@@ -14,8 +16,19 @@ type BubblewrapConfig struct {
 	CacheDir    string
 	AllowNetwork bool
 	ReadOnlyPaths []string
+	MaskedPaths  []string // Hidden behind an empty tmpfs or /dev/null
 	BindMounts   []BindMount
 	Environment  map[string]string
+	
+	// Copy-on-write workspace: either a staging copy mounted in place of the
+	// workspace, or a kernel overlay whose writes land in OverlayUpper
+	WorkspaceSource string
+	OverlayUpper    string
+	OverlayWork     string
+
+	CgroupPath string        // Resource-limited cgroup to start in (see CreateCgroup)
+	KillGrace  time.Duration // SIGTERM to SIGKILL when the context ends (see ProcessGroup.Wait)
+	Trace      *TraceConfig  // Report the programs the command runs (see TraceConfig)
 }
 
 // BindMount represents a bind mount configuration
@@ -35,23 +48,50 @@ func NewBubblewrapExecutor(config BubblewrapConfig) *BubblewrapExecutor {
 	return &BubblewrapExecutor{config: config}
 }
 
-// Execute runs a command in bubblewrap sandbox
-func (e *BubblewrapExecutor) Execute(cmdArgs []string) error {
+// Execute runs a command in bubblewrap sandbox. When ctx ends bwrap is
+// stopped, taking the sandbox with it, and ctx.Err() is returned.
+func (e *BubblewrapExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	// On a terminal the command gets a PTY (see StartProcessGroup). bwrap's
+	// --new-session leaves the command without a controlling terminal, and
+	// bwrap itself must not have one or Ctrl-C would kill it rather than
+	// reach the command, so the command takes the PTY inside the sandbox.
+	// Injecting input with TIOCSTI only reaches the PTY, not our terminal.
+	var opts groupOptions
+	var wrapper []string
+	if foregroundTerminal() {
+		if setsid, err := exec.LookPath("setsid"); err == nil {
+			wrapper = []string{setsid, "-w", "-c"}
+			opts.noCtty = true
+		}
+	}
+	if e.config.Trace != nil {
+		return e.executeTraced(ctx, wrapper, cmdArgs, opts)
+	}
+
 	// Build bubblewrap command
-	bwrapArgs := e.buildBubblewrapArgs(cmdArgs)
-	
-	cmd := exec.Command("bwrap", bwrapArgs...)
+	cmd := e.command(append(wrapper, cmdArgs...))
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	
-	// Set environment variables
+	closeCgroup, err := StartInCgroup(cmd, e.config.CgroupPath)
+	if err != nil {
+		return err
+	}
+	defer closeCgroup()
+	
+	return runProcessGroup(ctx, cmd, e.config.KillGrace, opts)
+}
+
+// command is bwrap running cmdArgs in the sandbox, with the configured
+// environment
+func (e *BubblewrapExecutor) command(cmdArgs []string) *exec.Cmd {
+	cmd := exec.Command("bwrap", e.buildBubblewrapArgs(cmdArgs)...)
 	cmd.Env = os.Environ()
 	for key, value := range e.config.Environment {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
-	
-	return cmd.Run()
+	return cmd
 }
 
 // buildBubblewrapArgs constructs the bubblewrap command arguments
@@ -70,13 +110,11 @@ func (e *BubblewrapExecutor) buildBubblewrapArgs(cmdArgs []string) []string {
 	// 4. Create writable /tmp (isolated)
 	args = append(args, "--tmpfs", "/tmp")
 	
-	// 5. Bind workspace as writable
+	// 5. Bind workspace as writable (or copy-on-write)
 	if e.config.Workspace != "" {
 		absWorkspace, err := filepath.Abs(e.config.Workspace)
 		if err == nil {
-			args = append(args, "--bind", absWorkspace, absWorkspace)
-			// Also bind to /workspace for convenience
-			args = append(args, "--bind", absWorkspace, "/workspace")
+			args = append(args, workspaceArgs(absWorkspace, e.config)...)
 		}
 	}
 	
@@ -92,35 +130,74 @@ func (e *BubblewrapExecutor) buildBubblewrapArgs(cmdArgs []string) []string {
 		}
 	}
 	
-	// 7. Unshare all namespaces
+	// 7. Protected paths go on top of the writable binds
+	args = append(args, protectedPathArgs(e.config.ReadOnlyPaths, e.config.MaskedPaths)...)
+	
+	// 8. Unshare all namespaces
 	args = append(args, "--unshare-all")
 	
-	// 8. Network sharing (optional)
+	// 9. Network sharing (optional)
 	if e.config.AllowNetwork {
 		args = append(args, "--share-net")
 	}
 	
-	// 9. Die with parent (cleanup on exit)
+	// 10. Die with parent (cleanup on exit)
 	args = append(args, "--die-with-parent")
 	
-	// 10. New session
+	// 11. New session
 	args = append(args, "--new-session")
 	
-	// 11. Security hardening
+	// 12. Security hardening
 	args = append(args, "--cap-drop", "ALL") // Drop all capabilities
 	
-	// 12. Set working directory
+	// 13. Set working directory
 	if e.config.Workspace != "" {
 		args = append(args, "--chdir", e.config.Workspace)
 	}
 	
-	// 13. Add the command to execute
+	// 14. Add the command to execute
 	args = append(args, "--")
 	args = append(args, cmdArgs...)
 	
 	return args
 }
 
+// workspaceArgs mounts the workspace writable, also at /workspace for
+// convenience. Copy-on-write workspaces mount the staging copy or an overlay
+// instead, so the real files are never written.
+func workspaceArgs(absWorkspace string, config BubblewrapConfig) []string {
+	switch {
+	case config.OverlayUpper != "":
+		return []string{"--overlay-src", absWorkspace, "--overlay", config.OverlayUpper, config.OverlayWork, absWorkspace}
+	case config.WorkspaceSource != "":
+		return []string{"--bind", config.WorkspaceSource, absWorkspace, "--bind", config.WorkspaceSource, "/workspace"}
+	}
+	return []string{"--bind", absWorkspace, absWorkspace, "--bind", absWorkspace, "/workspace"}
+}
+
+// protectedPathArgs re-binds read-only paths over the writable workspace and
+// masks hidden ones: directories with an empty tmpfs, files with /dev/null.
+func protectedPathArgs(readOnly, masked []string) []string {
+	var args []string
+	for _, path := range readOnly {
+		if _, err := os.Stat(path); err == nil {
+			args = append(args, "--ro-bind", path, path)
+		}
+	}
+	for _, path := range masked {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			continue
+		case info.IsDir():
+			args = append(args, "--tmpfs", path, "--remount-ro", path)
+		default:
+			args = append(args, "--ro-bind", "/dev/null", path)
+		}
+	}
+	return args
+}
+
 // getDefaultCacheBinds returns default cache directories to bind mount
 func (e *BubblewrapExecutor) getDefaultCacheBinds() []BindMount {
 	home := os.Getenv("HOME")
@@ -178,3 +255,15 @@ func GetVersion() (string, error) {
 	return version, nil
 }
 
+// BubblewrapSupportsOverlay reports whether the installed bwrap has --overlay (0.7.0+)
+func BubblewrapSupportsOverlay() bool {
+	version, err := GetVersion()
+	if err != nil {
+		return false
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(version, "bubblewrap "), "%d.%d", &major, &minor); err != nil {
+		return false
+	}
+	return major > 0 || minor >= 7
+}
@@ -1,6 +1,9 @@
 package namespace
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -100,3 +103,47 @@ func TestIsBubblewrapAvailable(t *testing.T) {
 	}
 }
 
+
+func TestProtectedPathArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.env")
+	if err := os.WriteFile(file, []byte("TOKEN=x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewBubblewrapExecutor(BubblewrapConfig{
+		Workspace:     dir,
+		ReadOnlyPaths: []string{filepath.Join(dir, "migrations-missing"), dir},
+		MaskedPaths:   []string{file},
+	})
+	args := strings.Join(executor.buildBubblewrapArgs([]string{"true"}), " ")
+
+	workspaceBind := strings.Index(args, "--bind "+dir+" "+dir)
+	readOnly := strings.Index(args, "--ro-bind "+dir+" "+dir)
+	if workspaceBind < 0 || readOnly < workspaceBind {
+		t.Errorf("expected read-only bind after the workspace bind: %s", args)
+	}
+	if !strings.Contains(args, "--ro-bind /dev/null "+file) {
+		t.Errorf("expected %s to be masked: %s", file, args)
+	}
+	if strings.Contains(args, "migrations-missing") {
+		t.Errorf("missing paths should be skipped: %s", args)
+	}
+}
+
+func TestWorkspaceArgs(t *testing.T) {
+	cases := []struct {
+		name   string
+		config BubblewrapConfig
+		want   string
+	}{
+		{"bind", BubblewrapConfig{}, "--bind /ws /ws --bind /ws /workspace"},
+		{"staging copy", BubblewrapConfig{WorkspaceSource: "/tmp/copy"}, "--bind /tmp/copy /ws --bind /tmp/copy /workspace"},
+		{"kernel overlay", BubblewrapConfig{OverlayUpper: "/tmp/upper", OverlayWork: "/tmp/work"}, "--overlay-src /ws --overlay /tmp/upper /tmp/work /ws"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(workspaceArgs("/ws", tc.config), " "); got != tc.want {
+			t.Errorf("%s: workspaceArgs = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupControllers are enabled for sandbox cgroups when available
+var cgroupControllers = []string{"memory", "pids", "cpu", "io"}
+
+// CgroupLimits are the resource limits for one sandboxed command. Zero
+// values mean no limit.
+type CgroupLimits struct {
+	MemoryMax int64   // Bytes
+	CPUs      float64 // CPU time per wall-clock second
+	PidsMax   int
+	IOBPS     int64  // Read and write bytes per second
+	IOPath    string // A path on the disk to throttle, usually the workspace
+}
+
+// CgroupUsage is what a sandboxed command used, read after it exits
+type CgroupUsage struct {
+	MemoryPeak int64 // Bytes, 0 if the kernel doesn't track it
+	OOMKills   int
+	PidsPeak   int // 0 if the kernel doesn't track it
+	CPUTime    time.Duration
+}
+
+// Cgroup is a cgroup v2 leaf created for one sandboxed command
+type Cgroup struct {
+	Path    string
+	Missing []string // Limits that couldn't be applied
+}
+
+// CreateCgroup creates a leaf cgroup and applies limits to it. The leaf goes
+// under the first writable place with the controllers delegated: our own
+// cgroup, its parent (a systemd user delegation), or a vectra-guard subtree
+// of the root.
+func CreateCgroup(name string, limits CgroupLimits) (*Cgroup, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not mounted at %s", cgroupRoot)
+	}
+
+	var parents []string
+	if own, err := ownCgroup(); err == nil {
+		parents = append(parents, own, filepath.Dir(own))
+	}
+	parents = append(parents, filepath.Join(cgroupRoot, "vectra-guard"))
+
+	for _, parent := range parents {
+		if !strings.HasPrefix(parent, cgroupRoot) {
+			continue
+		}
+		if filepath.Base(parent) == "vectra-guard" {
+			os.Mkdir(parent, 0o755)
+			enableControllers(parent)
+		}
+		if !hasControllers(parent, "memory", "pids") {
+			continue
+		}
+		path := filepath.Join(parent, name)
+		if err := os.Mkdir(path, 0o755); err != nil {
+			continue
+		}
+		cg := &Cgroup{Path: path}
+		if err := cg.apply(limits); err != nil {
+			cg.Remove()
+			return nil, err
+		}
+		return cg, nil
+	}
+	return nil, fmt.Errorf("no writable cgroup with the memory and pids controllers delegated")
+}
+
+// ownCgroup is the cgroup v2 directory of this process
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rel, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupRoot, rel), nil
+		}
+	}
+	return "", fmt.Errorf("not in a cgroup v2 hierarchy")
+}
+
+// enableControllers turns on the sandbox controllers for children of dir,
+// one at a time so an unavailable controller doesn't block the others.
+func enableControllers(dir string) {
+	for _, controller := range cgroupControllers {
+		os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0)
+	}
+}
+
+// hasControllers reports whether children of dir get the controllers
+func hasControllers(dir string, controllers ...string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return false
+	}
+	enabled := strings.Fields(string(data))
+	for _, controller := range controllers {
+		found := false
+		for _, e := range enabled {
+			if e == controller {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// apply writes the limits. Memory and pids limits must succeed; CPU and I/O
+// limits are skipped, and listed in Missing, when the controller or disk
+// isn't available.
+func (c *Cgroup) apply(limits CgroupLimits) error {
+	parent := filepath.Dir(c.Path)
+	if limits.MemoryMax > 0 {
+		if err := c.write("memory.max", strconv.FormatInt(limits.MemoryMax, 10)); err != nil {
+			return err
+		}
+		c.write("memory.swap.max", "0") // Absent without swap accounting
+	}
+	if limits.PidsMax > 0 {
+		if err := c.write("pids.max", strconv.Itoa(limits.PidsMax)); err != nil {
+			return err
+		}
+	}
+	if limits.CPUs > 0 {
+		if !hasControllers(parent, "cpu") || c.write("cpu.max", cpuMax(limits.CPUs)) != nil {
+			c.Missing = append(c.Missing, "cpu")
+		}
+	}
+	if limits.IOBPS > 0 {
+		device, ok := blockDevice(limits.IOPath)
+		if !ok || !hasControllers(parent, "io") || c.write("io.max", fmt.Sprintf("%s rbps=%d wbps=%d", device, limits.IOBPS, limits.IOBPS)) != nil {
+			c.Missing = append(c.Missing, "io")
+		}
+	}
+	return nil
+}
+
+func (c *Cgroup) write(file, value string) error {
+	if err := os.WriteFile(filepath.Join(c.Path, file), []byte(value), 0); err != nil {
+		return fmt.Errorf("set %s: %w", file, err)
+	}
+	return nil
+}
+
+// cpuMax formats a CPU count as a cpu.max quota over a 100ms period
+func cpuMax(cpus float64) string {
+	const period = 100000
+	return fmt.Sprintf("%d %d", int64(cpus*period), period)
+}
+
+// blockDevice is the "major:minor" of the whole disk holding path. io.max
+// only accepts whole disks, so partitions are resolved to their parent.
+func blockDevice(path string) (string, bool) {
+	var st unix.Stat_t
+	if path == "" || unix.Stat(path, &st) != nil {
+		return "", false
+	}
+	major, minor := unix.Major(st.Dev), unix.Minor(st.Dev)
+	if major == 0 {
+		return "", false // tmpfs, overlayfs and other virtual filesystems
+	}
+	sysDir := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	if _, err := os.Stat(filepath.Join(sysDir, "partition")); err == nil {
+		data, err := os.ReadFile(filepath.Join(sysDir, "..", "dev"))
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimSpace(string(data)), true
+	}
+	return fmt.Sprintf("%d:%d", major, minor), true
+}
+
+// Usage reads peak memory, OOM kills, peak process count and CPU time
+func (c *Cgroup) Usage() CgroupUsage {
+	var usage CgroupUsage
+	usage.MemoryPeak, _ = c.readInt("memory.peak")
+	pids, _ := c.readInt("pids.peak")
+	usage.PidsPeak = int(pids)
+	if v, ok := c.readKey("memory.events", "oom_kill"); ok {
+		usage.OOMKills = int(v)
+	}
+	if v, ok := c.readKey("cpu.stat", "usage_usec"); ok {
+		usage.CPUTime = time.Duration(v) * time.Microsecond
+	}
+	return usage
+}
+
+func (c *Cgroup) readInt(file string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(c.Path, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readKey reads a value from a flat keyed file such as memory.events
+func (c *Cgroup) readKey(file, key string) (int64, bool) {
+	f, err := os.Open(filepath.Join(c.Path, file))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			v, err := strconv.ParseInt(fields[1], 10, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// Remove kills anything left in the cgroup and deletes it
+func (c *Cgroup) Remove() error {
+	if err := c.write("cgroup.kill", "1"); err != nil {
+		c.killProcs() // cgroup.kill needs Linux 5.14
+	}
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = os.Remove(c.Path); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond) // Killed processes take a moment to leave
+	}
+	return err
+}
+
+func (c *Cgroup) killProcs() {
+	data, err := os.ReadFile(filepath.Join(c.Path, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			unix.Kill(pid, unix.SIGKILL)
+		}
+	}
+}
+
+// StartInCgroup makes cmd start inside the cgroup at path, so not even its
+// first fork escapes the limits. The returned func closes the cgroup fd once
+// the command has started.
+func StartInCgroup(cmd *exec.Cmd, path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	fd, err := unix.Open(path, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open cgroup: %w", err)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	return func() { unix.Close(fd) }, nil
+}
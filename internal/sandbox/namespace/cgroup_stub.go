@@ -0,0 +1,53 @@
+//go:build !linux
+// +build !linux
+
+package namespace
+
+import (
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// CgroupLimits are the resource limits for one sandboxed command (stub)
+type CgroupLimits struct {
+	MemoryMax int64
+	CPUs      float64
+	PidsMax   int
+	IOBPS     int64
+	IOPath    string
+}
+
+// CgroupUsage is what a sandboxed command used (stub)
+type CgroupUsage struct {
+	MemoryPeak int64
+	OOMKills   int
+	PidsPeak   int
+	CPUTime    time.Duration
+}
+
+// Cgroup is a cgroup v2 leaf created for one sandboxed command (stub)
+type Cgroup struct {
+	Path    string
+	Missing []string
+}
+
+// CreateCgroup returns an error on non-Linux
+func CreateCgroup(name string, limits CgroupLimits) (*Cgroup, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+// Usage returns no usage on non-Linux
+func (c *Cgroup) Usage() CgroupUsage {
+	return CgroupUsage{}
+}
+
+// Remove does nothing on non-Linux
+func (c *Cgroup) Remove() error {
+	return nil
+}
+
+// StartInCgroup does nothing on non-Linux
+func StartInCgroup(cmd *exec.Cmd, path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,62 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCgroupApplyAndUsage(t *testing.T) {
+	parent := t.TempDir()
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("memory pids\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cg := &Cgroup{Path: filepath.Join(parent, "exec-1")}
+	if err := os.Mkdir(cg.Path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cg.apply(CgroupLimits{MemoryMax: 512 << 20, PidsMax: 100, CPUs: 0.5, IOBPS: 50 << 20, IOPath: parent})
+	if err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	for file, want := range map[string]string{"memory.max": "536870912", "pids.max": "100"} {
+		data, _ := os.ReadFile(filepath.Join(cg.Path, file))
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", file, data, want)
+		}
+	}
+	// The parent doesn't delegate cpu or io
+	if len(cg.Missing) != 2 || cg.Missing[0] != "cpu" || cg.Missing[1] != "io" {
+		t.Errorf("Missing = %v, want [cpu io]", cg.Missing)
+	}
+
+	files := map[string]string{
+		"memory.peak":   "104857600\n",
+		"pids.peak":     "12\n",
+		"memory.events": "low 0\nhigh 0\nmax 4\noom 1\noom_kill 2\n",
+		"cpu.stat":      "usage_usec 1500000\nuser_usec 1000000\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(cg.Path, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := CgroupUsage{MemoryPeak: 100 << 20, OOMKills: 2, PidsPeak: 12, CPUTime: 1500 * time.Millisecond}
+	if got := cg.Usage(); got != want {
+		t.Errorf("Usage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCPUMax(t *testing.T) {
+	if got := cpuMax(0.5); got != "50000 100000" {
+		t.Errorf("cpuMax(0.5) = %q", got)
+	}
+	if got := cpuMax(2); got != "200000 100000" {
+		t.Errorf("cpuMax(2) = %q", got)
+	}
+}
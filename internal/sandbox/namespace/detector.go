@@ -13,6 +13,7 @@ type RuntimeType string
 const (
 	RuntimeBubblewrap RuntimeType = "bubblewrap"
 	RuntimeNamespace  RuntimeType = "namespace"
+	RuntimeLandlock   RuntimeType = "landlock"
 	RuntimeDocker     RuntimeType = "docker"
 	RuntimeNone       RuntimeType = "none"
 )
@@ -36,6 +37,7 @@ type Capabilities struct {
 	UserNamespaces   bool
 	MountNamespaces  bool
 	NetworkNamespaces bool
+	Landlock         int // Landlock ABI version, 0 if unavailable
 }
 
 // DetectEnvironment determines if we're running in dev, CI, or production
@@ -135,6 +137,9 @@ func DetectCapabilities() Capabilities {
 		if _, err := os.Stat("/sys/module/overlay"); err == nil {
 			caps.OverlayFS = true
 		}
+
+		// Check for Landlock (restricts file access without namespaces)
+		caps.Landlock = LandlockABI()
 	}
 
 	return caps
@@ -154,11 +159,14 @@ func SelectBestRuntime(env Environment, caps Capabilities) RuntimeType {
 		if caps.Namespaces {
 			return RuntimeNamespace
 		}
+		if caps.Landlock > 0 {
+			return RuntimeLandlock
+		}
 		return RuntimeNone
 	}
 
 	// For development, prefer lightweight solutions
-	// Priority: bubblewrap > custom namespace > docker
+	// Priority: bubblewrap > custom namespace > landlock > docker
 	if caps.Bubblewrap {
 		return RuntimeBubblewrap
 	}
@@ -167,6 +175,12 @@ func SelectBestRuntime(env Environment, caps Capabilities) RuntimeType {
 		return RuntimeNamespace
 	}
 
+	// Landlock doesn't isolate processes, but still beats a Docker startup
+	// when user namespaces are disabled
+	if caps.Landlock > 0 {
+		return RuntimeLandlock
+	}
+
 	if caps.Docker {
 		return RuntimeDocker
 	}
@@ -206,6 +220,8 @@ func GetRuntimeInfo(runtime RuntimeType, env Environment) string {
 		return "Using bubblewrap for fast, secure sandboxing (<1ms overhead)"
 	case RuntimeNamespace:
 		return "Using Linux namespaces for lightweight sandboxing"
+	case RuntimeLandlock:
+		return "Using Landlock to restrict file and network access (no process isolation)"
 	case RuntimeDocker:
 		if env == EnvironmentDev {
 			return "Using Docker (consider installing bubblewrap for faster dev experience)"
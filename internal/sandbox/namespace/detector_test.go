@@ -84,6 +84,7 @@ func TestDetectCapabilities(t *testing.T) {
 	t.Logf("  UserNamespaces:    %v", caps.UserNamespaces)
 	t.Logf("  MountNamespaces:   %v", caps.MountNamespaces)
 	t.Logf("  NetworkNamespaces: %v", caps.NetworkNamespaces)
+	t.Logf("  Landlock ABI:      %v", caps.Landlock)
 
 	// If we have user and mount namespaces, we should have general namespace support
 	if caps.UserNamespaces && caps.MountNamespaces {
@@ -150,6 +151,24 @@ func TestSelectBestRuntime(t *testing.T) {
 			},
 			expected: RuntimeBubblewrap,
 		},
+		{
+			name: "dev with landlock and docker",
+			env:  EnvironmentDev,
+			caps: Capabilities{
+				Namespaces: false,
+				Docker:     true,
+				Landlock:   4,
+			},
+			expected: RuntimeLandlock,
+		},
+		{
+			name: "CI with landlock only",
+			env:  EnvironmentCI,
+			caps: Capabilities{
+				Landlock: 1,
+			},
+			expected: RuntimeLandlock,
+		},
 		{
 			name: "no capabilities",
 			env:  EnvironmentDev,
@@ -179,6 +198,7 @@ func TestGetRuntimeInfo(t *testing.T) {
 	}{
 		{RuntimeBubblewrap, EnvironmentDev},
 		{RuntimeNamespace, EnvironmentDev},
+		{RuntimeLandlock, EnvironmentDev},
 		{RuntimeDocker, EnvironmentDev},
 		{RuntimeDocker, EnvironmentCI},
 		{RuntimeNone, EnvironmentDev},
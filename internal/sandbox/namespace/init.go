@@ -0,0 +1,365 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// initEnv marks a vectra-guard process started as a sandbox init
+const initEnv = "_VECTRA_GUARD_SANDBOX_INIT"
+
+// sandboxSetupFailed is the init's exit status when the sandbox can't be
+// built, distinct from the codes a shell uses for missing commands.
+const sandboxSetupFailed = 125
+
+// initArgv0 names the init in process listings
+const initArgv0 = "vectra-guard-init"
+
+// initSpec is handed to the init over fd 3. At most one of Mount and
+// Landlock is set; with neither, the init runs inside a sandbox bubblewrap
+// has built and only supervises the command. With Trace, the programs the
+// command runs are reported over fds 4 and 5 (see execReporter).
+type initSpec struct {
+	Mount    *MountConfig
+	Landlock *LandlockConfig
+	Trace    *TraceConfig
+	Args     []string
+}
+
+// startInit re-executes vectra-guard as a sandbox init with the given
+// process attributes and runs it (see launchInit).
+func startInit(ctx context.Context, spec initSpec, attr *syscall.SysProcAttr, cgroupPath string, grace time.Duration) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find vectra-guard executable: %w", err)
+	}
+	cmd := exec.Command(self)
+	cmd.Args = []string{initArgv0}
+	cmd.SysProcAttr = attr
+	// A Landlock init becomes the command; a namespace init stays to supervise it
+	return launchInit(ctx, cmd, spec, cgroupPath, grace, groupOptions{relaying: spec.Mount != nil})
+}
+
+// launchInit starts cmd, which runs a vectra-guard sandbox init, in the cgroup
+// if one is given, sends it the spec and waits for it in its own process
+// group (see ProcessGroup.Wait). With spec.Trace, the programs the command
+// runs are passed to OnExec until the init is gone; a Landlock command is
+// polled from here, having no init left to trace it. A non-zero exit is
+// returned as an *exec.ExitError.
+func launchInit(ctx context.Context, cmd *exec.Cmd, spec initSpec, cgroupPath string, grace time.Duration, opts groupOptions) error {
+	if len(spec.Args) == 0 {
+		return fmt.Errorf("no command specified")
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("encode sandbox spec: %w", err)
+	}
+	specReader, specWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create spec pipe: %w", err)
+	}
+	defer specWriter.Close()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, initEnv+"=1")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{specReader} // fd 3
+
+	var relay *execRelay
+	if spec.Trace != nil && spec.Landlock == nil {
+		var initEnds []*os.File
+		relay, initEnds, err = newExecRelay()
+		if err != nil {
+			specReader.Close()
+			return err
+		}
+		cmd.ExtraFiles = append(cmd.ExtraFiles, initEnds...) // fds 4 and 5
+		go relay.run(spec.Trace.OnExec)
+		defer relay.close()
+	}
+	closeChildEnds := func() {
+		for _, f := range cmd.ExtraFiles {
+			f.Close()
+		}
+	}
+
+	closeCgroup, err := StartInCgroup(cmd, cgroupPath)
+	if err != nil {
+		closeChildEnds()
+		return err
+	}
+	group, err := startProcessGroup(cmd, opts)
+	closeCgroup()
+	closeChildEnds()
+	if err != nil {
+		return fmt.Errorf("start sandbox: %w", err)
+	}
+	if _, err := specWriter.Write(data); err != nil {
+		cmd.Process.Kill()
+		group.Wait(ctx, 0)
+		return fmt.Errorf("send sandbox spec: %w", err)
+	}
+	specWriter.Close()
+
+	if spec.Trace != nil && spec.Landlock != nil {
+		stop := make(chan struct{})
+		polled := make(chan struct{})
+		go func() {
+			defer close(polled)
+			pollExecs(stop, cmd.Process.Pid, spec.Args, spec.Trace.interval(), spec.Trace.OnExec)
+		}()
+		defer func() {
+			close(stop)
+			<-polled
+		}()
+	}
+	return group.Wait(ctx, grace)
+}
+
+// executeTraced runs a vectra-guard init inside bubblewrap's sandbox, after
+// the wrapper, to trace the command (see launchInit). bwrap passes the
+// spec and trace pipes on to it.
+func (e *BubblewrapExecutor) executeTraced(ctx context.Context, wrapper, cmdArgs []string, opts groupOptions) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find vectra-guard executable: %w", err)
+	}
+	cmd := e.command(append(wrapper, self))
+	return launchInit(ctx, cmd, initSpec{Trace: e.config.Trace, Args: cmdArgs}, e.config.CgroupPath, e.config.KillGrace, opts)
+}
+
+// IsSandboxInit reports whether this process was started as a sandbox init.
+// main checks it before anything else so the init never runs CLI code.
+func IsSandboxInit() bool {
+	return os.Getenv(initEnv) == "1"
+}
+
+// RunSandboxInit builds the sandbox and runs the command. In namespaces it
+// stays as PID 1 and exits with the command's status; under Landlock it
+// restricts itself and execs the command. It never returns.
+func RunSandboxInit() {
+	code, err := runInit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vectra-guard sandbox: %v\n", err)
+		os.Exit(sandboxSetupFailed)
+	}
+	os.Exit(code)
+}
+
+func runInit() (int, error) {
+	// Mounts and prctl settings must land on the thread that starts the command
+	runtime.LockOSThread()
+	os.Unsetenv(initEnv)
+
+	specFile := os.NewFile(3, "spec")
+	var spec initSpec
+	err := json.NewDecoder(specFile).Decode(&spec)
+	specFile.Close()
+	if err != nil {
+		return 0, fmt.Errorf("read sandbox spec: %w", err)
+	}
+	if len(spec.Args) == 0 {
+		return 0, fmt.Errorf("no command specified")
+	}
+	if spec.Landlock != nil {
+		return 0, execLandlocked(*spec.Landlock, spec.Args)
+	}
+	var reporter *execReporter
+	if spec.Trace != nil {
+		reporter = openExecReporter()
+	}
+	if spec.Mount == nil {
+		// Inside bubblewrap, which has built the sandbox already
+		execPath, err := findExecutable(spec.Args[0])
+		if err != nil {
+			return 0, fmt.Errorf("executable not found: %w", err)
+		}
+		return supervise(execPath, spec.Args, spec.Trace, reporter)
+	}
+	e := NewMountNamespaceExecutor(*spec.Mount)
+
+	// Make all mounts private (don't propagate changes)
+	if err := unix.Mount("", "/", "", unix.MS_PRIVATE|unix.MS_REC, ""); err != nil {
+		return 0, fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	if err := e.setupFilesystemIsolation(); err != nil {
+		return 0, fmt.Errorf("failed to setup filesystem isolation: %w", err)
+	}
+
+	// A fresh /proc shows only the sandbox's processes
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return 0, fmt.Errorf("failed to mount /proc: %w", err)
+	}
+	if !spec.Mount.AllowNetwork {
+		if err := loopbackUp(); err != nil {
+			return 0, fmt.Errorf("failed to bring up loopback: %w", err)
+		}
+	}
+	if err := unix.Sethostname([]byte("vectra-guard")); err != nil {
+		return 0, fmt.Errorf("failed to set hostname: %w", err)
+	}
+
+	if err := DropCapabilities(spec.Mount.CapabilitySet); err != nil {
+		return 0, fmt.Errorf("failed to drop capabilities: %w", err)
+	}
+	if err := ApplySeccompFilter(spec.Mount.SeccompProfile); err != nil {
+		return 0, fmt.Errorf("failed to apply seccomp filter: %w", err)
+	}
+	if err := EnsureNoNewPrivs(); err != nil {
+		return 0, fmt.Errorf("failed to set NO_NEW_PRIVS: %w", err)
+	}
+
+	execPath, err := findExecutable(spec.Args[0])
+	if err != nil {
+		return 0, fmt.Errorf("executable not found: %w", err)
+	}
+	return supervise(execPath, spec.Args, spec.Trace, reporter)
+}
+
+// loopbackUp brings up lo in the new network namespace, which starts down.
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return err
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
+
+// supervise runs the command and acts as PID 1 for it: signals sent to the
+// init are forwarded, orphans are reaped, and once the command exits the
+// rest of the sandbox is killed. The command gets a process group of its
+// own, made the terminal's foreground job if the init is, so signals from
+// the terminal reach it once rather than through the init as well. With
+// trace, every program the command runs is reported first; the command is
+// ptraced from this thread, or polled if ptrace isn't allowed.
+func supervise(execPath string, args []string, trace *TraceConfig, reporter *execReporter) (int, error) {
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+
+	attr := &os.ProcAttr{
+		Env:   os.Environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys: &syscall.SysProcAttr{
+			Setpgid:    true,
+			Foreground: foregroundTerminal(),
+			Ctty:       0, // The command's stdin
+			Ptrace:     trace != nil && !trace.Poll,
+		},
+	}
+	proc, err := os.StartProcess(execPath, args, attr)
+	if err != nil && attr.Sys.Ptrace {
+		attr.Sys.Ptrace = false
+		proc, err = os.StartProcess(execPath, args, attr)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("start %s: %w", args[0], err)
+	}
+
+	var tracer *ptracer
+	if attr.Sys.Ptrace {
+		tracer = newPtracer(proc.Pid, reporter.report)
+	} else if trace != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go pollExecs(stop, proc.Pid, args, trace.interval(), func(ev ExecEvent) bool {
+			if !reporter.report(ev) {
+				unix.Kill(-1, unix.SIGKILL)
+				return false
+			}
+			return true
+		})
+	}
+
+	suspended := false
+	for sig := range signals {
+		switch sig {
+		case syscall.SIGCHLD:
+			if code, done := reap(proc.Pid, tracer, suspended); done {
+				unix.Kill(-1, unix.SIGKILL) // Stragglers die with the namespace anyway
+				return code, nil
+			}
+			// Only vectra-guard can suspend the job; a command that stops
+			// itself, like vim on Ctrl-Z, is continued as it would be
+			// without the init (see ProcessGroup.suspend). A traced one
+			// is continued by the tracer.
+			var info unix.Siginfo
+			if tracer == nil && !suspended && unix.Waitid(unix.P_PID, proc.Pid, &info, unix.WSTOPPED|unix.WNOHANG, nil) == nil && info.Signo != 0 {
+				unix.Kill(-proc.Pid, unix.SIGCONT)
+			}
+		case syscall.SIGURG:
+			// Go runtime preemption, not meant for the command
+		default:
+			switch sig {
+			case syscall.SIGTSTP, syscall.SIGSTOP:
+				suspended = true
+			case syscall.SIGCONT:
+				suspended = false
+				if tracer != nil {
+					tracer.release()
+				}
+			}
+			unix.Kill(-proc.Pid, sig.(syscall.Signal))
+		}
+	}
+	return 0, nil
+}
+
+// reap collects every exited child and reports the main command's status
+// once it has exited. When tracing, ptrace-stops are handled here too.
+func reap(pid int, tracer *ptracer, suspended bool) (int, bool) {
+	flags := unix.WNOHANG
+	if tracer != nil {
+		flags |= unix.WALL // Traced threads too
+	}
+	for {
+		var status unix.WaitStatus
+		wpid, err := unix.Wait4(-1, &status, flags, nil)
+		if err != nil || wpid <= 0 {
+			return 0, false
+		}
+		if tracer != nil {
+			if status.Stopped() {
+				tracer.stopped(wpid, status, suspended)
+				continue
+			}
+			tracer.exited(wpid)
+		}
+		if wpid == pid {
+			return exitCode(status), true
+		}
+	}
+}
+
+// exitCode maps a wait status to a shell-style exit code.
+func exitCode(status unix.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
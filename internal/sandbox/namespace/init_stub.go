@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package namespace
+
+import (
+	"context"
+	"fmt"
+)
+
+// IsSandboxInit reports whether this process was started as a sandbox init
+// (never on non-Linux).
+func IsSandboxInit() bool {
+	return false
+}
+
+// RunSandboxInit is only used on Linux.
+func RunSandboxInit() {}
+
+// executeTraced is only supported on Linux.
+func (e *BubblewrapExecutor) executeTraced(ctx context.Context, wrapper, cmdArgs []string, opts groupOptions) error {
+	return fmt.Errorf("tracing is only supported on Linux")
+}
@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestMain lets the test binary act as the sandbox init, as main does.
+func TestMain(m *testing.M) {
+	if IsSandboxInit() {
+		RunSandboxInit()
+	}
+	os.Exit(m.Run())
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		status unix.WaitStatus
+		want   int
+	}{
+		{status: 0, want: 0},
+		{status: 3 << 8, want: 3},
+		{status: unix.WaitStatus(unix.SIGKILL), want: 137},
+		{status: unix.WaitStatus(unix.SIGTERM), want: 143},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.status); got != tt.want {
+			t.Errorf("exitCode(%#x) = %d, want %d", uint32(tt.status), got, tt.want)
+		}
+	}
+}
+
+func TestMountNamespaceExecutor(t *testing.T) {
+	if err := exec.Command("unshare", "-Urpf", "--mount-proc", "true").Run(); err != nil {
+		t.Skipf("user namespaces not available: %v", err)
+	}
+
+	workspace := t.TempDir()
+	executor := NewMountNamespaceExecutor(MountConfig{
+		Workspace:      workspace,
+		CacheDir:       t.TempDir(),
+		SeccompProfile: SeccompProfileNone,
+		CapabilitySet:  CapSetMinimal,
+	})
+
+	script := `
+		test "$(hostname)" = vectra-guard || exit 10
+		grep -q vectra-guard-init /proc/1/cmdline || exit 11
+		echo ok > "$1/out" || exit 12
+		touch /etc/vectra-guard-test 2>/dev/null && exit 13
+		exit 3`
+	err := executor.Execute(context.Background(), []string{"sh", "-c", script, "sh", workspace})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Execute() error = %v, want exit status", err)
+	}
+	if code := exitErr.ExitCode(); code != 3 {
+		t.Fatalf("exit code = %d, want 3", code)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "out")); err != nil || string(data) != "ok\n" {
+		t.Errorf("workspace write = %q, %v", data, err)
+	}
+}
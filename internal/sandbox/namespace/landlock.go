@@ -0,0 +1,287 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// LandlockConfig holds configuration for the Landlock sandbox. Landlock can
+// only allow access, not remap paths, so bind mounts grant their source.
+type LandlockConfig struct {
+	Workspace       string
+	WorkspaceSource string // Staging copy the command runs in instead of the workspace
+	CacheDir        string
+	AllowNetwork    bool
+	ReadOnlyPaths   []string
+	MaskedPaths     []string // Not accessible at all
+	BindMounts      []BindMount
+	CgroupPath      string        // Resource-limited cgroup to start in (see CreateCgroup)
+	KillGrace       time.Duration // SIGTERM to SIGKILL when the context ends (see ProcessGroup.Wait)
+	Trace           *TraceConfig  `json:"-"` // Report the programs the command runs (see TraceConfig)
+}
+
+// LandlockExecutor restricts file and network access with Landlock. It needs
+// neither namespaces nor root, but doesn't isolate processes.
+type LandlockExecutor struct {
+	config LandlockConfig
+}
+
+// NewLandlockExecutor creates a new Landlock executor
+func NewLandlockExecutor(config LandlockConfig) *LandlockExecutor {
+	return &LandlockExecutor{config: config}
+}
+
+// Execute runs a command under a Landlock ruleset. vectra-guard re-executes
+// itself, restricts that process and execs the command, so a non-zero exit
+// is returned as an *exec.ExitError carrying the command's status. When ctx
+// ends the command's process group is stopped and ctx.Err() is returned.
+func (e *LandlockExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return startInit(ctx, initSpec{Landlock: &e.config, Trace: e.config.Trace, Args: cmdArgs}, &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}, e.config.CgroupPath, e.config.KillGrace)
+}
+
+// Access rights, see linux/landlock.h
+const (
+	landlockRead      = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockDevice    = landlockRead | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	landlockFileOnly  = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	landlockNetAccess = unix.LANDLOCK_ACCESS_NET_BIND_TCP | unix.LANDLOCK_ACCESS_NET_CONNECT_TCP
+)
+
+// landlockSystemDirs are readable (and executable) inside the sandbox
+var landlockSystemDirs = []string{
+	"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc",
+	"/var", "/opt", "/sys", "/proc", "/run", "/nix",
+}
+
+// LandlockABI returns the kernel's Landlock ABI version, or 0 if Landlock is
+// unsupported or disabled.
+func LandlockABI() int {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// landlockHandledFS is every filesystem right the ABI knows about. Rights
+// that aren't handled stay allowed everywhere.
+func landlockHandledFS(abi int) uint64 {
+	rights := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1) // ABI 1
+	if abi >= 2 {
+		rights |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		rights |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		rights |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return rights
+}
+
+// landlockRule grants rights beneath a path
+type landlockRule struct {
+	Path   string
+	Rights uint64
+}
+
+// landlockRules lists the access granted by config: system directories
+// read-only, the workspace, caches and /tmp read-write, and nothing else, so
+// secrets in the home directory stay out of reach. Rules only ever add
+// access, so read-only and masked paths inside a granted tree are carved out
+// by granting their siblings one by one.
+func landlockRules(config LandlockConfig, home string, handled uint64) []landlockRule {
+	readWrite := handled
+	except := map[string]uint64{}
+	for _, path := range config.ReadOnlyPaths {
+		except[filepath.Clean(path)] = landlockRead
+	}
+	for _, path := range config.MaskedPaths {
+		except[filepath.Clean(path)] = 0
+	}
+
+	var roots []landlockRule
+	for _, dir := range landlockSystemDirs {
+		roots = append(roots, landlockRule{dir, landlockRead})
+	}
+	roots = append(roots, landlockRule{"/dev", landlockDevice}, landlockRule{"/tmp", readWrite})
+	if home != "" {
+		roots = append(roots, landlockRule{filepath.Join(home, ".local", "bin"), landlockRead})
+		for _, dir := range homeCacheDirs(home) {
+			roots = append(roots, landlockRule{dir, readWrite})
+		}
+	}
+	if config.CacheDir != "" {
+		roots = append(roots, landlockRule{config.CacheDir, readWrite})
+	}
+	if config.Workspace != "" {
+		if config.WorkspaceSource != "" {
+			roots = append(roots, landlockRule{config.Workspace, landlockRead}, landlockRule{config.WorkspaceSource, readWrite})
+		} else {
+			roots = append(roots, landlockRule{config.Workspace, readWrite})
+		}
+	}
+	for _, mount := range config.BindMounts {
+		rights := readWrite
+		if mount.ReadOnly {
+			rights = landlockRead
+		}
+		roots = append(roots, landlockRule{mount.Source, rights})
+	}
+	for path, rights := range except {
+		if rights != 0 {
+			roots = append(roots, landlockRule{path, rights})
+		}
+	}
+
+	var rules []landlockRule
+	for _, root := range roots {
+		rules = appendTreeRules(rules, filepath.Clean(root.Path), root.Rights&handled, except)
+	}
+	return rules
+}
+
+// appendTreeRules grants rights beneath path, except at the paths in except,
+// which get their own rights. Any right on a directory covers everything
+// below it, so directories on the way to an exception can only be listed and
+// their other entries are granted individually.
+func appendTreeRules(rules []landlockRule, path string, rights uint64, except map[string]uint64) []landlockRule {
+	if own, ok := except[path]; ok {
+		rights &= own
+	}
+	if rights == 0 {
+		return rules
+	}
+	if !hasExceptionBelow(path, except) {
+		return append(rules, landlockRule{path, rights})
+	}
+	rules = append(rules, landlockRule{path, rights & unix.LANDLOCK_ACCESS_FS_READ_DIR})
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return rules
+	}
+	for _, entry := range entries {
+		rules = appendTreeRules(rules, filepath.Join(path, entry.Name()), rights, except)
+	}
+	return rules
+}
+
+func hasExceptionBelow(path string, except map[string]uint64) bool {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for p := range except {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLandlock restricts the calling thread, and whatever it execs, to the
+// rules. TCP bind and connect are blocked unless the network is allowed
+// (ABI 4+), and signals can't reach processes outside the sandbox (ABI 6+).
+func applyLandlock(config LandlockConfig, abi int) error {
+	handled := landlockHandledFS(abi)
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	if abi >= 4 && !config.AllowNetwork {
+		attr.Access_net = landlockNetAccess
+	}
+	if abi >= 6 {
+		attr.Scoped = unix.LANDLOCK_SCOPE_SIGNAL
+		if !config.AllowNetwork {
+			attr.Scoped |= unix.LANDLOCK_SCOPE_ABSTRACT_UNIX_SOCKET
+		}
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("create ruleset: %w", errno)
+	}
+	rulesetFd := int(fd)
+	defer unix.Close(rulesetFd)
+
+	home, _ := os.UserHomeDir()
+	for _, rule := range landlockRules(config, home, handled) {
+		if err := addLandlockRule(rulesetFd, rule); err != nil {
+			return fmt.Errorf("allow %s: %w", rule.Path, err)
+		}
+	}
+
+	if err := EnsureNoNewPrivs(); err != nil {
+		return err
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(rulesetFd), 0, 0); errno != 0 {
+		return fmt.Errorf("restrict self: %w", errno)
+	}
+	return nil
+}
+
+// addLandlockRule adds a path-beneath rule. Missing paths are skipped.
+func addLandlockRule(rulesetFd int, rule landlockRule) error {
+	if rule.Rights == 0 {
+		return nil
+	}
+	fd, err := unix.Open(rule.Path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil
+	}
+	defer unix.Close(fd)
+
+	rights := rule.Rights
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		rights &= landlockFileOnly // Directory rights are invalid on files
+		if rights == 0 {
+			return nil
+		}
+	}
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: rights, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// execLandlocked applies the ruleset and replaces this process with the
+// command, starting in the staging copy when there is one.
+func execLandlocked(config LandlockConfig, args []string) error {
+	abi := LandlockABI()
+	if abi == 0 {
+		return fmt.Errorf("landlock is not available")
+	}
+	if config.WorkspaceSource != "" {
+		cwd, err := os.Getwd()
+		if err == nil {
+			if rel, err := filepath.Rel(config.Workspace, cwd); err == nil && !strings.HasPrefix(rel, "..") {
+				if err := os.Chdir(filepath.Join(config.WorkspaceSource, rel)); err != nil {
+					return fmt.Errorf("enter workspace copy: %w", err)
+				}
+			}
+		}
+	}
+	if err := applyLandlock(config, abi); err != nil {
+		return fmt.Errorf("failed to apply landlock: %w", err)
+	}
+	execPath, err := findExecutable(args[0])
+	if err != nil {
+		return fmt.Errorf("executable not found: %w", err)
+	}
+	return syscall.Exec(execPath, args, os.Environ())
+}
+
+// IsLandlockAvailable checks if Landlock sandboxing is available
+func IsLandlockAvailable() bool {
+	return LandlockABI() > 0
+}
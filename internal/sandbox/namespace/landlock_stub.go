@@ -0,0 +1,49 @@
+//go:build !linux
+// +build !linux
+
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LandlockConfig holds configuration for the Landlock sandbox (stub for non-Linux)
+type LandlockConfig struct {
+	Workspace       string
+	WorkspaceSource string
+	CacheDir        string
+	AllowNetwork    bool
+	ReadOnlyPaths   []string
+	MaskedPaths     []string
+	BindMounts      []BindMount
+	CgroupPath      string
+	KillGrace       time.Duration
+	Trace           *TraceConfig
+}
+
+// LandlockExecutor restricts file and network access with Landlock (stub)
+type LandlockExecutor struct {
+	config LandlockConfig
+}
+
+// NewLandlockExecutor creates a new Landlock executor (stub)
+func NewLandlockExecutor(config LandlockConfig) *LandlockExecutor {
+	return &LandlockExecutor{config: config}
+}
+
+// Execute runs a command (returns error on non-Linux)
+func (e *LandlockExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return fmt.Errorf("landlock is only supported on Linux")
+}
+
+// LandlockABI returns 0: Landlock is Linux-only
+func LandlockABI() int {
+	return 0
+}
+
+// IsLandlockAvailable checks if Landlock sandboxing is available
+func IsLandlockAvailable() bool {
+	return false
+}
@@ -0,0 +1,98 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestLandlockRulesCarveOutProtectedPaths(t *testing.T) {
+	workspace := t.TempDir()
+	for _, dir := range []string{"src", "infra/prod", "infra/dev"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(workspace, ".env"), []byte("KEY=1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	handled := landlockHandledFS(3)
+	rules := landlockRules(LandlockConfig{
+		Workspace:     workspace,
+		ReadOnlyPaths: []string{filepath.Join(workspace, "infra/prod")},
+		MaskedPaths:   []string{filepath.Join(workspace, ".env")},
+	}, "", handled)
+
+	granted := map[string]uint64{}
+	for _, rule := range rules {
+		granted[rule.Path] |= rule.Rights
+	}
+	tests := []struct {
+		path string
+		want uint64
+	}{
+		{workspace, unix.LANDLOCK_ACCESS_FS_READ_DIR},
+		{filepath.Join(workspace, "src"), handled},
+		{filepath.Join(workspace, "infra"), unix.LANDLOCK_ACCESS_FS_READ_DIR},
+		{filepath.Join(workspace, "infra/dev"), handled},
+		{filepath.Join(workspace, "infra/prod"), landlockRead},
+		{filepath.Join(workspace, ".env"), 0},
+		{"/usr", landlockRead},
+	}
+	for _, tt := range tests {
+		if got := granted[tt.path]; got != tt.want {
+			t.Errorf("rights for %s = %#x, want %#x", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLandlockHandledFS(t *testing.T) {
+	if got := landlockHandledFS(1); got&unix.LANDLOCK_ACCESS_FS_REFER != 0 || got&unix.LANDLOCK_ACCESS_FS_MAKE_SYM == 0 {
+		t.Errorf("ABI 1 rights = %#x", got)
+	}
+	if got := landlockHandledFS(5); got&unix.LANDLOCK_ACCESS_FS_IOCTL_DEV == 0 || got&unix.LANDLOCK_ACCESS_FS_TRUNCATE == 0 {
+		t.Errorf("ABI 5 rights = %#x", got)
+	}
+}
+
+func TestLandlockExecutor(t *testing.T) {
+	if LandlockABI() == 0 {
+		t.Skip("landlock not available")
+	}
+
+	workspace := t.TempDir()
+	secret := filepath.Join(workspace, "secret")
+	if err := os.WriteFile(secret, []byte("s"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(workspace, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewLandlockExecutor(LandlockConfig{
+		Workspace:   workspace,
+		MaskedPaths: []string{secret},
+	})
+
+	script := `
+		echo ok > "$1/src/out" || exit 10
+		cat "$1/secret" 2>/dev/null && exit 11
+		touch /etc/vectra-guard-test 2>/dev/null && exit 12
+		exit 3`
+	err := executor.Execute(context.Background(), []string{"sh", "-c", script, "sh", workspace})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Execute() error = %v, want exit status", err)
+	}
+	if code := exitErr.ExitCode(); code != 3 {
+		t.Fatalf("exit code = %d, want 3", code)
+	}
+}
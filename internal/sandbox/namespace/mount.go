@@ -3,10 +3,13 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -17,10 +20,20 @@ type MountConfig struct {
 	CacheDir       string
 	AllowNetwork   bool
 	ReadOnlyPaths  []string
+	MaskedPaths    []string // Hidden behind an empty tmpfs or /dev/null
 	BindMounts     []BindMount
 	UseOverlayFS   bool
 	SeccompProfile SeccompProfile
 	CapabilitySet  CapabilitySet
+
+	// Copy-on-write workspace (see BubblewrapConfig)
+	WorkspaceSource string
+	OverlayUpper    string
+	OverlayWork     string
+
+	CgroupPath string        // Resource-limited cgroup to start in (see CreateCgroup)
+	KillGrace  time.Duration // SIGTERM to SIGKILL when the context ends (see ProcessGroup.Wait)
+	Trace      *TraceConfig  `json:"-"` // Report the programs the command runs (see TraceConfig)
 }
 
 // MountNamespaceExecutor executes commands in a custom mount namespace
@@ -33,58 +46,32 @@ func NewMountNamespaceExecutor(config MountConfig) *MountNamespaceExecutor {
 	return &MountNamespaceExecutor{config: config}
 }
 
-// Execute runs a command in a mount namespace sandbox
-func (e *MountNamespaceExecutor) Execute(cmdArgs []string) error {
-	// Fork and setup sandbox in child process
-	// The parent will wait for the child to complete
-	return e.executeInNamespace(cmdArgs)
+// Execute runs a command in a sandbox child. vectra-guard re-executes itself
+// as the init of new user, mount, PID, IPC, UTS and (unless the network is
+// allowed) network namespaces; the init sets up the filesystem and runs the
+// command. A non-zero exit is returned as an *exec.ExitError carrying the
+// command's status (sandboxSetupFailed if the sandbox couldn't be built).
+// When ctx ends the init is stopped, which takes the whole sandbox with it,
+// and ctx.Err() is returned.
+func (e *MountNamespaceExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return startInit(ctx, initSpec{Mount: &e.config, Trace: e.config.Trace, Args: cmdArgs}, e.sysProcAttr(), e.config.CgroupPath, e.config.KillGrace)
 }
 
-// executeInNamespace sets up the sandbox and executes the command
-func (e *MountNamespaceExecutor) executeInNamespace(cmdArgs []string) error {
-	// Create new mount namespace
-	if err := unix.Unshare(unix.CLONE_NEWNS); err != nil {
-		return fmt.Errorf("failed to create mount namespace: %w", err)
-	}
-
-	// Make all mounts private (don't propagate changes)
-	if err := unix.Mount("", "/", "", unix.MS_PRIVATE|unix.MS_REC, ""); err != nil {
-		return fmt.Errorf("failed to make mounts private: %w", err)
-	}
-
-	// Setup filesystem isolation
-	if err := e.setupFilesystemIsolation(); err != nil {
-		return fmt.Errorf("failed to setup filesystem isolation: %w", err)
-	}
-
-	// Drop capabilities
-	if err := DropCapabilities(e.config.CapabilitySet); err != nil {
-		return fmt.Errorf("failed to drop capabilities: %w", err)
-	}
-
-	// Apply seccomp filter
-	if err := ApplySeccompFilter(e.config.SeccompProfile); err != nil {
-		return fmt.Errorf("failed to apply seccomp filter: %w", err)
+// sysProcAttr creates the namespaces. The caller's uid and gid are mapped to
+// root inside the user namespace, which owns the others, so no host
+// privileges are needed.
+func (e *MountNamespaceExecutor) sysProcAttr() *syscall.SysProcAttr {
+	flags := unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID | unix.CLONE_NEWIPC | unix.CLONE_NEWUTS
+	if !e.config.AllowNetwork {
+		flags |= unix.CLONE_NEWNET // Only loopback inside
 	}
-
-	// Ensure NO_NEW_PRIVS
-	if err := EnsureNoNewPrivs(); err != nil {
-		return fmt.Errorf("failed to set NO_NEW_PRIVS: %w", err)
+	return &syscall.SysProcAttr{
+		Cloneflags:                 uintptr(flags),
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
 	}
-
-	// Execute the command
-	if len(cmdArgs) == 0 {
-		return fmt.Errorf("no command specified")
-	}
-
-	// Find the executable
-	execPath, err := findExecutable(cmdArgs[0])
-	if err != nil {
-		return fmt.Errorf("executable not found: %w", err)
-	}
-
-	// Execute the command (replaces current process)
-	return syscall.Exec(execPath, cmdArgs, os.Environ())
 }
 
 // setupFilesystemIsolation configures the filesystem for sandboxing
@@ -94,29 +81,67 @@ func (e *MountNamespaceExecutor) setupFilesystemIsolation() error {
 		return fmt.Errorf("failed to remount root as read-only: %w", err)
 	}
 
-	// Strategy 2: Setup writable /tmp with OverlayFS or tmpfs
-	if err := e.setupWritableTmp(); err != nil {
-		return fmt.Errorf("failed to setup writable /tmp: %w", err)
-	}
-
-	// Strategy 3: Bind mount workspace as writable
+	// Strategy 2: Bind mount workspace as writable (before /tmp is covered,
+	// which may hold the copy-on-write directories)
 	if err := e.bindMountWorkspace(); err != nil {
 		return fmt.Errorf("failed to bind mount workspace: %w", err)
 	}
 
+	// Strategy 3: Setup writable /tmp with OverlayFS or tmpfs
+	if err := e.setupWritableTmp(); err != nil {
+		return fmt.Errorf("failed to setup writable /tmp: %w", err)
+	}
+
 	// Strategy 4: Bind mount cache directories
 	if err := e.bindMountCaches(); err != nil {
 		return fmt.Errorf("failed to bind mount caches: %w", err)
 	}
 
+	// Strategy 5: Protect paths inside the writable mounts
+	if err := e.protectPaths(); err != nil {
+		return fmt.Errorf("failed to protect paths: %w", err)
+	}
+
+	return nil
+}
+
+// protectPaths remounts ReadOnlyPaths read-only and hides MaskedPaths behind
+// an empty read-only tmpfs (directories) or /dev/null (files).
+func (e *MountNamespaceExecutor) protectPaths() error {
+	for _, path := range e.config.ReadOnlyPaths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %s: %w", path, err)
+		}
+		if err := remountBind(path, true); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", path, err)
+		}
+	}
+
+	for _, path := range e.config.MaskedPaths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			err = unix.Mount("tmpfs", path, "tmpfs", unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "size=4k,mode=0555")
+		} else {
+			err = unix.Mount("/dev/null", path, "", unix.MS_BIND, "")
+		}
+		if err != nil {
+			return fmt.Errorf("mask %s: %w", path, err)
+		}
+	}
+
 	return nil
 }
 
 // remountRootReadOnly remounts the root filesystem as read-only
 func (e *MountNamespaceExecutor) remountRootReadOnly() error {
 	// Remount root as read-only
-	flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY
-	if err := unix.Mount("/", "/", "", uintptr(flags), ""); err != nil {
+	if err := remountBind("/", true); err != nil {
 		return fmt.Errorf("failed to remount / as read-only: %w", err)
 	}
 
@@ -130,15 +155,50 @@ func (e *MountNamespaceExecutor) remountRootReadOnly() error {
 		if _, err := os.Stat(path); err == nil {
 			// Bind mount to itself first, then remount as read-only
 			unix.Mount(path, path, "", unix.MS_BIND, "")
-			unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, "")
+			remountBind(path, true)
 		}
 	}
 
 	return nil
 }
 
+// remountBind sets whether the bind mount at path is read-only. Bind mounts
+// copy the flags of the mount they come from, so binds taken from the
+// read-only root must be made writable again. The existing
+// nosuid/nodev/noexec/atime flags are kept: inside a user namespace they are
+// locked and a remount that clears them fails.
+func remountBind(path string, readOnly bool) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	for stFlag, msFlag := range map[int64]uintptr{
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	return unix.Mount(path, path, "", flags, "")
+}
+
 // setupWritableTmp creates a writable /tmp using tmpfs or overlayfs
 func (e *MountNamespaceExecutor) setupWritableTmp() error {
+	// A private /tmp would hide a workspace that lives in it
+	if absWorkspace, err := filepath.Abs(e.config.Workspace); err == nil && e.config.Workspace != "" &&
+		(absWorkspace == "/tmp" || strings.HasPrefix(absWorkspace, "/tmp/")) {
+		return nil
+	}
+
 	if e.config.UseOverlayFS {
 		// Use OverlayFS for /tmp
 		return e.setupOverlayFS("/tmp")
@@ -159,11 +219,9 @@ func (e *MountNamespaceExecutor) setupOverlayFS(path string) error {
 	upperDir := filepath.Join(overlayDir, "upper")
 	workDir := filepath.Join(overlayDir, "work")
 
-	if err := os.MkdirAll(upperDir, 0755); err != nil {
-		return err
-	}
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		return err
+	// The cache may be on the now read-only root; fall back to tmpfs
+	if os.MkdirAll(upperDir, 0755) != nil || os.MkdirAll(workDir, 0755) != nil {
+		return unix.Mount("tmpfs", path, "tmpfs", 0, "size=1G,mode=1777")
 	}
 
 	// Mount OverlayFS
@@ -192,9 +250,43 @@ func (e *MountNamespaceExecutor) bindMountWorkspace() error {
 		return err
 	}
 
-	// Bind mount workspace to itself as writable
-	if err := unix.Mount(absWorkspace, absWorkspace, "", unix.MS_BIND, ""); err != nil {
-		return fmt.Errorf("failed to bind mount workspace: %w", err)
+	// Bind mount workspace to itself as writable, or copy-on-write
+	cwd, _ := os.Getwd()
+	switch {
+	case e.config.OverlayUpper != "":
+		// The upper and work dirs sit on the read-only root and must share
+		// a mount, so make their common parent writable
+		dir := filepath.Dir(e.config.OverlayUpper)
+		if err := unix.Mount(dir, dir, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("failed to bind mount %s: %w", dir, err)
+		}
+		if err := remountBind(dir, false); err != nil {
+			return fmt.Errorf("failed to make %s writable: %w", dir, err)
+		}
+		// Inside a user namespace overlayfs keeps its metadata in user.* xattrs
+		opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", absWorkspace, e.config.OverlayUpper, e.config.OverlayWork)
+		if err := unix.Mount("overlay", absWorkspace, "overlay", 0, opts+",userxattr"); err != nil {
+			if err := unix.Mount("overlay", absWorkspace, "overlay", 0, opts); err != nil {
+				return fmt.Errorf("failed to mount workspace overlay: %w", err)
+			}
+		}
+	case e.config.WorkspaceSource != "":
+		if err := unix.Mount(e.config.WorkspaceSource, absWorkspace, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind mount workspace copy: %w", err)
+		}
+		if err := remountBind(absWorkspace, false); err != nil {
+			return fmt.Errorf("failed to make workspace writable: %w", err)
+		}
+	default:
+		if err := unix.Mount(absWorkspace, absWorkspace, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("failed to bind mount workspace: %w", err)
+		}
+		if err := remountBind(absWorkspace, false); err != nil {
+			return fmt.Errorf("failed to make workspace writable: %w", err)
+		}
+	}
+	if cwd != "" {
+		os.Chdir(cwd) // Step onto the new mount if we were inside the workspace
 	}
 
 	// Also create a /workspace symlink for convenience
@@ -215,15 +307,9 @@ func (e *MountNamespaceExecutor) bindMountCaches() error {
 	}
 
 	// Default cache directories
-	caches := []BindMount{
-		{Source: filepath.Join(home, ".cache"), Target: filepath.Join(home, ".cache"), ReadOnly: false},
-		{Source: filepath.Join(home, ".npm"), Target: filepath.Join(home, ".npm"), ReadOnly: false},
-		{Source: filepath.Join(home, ".cargo"), Target: filepath.Join(home, ".cargo"), ReadOnly: false},
-		{Source: filepath.Join(home, ".rustup"), Target: filepath.Join(home, ".rustup"), ReadOnly: false},
-		{Source: filepath.Join(home, "go"), Target: filepath.Join(home, "go"), ReadOnly: false},
-		{Source: filepath.Join(home, ".m2"), Target: filepath.Join(home, ".m2"), ReadOnly: false},
-		{Source: filepath.Join(home, ".gradle"), Target: filepath.Join(home, ".gradle"), ReadOnly: false},
-		{Source: filepath.Join(home, ".pip"), Target: filepath.Join(home, ".pip"), ReadOnly: false},
+	caches := []BindMount{}
+	for _, dir := range homeCacheDirs(home) {
+		caches = append(caches, BindMount{Source: dir, Target: dir, ReadOnly: false})
 	}
 
 	// Add custom bind mounts
@@ -240,20 +326,25 @@ func (e *MountNamespaceExecutor) bindMountCaches() error {
 		}
 
 		// Bind mount
-		flags := unix.MS_BIND
-		if cache.ReadOnly {
-			flags |= unix.MS_RDONLY
-		}
-
-		if err := unix.Mount(cache.Source, cache.Target, "", uintptr(flags), ""); err != nil {
+		if err := unix.Mount(cache.Source, cache.Target, "", unix.MS_BIND, ""); err != nil {
 			// Non-fatal - just skip this cache
 			continue
 		}
+		remountBind(cache.Target, cache.ReadOnly)
 	}
 
 	return nil
 }
 
+// homeCacheDirs lists the package manager caches kept writable in the sandbox
+func homeCacheDirs(home string) []string {
+	var dirs []string
+	for _, name := range []string{".cache", ".npm", ".cargo", ".rustup", "go", ".m2", ".gradle", ".pip"} {
+		dirs = append(dirs, filepath.Join(home, name))
+	}
+	return dirs
+}
+
 // findExecutable finds the full path to an executable
 func findExecutable(name string) (string, error) {
 	// If it's already an absolute path, use it
@@ -3,7 +3,9 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
 // MountConfig holds configuration for mount namespace sandbox (stub for non-Linux)
@@ -12,10 +14,20 @@ type MountConfig struct {
 	CacheDir       string
 	AllowNetwork   bool
 	ReadOnlyPaths  []string
+	MaskedPaths    []string // Hidden behind an empty tmpfs or /dev/null
 	BindMounts     []BindMount
 	UseOverlayFS   bool
 	SeccompProfile SeccompProfile
 	CapabilitySet  CapabilitySet
+
+	// Copy-on-write workspace (see BubblewrapConfig)
+	WorkspaceSource string
+	OverlayUpper    string
+	OverlayWork     string
+
+	CgroupPath string        // Resource-limited cgroup to start in (see CreateCgroup)
+	KillGrace  time.Duration // SIGTERM to SIGKILL when the context ends (see ProcessGroup.Wait)
+	Trace      *TraceConfig  `json:"-"` // Report the programs the command runs (see TraceConfig)
 }
 
 // MountNamespaceExecutor executes commands in a custom mount namespace (stub)
@@ -29,7 +41,7 @@ func NewMountNamespaceExecutor(config MountConfig) *MountNamespaceExecutor {
 }
 
 // Execute runs a command (returns error on non-Linux)
-func (e *MountNamespaceExecutor) Execute(cmdArgs []string) error {
+func (e *MountNamespaceExecutor) Execute(ctx context.Context, cmdArgs []string) error {
 	return fmt.Errorf("mount namespaces are only supported on Linux")
 }
 
@@ -0,0 +1,312 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProcessGroup is a command started as the leader of its own process group,
+// so the command and everything it forks can be signalled together.
+type ProcessGroup struct {
+	cmd        *exec.Cmd
+	pty        *pty // The command's terminal, nil if it doesn't get one
+	foreground bool // Without a PTY, the group took over our terminal
+	relaying   bool // The leader is a sandbox init passing signals on to the command
+	signals    chan os.Signal
+	relayDone  chan struct{}
+	done       chan error
+	exited     bool // The leader has been reaped
+}
+
+// relayedSignals are forwarded to the whole group while it runs
+var relayedSignals = []os.Signal{unix.SIGINT, unix.SIGTERM, unix.SIGHUP, unix.SIGQUIT, unix.SIGTSTP}
+
+// StartProcessGroup starts cmd in a new process group and forwards the
+// signals we get to it until Wait returns. If cmd reads our stdin and we are
+// the terminal's foreground job, it runs on a PTY of its own in a new
+// session, with our terminal in raw mode relaying to it; Wait restores the
+// terminal.
+func StartProcessGroup(cmd *exec.Cmd) (*ProcessGroup, error) {
+	return startProcessGroup(cmd, groupOptions{})
+}
+
+// groupOptions adjust StartProcessGroup for the sandbox runtimes
+type groupOptions struct {
+	noCtty   bool // A command given a PTY doesn't take it as its controlling terminal; what it runs has to
+	relaying bool // The command is a sandbox init that passes signals on
+}
+
+func startProcessGroup(cmd *exec.Cmd, opts groupOptions) (*ProcessGroup, error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	g := &ProcessGroup{cmd: cmd, relaying: opts.relaying}
+	if ownsTerminal(cmd) {
+		if p, err := openPTY(); err == nil {
+			g.pty = p
+			p.attach(cmd, !opts.noCtty)
+		} else {
+			// Share our terminal instead, as its foreground job
+			g.foreground = true
+			cmd.SysProcAttr.Foreground = true
+			cmd.SysProcAttr.Ctty = 0 // The child's stdin
+		}
+	}
+	if g.pty == nil {
+		cmd.SysProcAttr.Setpgid = true
+	}
+
+	g.signals = make(chan os.Signal, 16)
+	signal.Notify(g.signals, relayedSignals...)
+	if g.pty != nil {
+		signal.Notify(g.signals, unix.SIGWINCH)
+	}
+	if err := cmd.Start(); err != nil {
+		signal.Stop(g.signals)
+		if g.pty != nil {
+			g.pty.close()
+		}
+		if g.foreground {
+			reclaimTerminal()
+		}
+		return nil, err
+	}
+	if g.pty != nil {
+		if err := g.pty.start(); err != nil {
+			g.pty.close()
+			g.pty = nil
+		}
+	}
+	g.relayDone = make(chan struct{})
+	go g.relay()
+	return g, nil
+}
+
+// RunProcessGroup starts cmd in a new process group and waits for it (see
+// ProcessGroup.Wait).
+func RunProcessGroup(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	g, err := StartProcessGroup(cmd)
+	if err != nil {
+		return err
+	}
+	return g.Wait(ctx, grace)
+}
+
+// runProcessGroup is RunProcessGroup with options
+func runProcessGroup(ctx context.Context, cmd *exec.Cmd, grace time.Duration, opts groupOptions) error {
+	g, err := startProcessGroup(cmd, opts)
+	if err != nil {
+		return err
+	}
+	return g.Wait(ctx, grace)
+}
+
+// Wait waits for the group leader to exit. If ctx ends first, the whole
+// group gets SIGTERM, then SIGKILL if any of it is still running after
+// grace, and Wait returns ctx.Err().
+func (g *ProcessGroup) Wait(ctx context.Context, grace time.Duration) error {
+	defer g.finish()
+	g.done = make(chan error, 1)
+	go func() { g.done <- g.cmd.Wait() }()
+	select {
+	case err := <-g.done:
+		return err
+	case <-ctx.Done():
+	}
+
+	pgid := g.cmd.Process.Pid
+	unix.Kill(-pgid, unix.SIGTERM)
+	if !g.waitGone(grace) {
+		unix.Kill(-pgid, unix.SIGKILL)
+		g.waitGone(time.Second)
+	}
+	return ctx.Err()
+}
+
+// finish stops forwarding signals and gives the terminal back
+func (g *ProcessGroup) finish() {
+	signal.Stop(g.signals)
+	close(g.signals)
+	<-g.relayDone
+	if g.pty != nil {
+		g.pty.close()
+	}
+	if g.foreground {
+		reclaimTerminal()
+	}
+}
+
+// relay forwards signals to the group until finish. Termination signals
+// are passed on as they are, so Ctrl-C from a terminal the command doesn't
+// own, or a kill of vectra-guard, reaches the command rather than orphaning
+// it.
+func (g *ProcessGroup) relay() {
+	defer close(g.relayDone)
+	pgid := g.cmd.Process.Pid
+	for sig := range g.signals {
+		switch sig {
+		case unix.SIGWINCH:
+			g.pty.resize()
+		case unix.SIGTSTP:
+			g.suspend()
+		default:
+			unix.Kill(-pgid, sig.(syscall.Signal))
+		}
+	}
+}
+
+// suspend stops the command and our whole job, as Ctrl-Z would outside
+// vectra-guard, and continues the command once the shell resumes us. The
+// terminal is out of raw mode in between. A command on a PTY leads its own
+// session, where the kernel drops SIGTSTP, so it gets SIGSTOP; a sandbox
+// init gets SIGTSTP to pass on instead.
+func (g *ProcessGroup) suspend() {
+	pgid := g.cmd.Process.Pid
+	if g.relaying {
+		unix.Kill(-pgid, unix.SIGTSTP)
+	} else {
+		unix.Kill(-pgid, unix.SIGSTOP)
+	}
+	if g.pty != nil {
+		g.pty.restore()
+	}
+	unix.Kill(0, unix.SIGSTOP)
+	// Continued, possibly in a differently sized window
+	if g.pty != nil {
+		g.pty.raw()
+		g.pty.resize()
+	}
+	unix.Kill(-pgid, unix.SIGCONT)
+}
+
+// waitGone waits up to timeout for the leader to be reaped and every other
+// member of the group to exit. Members orphaned to us (see
+// SetChildSubreaper) are reaped here.
+func (g *ProcessGroup) waitGone(timeout time.Duration) bool {
+	pgid := g.cmd.Process.Pid
+	deadline := time.After(timeout)
+	for {
+		if !g.exited {
+			select {
+			case <-g.done:
+				g.exited = true
+			case <-deadline:
+				return false
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		// Only reap the rest once the leader's status is in
+		if g.exited {
+			for {
+				if wpid, _ := unix.Wait4(-pgid, nil, unix.WNOHANG, nil); wpid <= 0 {
+					break
+				}
+			}
+			if unix.Kill(-pgid, 0) == unix.ESRCH {
+				return true
+			}
+			select {
+			case <-deadline:
+				return false
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// ownsTerminal reports whether cmd's stdin is our terminal and we are its
+// foreground job.
+func ownsTerminal(cmd *exec.Cmd) bool {
+	if f, ok := cmd.Stdin.(*os.File); !ok || f != os.Stdin {
+		return false
+	}
+	return foregroundTerminal()
+}
+
+// reclaimTerminal makes our process group the terminal's foreground job
+// again. We are a background job until then, so SIGTTOU must be ignored.
+func reclaimTerminal() {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(0, unix.TIOCSPGRP, unix.Getpgrp())
+}
+
+// Process is a process a command left running
+type Process struct {
+	PID     int
+	Command string
+}
+
+// SetChildSubreaper makes orphaned descendants of this process reparent to
+// it rather than to init, so whatever a command leaves running in the
+// background, daemons included, can be found with Stragglers.
+func SetChildSubreaper() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
+}
+
+// Stragglers lists this process's live children. Called after a command
+// has been waited for, they are the background jobs and daemons it left
+// behind. Exited ones are reaped.
+func Stragglers() []Process {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	self := os.Getpid()
+	var procs []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// The command name in parentheses may contain spaces; the state and ppid follow it
+		stat := string(data)
+		fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+		if len(fields) < 2 || fields[1] != strconv.Itoa(self) {
+			continue
+		}
+		if fields[0] == "Z" {
+			unix.Wait4(pid, nil, unix.WNOHANG, nil)
+			continue
+		}
+		cmdline, _ := os.ReadFile("/proc/" + entry.Name() + "/cmdline")
+		command := strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+		if command == "" {
+			command = stat[strings.IndexByte(stat, '(')+1 : strings.LastIndexByte(stat, ')')]
+		}
+		procs = append(procs, Process{PID: pid, Command: command})
+	}
+	return procs
+}
+
+// KillStragglers kills and reaps every straggler, including the children
+// they leave behind as they die.
+func KillStragglers() {
+	for i := 0; i < 10; i++ {
+		procs := Stragglers()
+		if len(procs) == 0 {
+			return
+		}
+		for _, p := range procs {
+			unix.Kill(p.PID, unix.SIGKILL)
+		}
+		for _, p := range procs {
+			unix.Wait4(p.PID, nil, 0, nil)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !linux
+// +build !linux
+
+package namespace
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// ProcessGroup is a started command (stub, no process group)
+type ProcessGroup struct {
+	cmd *exec.Cmd
+}
+
+// StartProcessGroup starts cmd on non-Linux
+func StartProcessGroup(cmd *exec.Cmd) (*ProcessGroup, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &ProcessGroup{cmd: cmd}, nil
+}
+
+// RunProcessGroup starts cmd and waits for it
+func RunProcessGroup(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	g, err := StartProcessGroup(cmd)
+	if err != nil {
+		return err
+	}
+	return g.Wait(ctx, grace)
+}
+
+// groupOptions only matter on Linux
+type groupOptions struct {
+	noCtty   bool
+	relaying bool
+}
+
+// runProcessGroup is RunProcessGroup on non-Linux
+func runProcessGroup(ctx context.Context, cmd *exec.Cmd, grace time.Duration, opts groupOptions) error {
+	return RunProcessGroup(ctx, cmd, grace)
+}
+
+// foregroundTerminal is false on non-Linux, where commands never get a PTY
+func foregroundTerminal() bool {
+	return false
+}
+
+// Wait waits for the command, killing only it if ctx ends first
+func (g *ProcessGroup) Wait(ctx context.Context, grace time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- g.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	g.cmd.Process.Kill()
+	<-done
+	return ctx.Err()
+}
+
+// Process is a process a command left running
+type Process struct {
+	PID     int
+	Command string
+}
+
+// SetChildSubreaper does nothing on non-Linux
+func SetChildSubreaper() error {
+	return nil
+}
+
+// Stragglers finds nothing on non-Linux
+func Stragglers() []Process {
+	return nil
+}
+
+// KillStragglers does nothing on non-Linux
+func KillStragglers() {}
@@ -0,0 +1,108 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestRunProcessGroupTimeout(t *testing.T) {
+	// Reap orphaned members ourselves instead of waiting on init
+	if err := SetChildSubreaper(); err != nil {
+		t.Skipf("subreaper not available: %v", err)
+	}
+	tests := []struct {
+		name   string
+		script string
+		max    time.Duration
+	}{
+		// The whole group gets SIGTERM, background jobs included
+		{name: "terminated", script: "sleep 30 & sleep 30", max: 2 * time.Second},
+		// Ignoring SIGTERM only buys the grace period
+		{name: "killed", script: `trap "" TERM; sleep 30 & sleep 30`, max: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			cmd := exec.Command("sh", "-c", tt.script)
+			start := time.Now()
+			err := RunProcessGroup(ctx, cmd, 500*time.Millisecond)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("RunProcessGroup() error = %v, want deadline exceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.max {
+				t.Errorf("took %s", elapsed)
+			}
+			if err := unix.Kill(-cmd.Process.Pid, 0); err != unix.ESRCH {
+				t.Errorf("process group still alive: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunProcessGroupExit(t *testing.T) {
+	err := RunProcessGroup(context.Background(), exec.Command("sh", "-c", "exit 4"), time.Second)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Fatalf("RunProcessGroup() error = %v, want exit status 4", err)
+	}
+}
+
+func TestProcessGroupForwardsSignals(t *testing.T) {
+	// The shell only exits 7 if the SIGTERM sent to us reaches it
+	cmd := exec.Command("sh", "-c", `trap "exit 7" TERM; sleep 5 & wait`)
+	g, err := StartProcessGroup(cmd)
+	if err != nil {
+		t.Fatalf("StartProcessGroup() error = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	err = g.Wait(context.Background(), time.Second)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 7 {
+		t.Fatalf("Wait() error = %v, want exit status 7", err)
+	}
+}
+
+func TestStragglers(t *testing.T) {
+	if err := SetChildSubreaper(); err != nil {
+		t.Skipf("subreaper not available: %v", err)
+	}
+	// The daemon double-forks into its own session, out of reach of the group
+	script := `setsid sh -c 'sleep 30 &' ; sleep 31 &`
+	if err := exec.Command("sh", "-c", script).Run(); err != nil {
+		t.Fatal(err)
+	}
+	defer KillStragglers()
+
+	var commands []string
+	for i := 0; i < 50; i++ {
+		commands = nil
+		for _, p := range Stragglers() {
+			commands = append(commands, p.Command)
+		}
+		if len(commands) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond) // The setsid shell may not have exited yet
+	}
+	got := strings.Join(commands, ",")
+	if !strings.Contains(got, "sleep 30") || !strings.Contains(got, "sleep 31") {
+		t.Fatalf("Stragglers() = %q, want both sleeps", got)
+	}
+
+	KillStragglers()
+	if procs := Stragglers(); len(procs) != 0 {
+		t.Errorf("after KillStragglers: %v", procs)
+	}
+}
@@ -0,0 +1,240 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pty is a pseudo-terminal a command runs on. Our terminal is put in raw
+// mode and relayed to it, so the command sees a terminal of its own: the
+// terminal's own job control, line editing and window size work as they
+// would outside, and whatever state the command leaves it in doesn't leak
+// into the user's terminal.
+type pty struct {
+	master *os.File
+	slave  *os.File
+	out    *os.File     // Our terminal, where the command's output goes
+	saved  unix.Termios // Our terminal's state outside raw mode
+
+	stopInput  *os.File // Closed to stop the input relay
+	inputDone  chan struct{}
+	outputDone chan struct{}
+}
+
+// foregroundTerminal reports whether stdin is a terminal and we are its
+// foreground job, in which case commands get a PTY.
+func foregroundTerminal() bool {
+	pgrp, err := unix.IoctlGetInt(0, unix.TIOCGPGRP)
+	return err == nil && pgrp == unix.Getpgrp()
+}
+
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	return err == nil
+}
+
+// openPTY allocates a PTY with the settings and size of our terminal
+func openPTY() (*pty, error) {
+	saved, err := unix.IoctlGetTermios(0, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	p := &pty{master: master, saved: *saved, out: os.Stdout}
+	if !isTerminal(1) {
+		p.out = os.Stderr
+	}
+
+	var n uint32
+	err = p.control(func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return err
+		}
+		n, err = unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+		return err
+	})
+	if err == nil {
+		p.slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	}
+	if err == nil {
+		err = unix.IoctlSetTermios(int(p.slave.Fd()), unix.TCSETS, saved)
+	}
+	if err != nil {
+		master.Close()
+		if p.slave != nil {
+			p.slave.Close()
+		}
+		return nil, err
+	}
+	p.resize()
+	return p, nil
+}
+
+// control runs f on the master's fd without taking it out of non-blocking
+// mode, so Close still interrupts a pending Read.
+func (p *pty) control(f func(fd int) error) error {
+	conn, err := p.master.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := conn.Control(func(fd uintptr) { ferr = f(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// attach makes the PTY the command's terminal: whichever of its stdin,
+// stdout and stderr are ours and a terminal use the PTY instead. With
+// ctty the command becomes the leader of a new session with the PTY as its
+// controlling terminal; without, it only gets the new session and whatever
+// it runs must take the PTY itself.
+func (p *pty) attach(cmd *exec.Cmd, ctty bool) {
+	cmd.Stdin = p.slave
+	if cmd.Stdout == os.Stdout && isTerminal(1) {
+		cmd.Stdout = p.slave
+	}
+	if cmd.Stderr == os.Stderr && isTerminal(2) {
+		cmd.Stderr = p.slave
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = ctty
+	cmd.SysProcAttr.Ctty = 0 // The child's stdin
+}
+
+// start puts our terminal in raw mode and relays it to the started command
+func (p *pty) start() error {
+	p.slave.Close() // The command holds it now; reads on the master end once it's gone
+	stopR, stopW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	p.stopInput = stopW
+	p.inputDone = make(chan struct{})
+	p.outputDone = make(chan struct{})
+	p.raw()
+	go p.relayInput(stopR)
+	go func() {
+		io.Copy(p.out, p.master) // Ends with EIO once the command's side is closed
+		close(p.outputDone)
+	}()
+	return nil
+}
+
+// raw puts our terminal in raw mode: every key goes to the command, which
+// does its own echo, line editing and signal characters.
+func (p *pty) raw() {
+	t := p.saved
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB
+	t.Cflag |= unix.CS8
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	unix.IoctlSetTermios(0, unix.TCSETS, &t)
+}
+
+// restore puts our terminal back the way it was
+func (p *pty) restore() {
+	unix.IoctlSetTermios(0, unix.TCSETSW, &p.saved)
+}
+
+// resize gives the PTY our terminal's size; the kernel sends SIGWINCH to the
+// command when it changes.
+func (p *pty) resize() {
+	ws, err := unix.IoctlGetWinsize(0, unix.TIOCGWINSZ)
+	if err != nil {
+		return
+	}
+	p.control(func(fd int) error { return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, ws) })
+}
+
+// relayInput copies our stdin to the command until stop is closed. Reads
+// wait in poll so that nothing typed after the command ends is swallowed.
+func (p *pty) relayInput(stop *os.File) {
+	defer close(p.inputDone)
+	defer stop.Close()
+	buf := make([]byte, 4096)
+	fds := []unix.PollFd{{Fd: 0, Events: unix.POLLIN}, {Fd: int32(stop.Fd()), Events: unix.POLLIN}}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return
+		}
+		if fds[1].Revents != 0 {
+			return
+		}
+		n, err := unix.Read(0, buf)
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		if n <= 0 {
+			return
+		}
+		p.forwardInput(buf[:n])
+	}
+}
+
+// forwardInput writes what the user typed to the command. Where the PTY
+// would turn the suspend character (Ctrl-Z) into SIGTSTP for the command
+// alone, vectra-guard suspends itself instead, so the whole job stops and
+// the shell gets the terminal back (see ProcessGroup.suspend).
+func (p *pty) forwardInput(data []byte) {
+	var susp byte
+	p.control(func(fd int) error {
+		// On the master, TCGETS reads the command's side of the PTY
+		t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err == nil && t.Lflag&unix.ISIG != 0 {
+			susp = t.Cc[unix.VSUSP]
+		}
+		return err
+	})
+	for len(data) > 0 {
+		i := -1
+		if susp != 0 {
+			i = bytes.IndexByte(data, susp)
+		}
+		if i < 0 {
+			p.master.Write(data)
+			return
+		}
+		p.master.Write(data[:i])
+		unix.Kill(os.Getpid(), unix.SIGTSTP)
+		data = data[i+1:]
+	}
+}
+
+// close stops relaying once the command has exited and restores our
+// terminal. Output still buffered in the PTY is flushed first, unless a
+// background process keeps the PTY open.
+func (p *pty) close() {
+	if p.outputDone != nil {
+		select {
+		case <-p.outputDone:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	p.master.Close()
+	if p.stopInput != nil {
+		p.stopInput.Close()
+		<-p.inputDone
+		p.restore()
+	} else {
+		p.slave.Close() // Never started
+	}
+}
@@ -0,0 +1,338 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ExecEvent is a program started inside a traced command
+type ExecEvent struct {
+	PID  int       `json:"pid"` // As seen inside the sandbox
+	PPID int       `json:"ppid"`
+	Argv []string  `json:"argv"`
+	Cwd  string    `json:"cwd,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// ExecHandler is told about each program a traced command runs. Returning
+// false kills the command and everything it started.
+type ExecHandler func(ExecEvent) bool
+
+// TraceConfig asks a sandbox runtime to report the programs the command runs.
+// The namespace and bubblewrap runtimes ptrace the command from their init,
+// so each program is reported before it runs its first instruction. Where
+// ptrace isn't allowed, or Poll is set, /proc is polled instead: programs
+// are reported once running, and short-lived ones may be missed. Landlock
+// always polls.
+type TraceConfig struct {
+	OnExec       ExecHandler `json:"-"`
+	Poll         bool
+	PollInterval time.Duration
+}
+
+// defaultPollInterval is how often /proc is polled when PollInterval isn't set
+const defaultPollInterval = 50 * time.Millisecond
+
+// interval is PollInterval, or the default
+func (c *TraceConfig) interval() time.Duration {
+	if c.PollInterval > 0 {
+		return c.PollInterval
+	}
+	return defaultPollInterval
+}
+
+// traceOptions follow every process the command forks and stop at each exec.
+// Tracees die with the init.
+const traceOptions = unix.PTRACE_O_TRACEEXEC | unix.PTRACE_O_TRACEFORK | unix.PTRACE_O_TRACEVFORK |
+	unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_EXITKILL
+
+// ptracer follows the command and its descendants from the init, which must
+// stay on one OS thread: only the thread that attached may use ptrace.
+type ptracer struct {
+	root   int
+	report func(ExecEvent) bool
+	seen   map[int]bool // Tracees past their initial stop
+	held   []int        // Tracees left in group-stop while the job is suspended
+	killed bool
+}
+
+func newPtracer(root int, report func(ExecEvent) bool) *ptracer {
+	return &ptracer{root: root, report: report, seen: map[int]bool{}}
+}
+
+// stopped handles a ptrace-stop and restarts the tracee, unless the job is
+// suspended and the tracee is stopping with it.
+func (t *ptracer) stopped(pid int, status unix.WaitStatus, suspended bool) {
+	sig := status.StopSignal()
+	switch {
+	case !t.seen[pid]:
+		// The SIGTRAP after the command's own exec, or the SIGSTOP every
+		// new tracee starts with; neither is a real signal
+		t.seen[pid] = true
+		if pid == t.root {
+			unix.PtraceSetOptions(pid, traceOptions)
+		}
+		unix.PtraceCont(pid, 0)
+	case sig == unix.SIGTRAP && status.TrapCause() == unix.PTRACE_EVENT_EXEC:
+		if t.killed || !t.report(readExecEvent(pid)) {
+			t.kill()
+			return
+		}
+		unix.PtraceCont(pid, 0)
+	case sig == unix.SIGTRAP && status.TrapCause() > 0:
+		// Fork, vfork and clone; the child reports in on its own
+		unix.PtraceCont(pid, 0)
+	case isStopSignal(sig) && inGroupStop(pid):
+		// Restarting a tracee in group-stop runs it, so it stays put
+		// until the job is continued (see release)
+		if suspended {
+			t.held = append(t.held, pid)
+			return
+		}
+		unix.PtraceCont(pid, 0)
+	default:
+		unix.PtraceCont(pid, int(sig))
+	}
+}
+
+// exited forgets a tracee, so a reused PID starts over
+func (t *ptracer) exited(pid int) {
+	delete(t.seen, pid)
+}
+
+// release restarts the tracees held in group-stop
+func (t *ptracer) release() {
+	for _, pid := range t.held {
+		unix.PtraceCont(pid, 0)
+	}
+	t.held = nil
+}
+
+// kill ends the whole sandbox, leaving the init to reap it
+func (t *ptracer) kill() {
+	t.killed = true
+	unix.Kill(-1, unix.SIGKILL)
+}
+
+func isStopSignal(sig unix.Signal) bool {
+	return sig == unix.SIGSTOP || sig == unix.SIGTSTP || sig == unix.SIGTTIN || sig == unix.SIGTTOU
+}
+
+// inGroupStop tells a group-stop from a stop signal being delivered: only
+// the latter has signal information.
+func inGroupStop(pid int) bool {
+	var info unix.Siginfo
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_GETSIGINFO, uintptr(pid), 0, uintptr(unsafe.Pointer(&info)), 0, 0)
+	return errno == unix.EINVAL
+}
+
+// readExecEvent describes the program pid is running from /proc
+func readExecEvent(pid int) ExecEvent {
+	dir := "/proc/" + strconv.Itoa(pid)
+	ev := ExecEvent{PID: pid, Time: time.Now()}
+	ev.Argv = readCmdline(dir)
+	ev.Cwd, _ = os.Readlink(filepath.Join(dir, "cwd"))
+	if stat, err := readStat(dir); err == nil {
+		ev.PPID = stat.ppid
+	}
+	return ev
+}
+
+// readCmdline splits /proc/PID/cmdline into arguments
+func readCmdline(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+// procStat is what the tracers need from /proc/PID/stat
+type procStat struct {
+	state     string
+	ppid      int
+	startTime string // Clock ticks after boot; with the PID, identifies the process
+}
+
+func readStat(dir string) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	// The command name in parentheses may contain spaces; the state follows it
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("short stat for %s", dir)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, err
+	}
+	return procStat{state: fields[0], ppid: ppid, startTime: fields[19]}, nil
+}
+
+// pollExecs reports the programs root and its descendants run by polling
+// /proc every interval until stop is closed. A program shows up when a
+// process appears or its command line changes, so execs that keep the same
+// arguments, or exit between polls, are missed. root's own program, which
+// the caller already knows, isn't reported while it is skip or the init. If
+// report returns false every process found is killed.
+func pollExecs(stop <-chan struct{}, root int, skip []string, interval time.Duration, report func(ExecEvent) bool) {
+	type seen struct {
+		startTime string
+		argv      string
+	}
+	known := map[int]seen{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		procs := descendants(root)
+		for _, pid := range procs {
+			dir := "/proc/" + strconv.Itoa(pid)
+			stat, err := readStat(dir)
+			if err != nil || stat.state == "Z" {
+				continue
+			}
+			argv := readCmdline(dir)
+			if len(argv) == 0 {
+				continue
+			}
+			key := strings.Join(argv, "\x00")
+			if prev, ok := known[pid]; ok && prev.startTime == stat.startTime && prev.argv == key {
+				continue
+			}
+			known[pid] = seen{stat.startTime, key}
+			if pid == root && (key == strings.Join(skip, "\x00") || argv[0] == initArgv0) {
+				continue
+			}
+			ev := readExecEvent(pid)
+			ev.Argv = argv
+			if !report(ev) {
+				for _, pid := range procs {
+					unix.Kill(pid, unix.SIGKILL)
+				}
+				return
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// descendants lists root and every live process below it
+func descendants(root int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if stat, err := readStat("/proc/" + entry.Name()); err == nil {
+			children[stat.ppid] = append(children[stat.ppid], pid)
+		}
+	}
+	procs := []int{root}
+	for i := 0; i < len(procs); i++ {
+		procs = append(procs, children[procs[i]]...)
+	}
+	return procs
+}
+
+// execReporter is the init's end of the trace channel: events go out on
+// fd 4 and a verdict byte comes back on fd 5 before the program may run.
+type execReporter struct {
+	events   *os.File
+	verdicts *os.File
+	encoder  *json.Encoder
+}
+
+// openExecReporter takes over fds 4 and 5, keeping them from the command
+func openExecReporter() *execReporter {
+	unix.CloseOnExec(4)
+	unix.CloseOnExec(5)
+	events := os.NewFile(4, "trace-events")
+	return &execReporter{events: events, verdicts: os.NewFile(5, "trace-verdicts"), encoder: json.NewEncoder(events)}
+}
+
+// report sends ev and waits for the verdict. A broken channel means
+// vectra-guard is gone, and the command goes with it.
+func (r *execReporter) report(ev ExecEvent) bool {
+	if err := r.encoder.Encode(ev); err != nil {
+		return false
+	}
+	var verdict [1]byte
+	if _, err := r.verdicts.Read(verdict[:]); err != nil {
+		return false
+	}
+	return verdict[0] == 'y'
+}
+
+// execRelay is vectra-guard's end of the trace channel
+type execRelay struct {
+	events   *os.File // Read end
+	verdicts *os.File // Write end
+	done     chan struct{}
+}
+
+// newExecRelay creates the trace channel and returns the init's ends, to be
+// passed as fds 4 and 5
+func newExecRelay() (*execRelay, []*os.File, error) {
+	eventsReader, eventsWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("create trace pipe: %w", err)
+	}
+	verdictsReader, verdictsWriter, err := os.Pipe()
+	if err != nil {
+		eventsReader.Close()
+		eventsWriter.Close()
+		return nil, nil, fmt.Errorf("create trace pipe: %w", err)
+	}
+	relay := &execRelay{events: eventsReader, verdicts: verdictsWriter, done: make(chan struct{})}
+	return relay, []*os.File{eventsWriter, verdictsReader}, nil
+}
+
+// run passes events to onExec and its verdicts back until the init is gone
+// and close is called
+func (r *execRelay) run(onExec ExecHandler) {
+	defer close(r.done)
+	decoder := json.NewDecoder(r.events)
+	for {
+		var ev ExecEvent
+		if err := decoder.Decode(&ev); err != nil {
+			return
+		}
+		verdict := []byte("y")
+		if !onExec(ev) {
+			verdict = []byte("n")
+		}
+		r.verdicts.Write(verdict)
+	}
+}
+
+// close stops the relay and waits for it, so onExec isn't called after the
+// command has been waited for
+func (r *execRelay) close() {
+	r.events.Close()
+	r.verdicts.Close()
+	<-r.done
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package namespace
+
+import "time"
+
+// ExecEvent is a program started inside a traced command
+type ExecEvent struct {
+	PID  int       `json:"pid"`
+	PPID int       `json:"ppid"`
+	Argv []string  `json:"argv"`
+	Cwd  string    `json:"cwd,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// ExecHandler is told about each program a traced command runs
+type ExecHandler func(ExecEvent) bool
+
+// TraceConfig asks a sandbox runtime to report the programs the command runs
+// (only on Linux)
+type TraceConfig struct {
+	OnExec       ExecHandler `json:"-"`
+	Poll         bool
+	PollInterval time.Duration
+}
@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package namespace
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceReportsExecs(t *testing.T) {
+	if err := exec.Command("unshare", "-Urpf", "--mount-proc", "true").Run(); err != nil {
+		t.Skipf("user namespaces not available: %v", err)
+	}
+	for _, poll := range []bool{false, true} {
+		name := "ptrace"
+		if poll {
+			name = "poll"
+		}
+		t.Run(name, func(t *testing.T) {
+			var execs []ExecEvent
+			trace := &TraceConfig{Poll: poll, PollInterval: 10 * time.Millisecond, OnExec: func(ev ExecEvent) bool {
+				execs = append(execs, ev)
+				return strings.Join(ev.Argv, " ") != "/bin/sleep 10"
+			}}
+			workspace := t.TempDir()
+			executor := NewMountNamespaceExecutor(MountConfig{
+				Workspace:      workspace,
+				CacheDir:       t.TempDir(),
+				SeccompProfile: SeccompProfileNone,
+				CapabilitySet:  CapSetMinimal,
+				Trace:          trace,
+			})
+
+			start := time.Now()
+			err := executor.Execute(context.Background(), []string{"sh", "-c", "cd " + workspace + " && /bin/true && /bin/sleep 1 && /bin/sleep 10; exit 0"})
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 137 {
+				t.Fatalf("Execute() error = %v, want the sandbox killed", err)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Errorf("the second sleep wasn't killed")
+			}
+			// The shell itself was already known; polling misses true
+			var got []string
+			for _, ev := range execs {
+				got = append(got, strings.Join(ev.Argv, " "))
+			}
+			want := "/bin/true,/bin/sleep 1,/bin/sleep 10"
+			if poll {
+				want = "/bin/sleep 1,/bin/sleep 10"
+			}
+			if strings.Join(got, ",") != want {
+				t.Fatalf("execs = %q, want %s", got, want)
+			}
+			if ev := execs[len(execs)-1]; ev.Cwd != workspace || ev.PPID == 0 {
+				t.Errorf("exec = %+v", ev)
+			}
+		})
+	}
+}
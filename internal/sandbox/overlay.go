@@ -0,0 +1,393 @@
+package sandbox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+)
+
+// ChangeKind describes how a path differs from the real workspace
+type ChangeKind string
+
+const (
+	ChangeCreated  ChangeKind = "created"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+)
+
+// maxDiffBytes is the largest file Diff will compare
+const maxDiffBytes = 256 << 10
+
+// Change is one path the sandboxed command created, modified or deleted
+type Change struct {
+	Path  string // Relative to the workspace
+	Kind  ChangeKind
+	IsDir bool
+	Size  int64 // Size in the sandbox, for created and modified files
+}
+
+// OverlayWorkspace is a copy-on-write view of a workspace. The sandbox sees
+// the workspace at its usual path but writes land in Upper: an overlayfs
+// upper directory when Kernel is set, otherwise a full staging copy. Nothing
+// reaches the real workspace until Promote.
+type OverlayWorkspace struct {
+	Workspace string
+	Upper     string
+	Work      string // overlayfs work directory (kernel overlays only)
+	Kernel    bool
+	dir       string
+}
+
+// NewOverlayWorkspace prepares a copy-on-write view of workspace. Without
+// kernel overlays the workspace is copied up front.
+func NewOverlayWorkspace(workspace string, kernel bool) (*OverlayWorkspace, error) {
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "vectra-guard-cow-")
+	if err != nil {
+		return nil, fmt.Errorf("create overlay directory: %w", err)
+	}
+	o := &OverlayWorkspace{
+		Workspace: workspace,
+		Upper:     filepath.Join(dir, "upper"),
+		Kernel:    kernel,
+		dir:       dir,
+	}
+	if kernel {
+		o.Work = filepath.Join(dir, "work")
+		err = os.Mkdir(o.Upper, 0o755)
+		if err == nil {
+			err = os.Mkdir(o.Work, 0o755)
+		}
+	} else {
+		err = copyTree(workspace, o.Upper)
+	}
+	if err != nil {
+		o.Cleanup()
+		return nil, fmt.Errorf("prepare copy-on-write workspace: %w", err)
+	}
+	return o, nil
+}
+
+// mountSources returns what a runtime mounts at the workspace: the staging
+// copy, or the overlay upper and work directories.
+func (o *OverlayWorkspace) mountSources() (source, upper, work string) {
+	if o.Kernel {
+		return "", o.Upper, o.Work
+	}
+	return o.Upper, "", ""
+}
+
+// Changes lists what the sandboxed command did to the workspace, sorted by
+// path. Contents of created directories are listed; deleted directories are
+// listed once.
+func (o *OverlayWorkspace) Changes() ([]Change, error) {
+	var changes []Change
+	var err error
+	if o.Kernel {
+		changes, err = o.upperChanges()
+	} else {
+		changes, err = o.copyChanges()
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, err
+}
+
+// upperChanges reads an overlayfs upper directory: whiteouts are deletions,
+// opaque directories hide everything beneath them in the workspace, and any
+// other entry is new or (possibly) modified.
+func (o *OverlayWorkspace) upperChanges() ([]Change, error) {
+	var changes []Change
+	err := filepath.WalkDir(o.Upper, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == o.Upper {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(o.Upper, p)
+		lower := filepath.Join(o.Workspace, rel)
+		lowerInfo, lowerErr := os.Lstat(lower)
+
+		switch {
+		case isWhiteout(info):
+			if lowerErr == nil {
+				changes = append(changes, Change{Path: rel, Kind: ChangeDeleted, IsDir: lowerInfo.IsDir()})
+			}
+		case lowerErr != nil:
+			changes = append(changes, newChange(rel, ChangeCreated, info))
+		case info.IsDir() && lowerInfo.IsDir():
+			if isOpaqueDir(p) {
+				entries, _ := os.ReadDir(lower)
+				for _, entry := range entries {
+					if _, err := os.Lstat(filepath.Join(p, entry.Name())); os.IsNotExist(err) {
+						changes = append(changes, Change{Path: filepath.Join(rel, entry.Name()), Kind: ChangeDeleted, IsDir: entry.IsDir()})
+					}
+				}
+			}
+		case !sameEntry(lower, lowerInfo, p, info):
+			// Copy-up happens on open for writing, so compare contents
+			changes = append(changes, newChange(rel, ChangeModified, info))
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// copyChanges compares the staging copy against the workspace.
+func (o *OverlayWorkspace) copyChanges() ([]Change, error) {
+	var changes []Change
+	seen := map[string]bool{}
+	err := filepath.WalkDir(o.Upper, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == o.Upper {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(o.Upper, p)
+		seen[rel] = true
+		orig := filepath.Join(o.Workspace, rel)
+		origInfo, origErr := os.Lstat(orig)
+
+		switch {
+		case origErr != nil:
+			changes = append(changes, newChange(rel, ChangeCreated, info))
+		case info.IsDir() && origInfo.IsDir():
+		case !sameEntry(orig, origInfo, p, info):
+			changes = append(changes, newChange(rel, ChangeModified, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(o.Workspace, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == o.Workspace || !copyable(d.Type()) {
+			return nil
+		}
+		rel, _ := filepath.Rel(o.Workspace, p)
+		if !seen[rel] {
+			changes = append(changes, Change{Path: rel, Kind: ChangeDeleted, IsDir: d.IsDir()})
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+func newChange(rel string, kind ChangeKind, info fs.FileInfo) Change {
+	change := Change{Path: rel, Kind: kind, IsDir: info.IsDir()}
+	if info.Mode().IsRegular() {
+		change.Size = info.Size()
+	}
+	return change
+}
+
+// Promote applies the given changes to the real workspace.
+func (o *OverlayWorkspace) Promote(changes []Change) error {
+	sorted := append([]Change{}, changes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	for _, change := range sorted {
+		dst := filepath.Join(o.Workspace, change.Path)
+		var err error
+		if change.Kind == ChangeDeleted {
+			err = os.RemoveAll(dst)
+		} else {
+			err = promoteEntry(filepath.Join(o.Upper, change.Path), dst)
+		}
+		if err != nil {
+			return fmt.Errorf("promote %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+func promoteEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if current, err := os.Lstat(dst); err == nil && !(current.IsDir() && info.IsDir()) {
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return copyEntry(src, dst, info)
+}
+
+// Diff returns a unified diff of a created or modified text file, or "" for
+// directories, deletions, binary files and files over maxDiffBytes.
+func (o *OverlayWorkspace) Diff(change Change) string {
+	if change.IsDir || change.Kind == ChangeDeleted {
+		return ""
+	}
+	after, ok := readText(filepath.Join(o.Upper, change.Path))
+	if !ok {
+		return ""
+	}
+	before := ""
+	from := "/dev/null"
+	if change.Kind == ChangeModified {
+		if before, ok = readText(filepath.Join(o.Workspace, change.Path)); !ok {
+			return ""
+		}
+		from = "a/" + filepath.ToSlash(change.Path)
+	}
+	return unifiedDiff(from, "b/"+filepath.ToSlash(change.Path), before, after)
+}
+
+// Cleanup removes the overlay and everything the command wrote to it.
+func (o *OverlayWorkspace) Cleanup() error {
+	// overlayfs leaves its work directory unreadable
+	filepath.WalkDir(o.dir, func(p string, d fs.DirEntry, err error) error {
+		if d != nil && d.IsDir() {
+			os.Chmod(p, 0o700)
+		}
+		return nil
+	})
+	return os.RemoveAll(o.dir)
+}
+
+func readText(p string) (string, bool) {
+	info, err := os.Lstat(p)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxDiffBytes {
+		return "", false
+	}
+	data, err := os.ReadFile(p)
+	if err != nil || bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// sameEntry reports whether two paths have the same type, permissions and content.
+func sameEntry(a string, ai fs.FileInfo, b string, bi fs.FileInfo) bool {
+	if ai.Mode().Type() != bi.Mode().Type() || ai.Mode().Perm() != bi.Mode().Perm() {
+		return false
+	}
+	switch {
+	case ai.IsDir():
+		return true
+	case ai.Mode()&fs.ModeSymlink != 0:
+		at, _ := os.Readlink(a)
+		bt, _ := os.Readlink(b)
+		return at == bt
+	case ai.Size() != bi.Size():
+		return false
+	}
+	return sameContent(a, b)
+}
+
+func sameContent(a, b string) bool {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false
+	}
+	defer fb.Close()
+	bufA := make([]byte, 32<<10)
+	bufB := make([]byte, 32<<10)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, _ := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false
+		}
+		if errA != nil {
+			return true // Sizes already match
+		}
+	}
+}
+
+// copyable reports whether copyTree copies entries of this type.
+func copyable(mode fs.FileMode) bool {
+	return mode.IsRegular() || mode.IsDir() || mode&fs.ModeSymlink != 0
+}
+
+// copyTree copies directories, regular files and symlinks from src to dst,
+// keeping permissions and modification times.
+func copyTree(src, dst string) error {
+	var dirs []string
+	var modes []fs.FileMode
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !copyable(d.Type()) {
+			return nil // sockets, fifos, devices
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			// Keep directories writable until their contents are copied
+			dirs = append(dirs, target)
+			modes = append(modes, info.Mode().Perm())
+			return os.MkdirAll(target, 0o700)
+		}
+		return copyEntry(p, target, info)
+	})
+	for i := len(dirs) - 1; i >= 0 && err == nil; i-- {
+		err = os.Chmod(dirs[i], modes[i])
+	}
+	return err
+}
+
+func copyEntry(src, dst string, info fs.FileInfo) error {
+	switch {
+	case info.IsDir():
+		if err := os.MkdirAll(dst, 0o700); err != nil {
+			return err
+		}
+		return os.Chmod(dst, info.Mode().Perm())
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
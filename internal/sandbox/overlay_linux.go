@@ -0,0 +1,33 @@
+//go:build linux
+// +build linux
+
+package sandbox
+
+import (
+	"io/fs"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// isWhiteout reports whether an overlayfs upper entry marks a deleted path
+// (a 0:0 character device).
+func isWhiteout(info fs.FileInfo) bool {
+	if info.Mode()&fs.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
+
+// isOpaqueDir reports whether an overlayfs upper directory hides the lower
+// one's contents (it was deleted and recreated).
+func isOpaqueDir(path string) bool {
+	buf := make([]byte, 1)
+	for _, attr := range []string{"trusted.overlay.opaque", "user.overlay.opaque"} {
+		if n, err := unix.Getxattr(path, attr, buf); err == nil && n == 1 && buf[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package sandbox
+
+import "io/fs"
+
+// isWhiteout always reports false: kernel overlays are Linux-only
+func isWhiteout(info fs.FileInfo) bool {
+	return false
+}
+
+// isOpaqueDir always reports false: kernel overlays are Linux-only
+func isOpaqueDir(path string) bool {
+	return false
+}
@@ -0,0 +1,156 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func changeSummary(changes []Change) string {
+	var parts []string
+	for _, c := range changes {
+		parts = append(parts, string(c.Kind)+":"+c.Path)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestOverlayWorkspaceCopy(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, ws, "keep.txt", "unchanged\n")
+	writeTestFile(t, ws, "edit.txt", "one\ntwo\nthree\n")
+	writeTestFile(t, ws, "old/gone.txt", "bye\n")
+
+	overlay, err := NewOverlayWorkspace(ws, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer overlay.Cleanup()
+
+	// What a sandboxed command would do to the staging copy
+	writeTestFile(t, overlay.Upper, "edit.txt", "one\n2\nthree\n")
+	writeTestFile(t, overlay.Upper, "src/new.go", "package src\n")
+	os.RemoveAll(filepath.Join(overlay.Upper, "old"))
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "modified:edit.txt deleted:old created:src created:src/new.go"
+	if got := changeSummary(changes); got != want {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+
+	diff := overlay.Diff(changes[0])
+	for _, line := range []string{"--- a/edit.txt", "+++ b/edit.txt", "-two", "+2", " one"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("diff missing %q:\n%s", line, diff)
+		}
+	}
+
+	// The real workspace is untouched until promotion
+	if data, _ := os.ReadFile(filepath.Join(ws, "edit.txt")); string(data) != "one\ntwo\nthree\n" {
+		t.Fatalf("workspace modified before promote: %q", data)
+	}
+
+	// Promote only the new file and the deletion
+	if err := overlay.Promote([]Change{changes[1], changes[3]}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "src/new.go")); string(data) != "package src\n" {
+		t.Errorf("src/new.go = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "old")); !os.IsNotExist(err) {
+		t.Error("expected old/ to be deleted")
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "edit.txt")); string(data) != "one\ntwo\nthree\n" {
+		t.Errorf("unselected change was promoted: %q", data)
+	}
+
+	dir := overlay.dir
+	overlay.Cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("expected cleanup to remove the overlay")
+	}
+}
+
+func TestOverlayWorkspaceKernelUpper(t *testing.T) {
+	ws := t.TempDir()
+	writeTestFile(t, ws, "copied-up.txt", "same\n")
+	writeTestFile(t, ws, "edit.txt", "before\n")
+	writeTestFile(t, ws, "deleted.txt", "bye\n")
+
+	overlay, err := NewOverlayWorkspace(ws, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer overlay.Cleanup()
+
+	// Lay out an upper directory the way overlayfs would
+	writeTestFile(t, overlay.Upper, "copied-up.txt", "same\n")
+	writeTestFile(t, overlay.Upper, "edit.txt", "after\n")
+	writeTestFile(t, overlay.Upper, "new.txt", "hello\n")
+	want := "modified:edit.txt created:new.txt"
+	if err := syscall.Mknod(filepath.Join(overlay.Upper, "deleted.txt"), syscall.S_IFCHR, 0); err == nil {
+		want = "deleted:deleted.txt " + want
+	}
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeSummary(changes); got != want {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+
+	if err := overlay.Promote(changes); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "edit.txt")); string(data) != "after\n" {
+		t.Errorf("edit.txt = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws, "new.txt")); string(data) != "hello\n" {
+		t.Errorf("new.txt = %q", data)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- a/f
++++ b/f
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := unifiedDiff("a/f", "b/f", before, after); got != want {
+		t.Errorf("unifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("a/f", "b/f", before, before); got != "" {
+		t.Errorf("expected no diff for equal input, got %q", got)
+	}
+	if got := unifiedDiff("/dev/null", "b/f", "", "x\n"); !strings.Contains(got, "@@ -0,0 +1,1 @@\n+x\n") {
+		t.Errorf("unexpected diff for a new file:\n%s", got)
+	}
+}
@@ -0,0 +1,73 @@
+package sandbox
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+// protectedSkipDirs are not searched for protected paths (they can still be
+// protected themselves).
+var protectedSkipDirs = map[string]bool{".git": true, "node_modules": true, ".venv": true, "vendor": true}
+
+// expandProtectedPaths resolves protected_paths globs to existing host paths:
+// no-write and no-delete entries become read-only mounts, no-read entries are
+// masked. A directory that matches is returned instead of its contents.
+func expandProtectedPaths(paths []config.ProtectedPath, workspace, home string) (readOnly, masked []string) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	add := func(p string, mode config.ProtectionMode) {
+		if mode == config.ProtectNoRead {
+			masked = append(masked, p)
+		} else {
+			readOnly = append(readOnly, p)
+		}
+	}
+
+	// Absolute and home patterns are globbed directly
+	var relative []config.ProtectedPath
+	for _, pp := range paths {
+		pattern := pp.Path
+		if strings.HasPrefix(pattern, "~/") && home != "" {
+			pattern = filepath.Join(home, pattern[2:])
+		}
+		if !filepath.IsAbs(pattern) {
+			relative = append(relative, pp)
+			continue
+		}
+		if i := strings.Index(pattern, "**"); i >= 0 {
+			pattern = filepath.Dir(pattern[:i+1])
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			add(m, pp.Mode)
+		}
+	}
+
+	if len(relative) > 0 && workspace != "" {
+		filepath.WalkDir(workspace, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == workspace {
+				return nil
+			}
+			if pp, ok := config.ProtectionFor(relative, p, workspace, home); ok {
+				add(p, pp.Mode)
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() && protectedSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+
+	sort.Strings(readOnly)
+	sort.Strings(masked)
+	return readOnly, masked
+}
@@ -0,0 +1,42 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+func TestExpandProtectedPaths(t *testing.T) {
+	ws := t.TempDir()
+	for _, dir := range []string{"migrations", "infra/prod", "node_modules/pkg", "src"} {
+		if err := os.MkdirAll(filepath.Join(ws, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"migrations/001.sql", "src/vectra-guard.yaml", "vectra-guard.yaml", "node_modules/pkg/vectra-guard.yaml"} {
+		if err := os.WriteFile(filepath.Join(ws, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readOnly, masked := expandProtectedPaths([]config.ProtectedPath{
+		{Path: "migrations/**", Mode: config.ProtectNoDelete},
+		{Path: "infra/prod", Mode: config.ProtectNoRead},
+		{Path: "vectra-guard.yaml", Mode: config.ProtectNoWrite},
+	}, ws, "")
+
+	wantRO := []string{
+		filepath.Join(ws, "migrations"),
+		filepath.Join(ws, "src/vectra-guard.yaml"),
+		filepath.Join(ws, "vectra-guard.yaml"),
+	}
+	if !reflect.DeepEqual(readOnly, wantRO) {
+		t.Errorf("read-only = %v, want %v", readOnly, wantRO)
+	}
+	if want := []string{filepath.Join(ws, "infra/prod")}; !reflect.DeepEqual(masked, want) {
+		t.Errorf("masked = %v, want %v", masked, want)
+	}
+}
@@ -14,15 +14,18 @@ import (
 
 // RuntimeExecutor is the interface for different sandbox runtimes
 type RuntimeExecutor interface {
-	Execute(cmdArgs []string) error
+	Execute(ctx context.Context, cmdArgs []string) error
 	Name() string
 	IsAvailable() bool
 }
 
 // RuntimeSelector selects the best available runtime based on configuration
 type RuntimeSelector struct {
-	config config.Config
-	logger *logging.Logger
+	config  config.Config
+	logger  *logging.Logger
+	overlay *OverlayWorkspace // Copy-on-write workspace, if any
+	cgroupPath string         // Resource-limited cgroup, if any
+	trace   *namespace.TraceConfig // Tracing of the programs the command runs, if enabled
 }
 
 // NewRuntimeSelector creates a new runtime selector
@@ -35,6 +38,12 @@ func NewRuntimeSelector(cfg config.Config, logger *logging.Logger) *RuntimeSelec
 
 // SelectRuntime selects the best available runtime
 func (rs *RuntimeSelector) SelectRuntime(ctx context.Context) (RuntimeExecutor, error) {
+	selectedRuntime, caps := rs.selectRuntimeType()
+	return rs.createExecutor(selectedRuntime, caps)
+}
+
+// selectRuntimeType picks the runtime from configuration, environment and capabilities
+func (rs *RuntimeSelector) selectRuntimeType() (namespace.RuntimeType, namespace.Capabilities) {
 	sandboxCfg := rs.config.Sandbox
 	runtimeName := sandboxCfg.Runtime
 
@@ -58,6 +67,7 @@ func (rs *RuntimeSelector) SelectRuntime(ctx context.Context) (RuntimeExecutor,
 		"user_namespaces":   caps.UserNamespaces,
 		"mount_namespaces":  caps.MountNamespaces,
 		"network_namespaces": caps.NetworkNamespaces,
+		"landlock_abi":      caps.Landlock,
 	})
 
 	// Select runtime based on configuration
@@ -87,20 +97,27 @@ func (rs *RuntimeSelector) SelectRuntime(ctx context.Context) (RuntimeExecutor,
 		rs.logger.Info(namespace.GetRuntimeInfo(selectedRuntime, env), nil)
 	}
 
-	// Create runtime executor
-	return rs.createExecutor(selectedRuntime, caps)
+	return selectedRuntime, caps
+}
+
+// sandboxWorkspace is sandbox.workspace_dir, or the current directory
+func sandboxWorkspace(sandboxCfg config.SandboxConfig) (string, error) {
+	if sandboxCfg.WorkspaceDir != "" {
+		return sandboxCfg.WorkspaceDir, nil
+	}
+	workspaceDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get working directory: %w", err)
+	}
+	return workspaceDir, nil
 }
 
 // createExecutor creates the appropriate executor for the runtime
 func (rs *RuntimeSelector) createExecutor(runtime namespace.RuntimeType, caps namespace.Capabilities) (RuntimeExecutor, error) {
 	sandboxCfg := rs.config.Sandbox
-	workspaceDir := sandboxCfg.WorkspaceDir
-	if workspaceDir == "" {
-		var err error
-		workspaceDir, err = os.Getwd()
-		if err != nil {
-			return nil, fmt.Errorf("get working directory: %w", err)
-		}
+	workspaceDir, err := sandboxWorkspace(sandboxCfg)
+	if err != nil {
+		return nil, err
 	}
 
 	cacheDir := sandboxCfg.CacheDir
@@ -126,6 +143,11 @@ func (rs *RuntimeSelector) createExecutor(runtime namespace.RuntimeType, caps na
 		})
 	}
 
+	// protected_paths are mounted read-only or masked on top of the workspace
+	homeDir, _ := os.UserHomeDir()
+	protectedRO, masked := expandProtectedPaths(rs.config.ProtectedPaths, workspaceDir, homeDir)
+	readOnlyPaths := append(append([]string{}, sandboxCfg.ReadOnlyPaths...), protectedRO...)
+
 	switch runtime {
 	case namespace.RuntimeBubblewrap:
 		if !caps.Bubblewrap {
@@ -136,9 +158,17 @@ func (rs *RuntimeSelector) createExecutor(runtime namespace.RuntimeType, caps na
 			Workspace:     workspaceDir,
 			CacheDir:      cacheDir,
 			AllowNetwork:  sandboxCfg.AllowNetwork || sandboxCfg.NetworkMode == "full",
-			ReadOnlyPaths: sandboxCfg.ReadOnlyPaths,
+			ReadOnlyPaths: readOnlyPaths,
+			MaskedPaths:   masked,
 			BindMounts:    bindMounts,
 			Environment:   make(map[string]string),
+			CgroupPath:    rs.cgroupPath,
+			KillGrace:    killGrace(rs.config),
+			Trace:         rs.trace,
+		}
+		if rs.overlay != nil {
+			bwrapConfig.Workspace = rs.overlay.Workspace
+			bwrapConfig.WorkspaceSource, bwrapConfig.OverlayUpper, bwrapConfig.OverlayWork = rs.overlay.mountSources()
 		}
 
 		executor := namespace.NewBubblewrapExecutor(bwrapConfig)
@@ -180,11 +210,19 @@ func (rs *RuntimeSelector) createExecutor(runtime namespace.RuntimeType, caps na
 			Workspace:      workspaceDir,
 			CacheDir:       cacheDir,
 			AllowNetwork:   sandboxCfg.AllowNetwork || sandboxCfg.NetworkMode == "full",
-			ReadOnlyPaths:  sandboxCfg.ReadOnlyPaths,
+			ReadOnlyPaths:  readOnlyPaths,
+			MaskedPaths:    masked,
 			BindMounts:     bindMounts,
 			UseOverlayFS:   sandboxCfg.UseOverlayFS && caps.OverlayFS,
 			SeccompProfile: seccompProfile,
 			CapabilitySet:  capabilitySet,
+			CgroupPath:     rs.cgroupPath,
+			KillGrace:     killGrace(rs.config),
+			Trace:          rs.trace,
+		}
+		if rs.overlay != nil {
+			mountConfig.Workspace = rs.overlay.Workspace
+			mountConfig.WorkspaceSource, mountConfig.OverlayUpper, mountConfig.OverlayWork = rs.overlay.mountSources()
 		}
 
 		executor := namespace.NewMountNamespaceExecutor(mountConfig)
@@ -193,6 +231,34 @@ func (rs *RuntimeSelector) createExecutor(runtime namespace.RuntimeType, caps na
 			logger:   rs.logger,
 		}, nil
 
+	case namespace.RuntimeLandlock:
+		if caps.Landlock == 0 {
+			return nil, fmt.Errorf("landlock not available")
+		}
+
+		landlockConfig := namespace.LandlockConfig{
+			Workspace:     workspaceDir,
+			CacheDir:      cacheDir,
+			AllowNetwork:  sandboxCfg.AllowNetwork || sandboxCfg.NetworkMode == "full",
+			ReadOnlyPaths: readOnlyPaths,
+			MaskedPaths:   masked,
+			BindMounts:    bindMounts,
+			CgroupPath:    rs.cgroupPath,
+			KillGrace:    killGrace(rs.config),
+			Trace:         rs.trace,
+		}
+		if rs.overlay != nil {
+			// Landlock can't mount, so the command runs in the staging copy
+			landlockConfig.Workspace = rs.overlay.Workspace
+			landlockConfig.WorkspaceSource, _, _ = rs.overlay.mountSources()
+		}
+
+		executor := namespace.NewLandlockExecutor(landlockConfig)
+		return &landlockRuntimeExecutor{
+			executor: executor,
+			logger:   rs.logger,
+		}, nil
+
 	case namespace.RuntimeDocker:
 		if !caps.Docker {
 			return nil, fmt.Errorf("docker not available")
@@ -215,8 +281,8 @@ type bubblewrapRuntimeExecutor struct {
 	logger   *logging.Logger
 }
 
-func (e *bubblewrapRuntimeExecutor) Execute(cmdArgs []string) error {
-	return e.executor.Execute(cmdArgs)
+func (e *bubblewrapRuntimeExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return e.executor.Execute(ctx, cmdArgs)
 }
 
 func (e *bubblewrapRuntimeExecutor) Name() string {
@@ -233,8 +299,8 @@ type mountNamespaceRuntimeExecutor struct {
 	logger   *logging.Logger
 }
 
-func (e *mountNamespaceRuntimeExecutor) Execute(cmdArgs []string) error {
-	return e.executor.Execute(cmdArgs)
+func (e *mountNamespaceRuntimeExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return e.executor.Execute(ctx, cmdArgs)
 }
 
 func (e *mountNamespaceRuntimeExecutor) Name() string {
@@ -245,13 +311,31 @@ func (e *mountNamespaceRuntimeExecutor) IsAvailable() bool {
 	return namespace.IsMountNamespaceAvailable()
 }
 
+// landlockRuntimeExecutor wraps Landlock executor
+type landlockRuntimeExecutor struct {
+	executor *namespace.LandlockExecutor
+	logger   *logging.Logger
+}
+
+func (e *landlockRuntimeExecutor) Execute(ctx context.Context, cmdArgs []string) error {
+	return e.executor.Execute(ctx, cmdArgs)
+}
+
+func (e *landlockRuntimeExecutor) Name() string {
+	return "landlock"
+}
+
+func (e *landlockRuntimeExecutor) IsAvailable() bool {
+	return namespace.IsLandlockAvailable()
+}
+
 // dockerRuntimeExecutor wraps Docker executor (legacy)
 type dockerRuntimeExecutor struct {
 	config config.SandboxConfig
 	logger *logging.Logger
 }
 
-func (e *dockerRuntimeExecutor) Execute(cmdArgs []string) error {
+func (e *dockerRuntimeExecutor) Execute(ctx context.Context, cmdArgs []string) error {
 	// Use existing Docker execution logic
 	// This is a placeholder - the actual implementation would use the
 	// existing runInContainer function from sandbox.go
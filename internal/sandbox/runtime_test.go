@@ -14,7 +14,7 @@ func TestRuntimeSelection(t *testing.T) {
 	tests := []struct {
 		name           string
 		config         config.Config
-		expectedType   string // "bubblewrap", "namespace", "docker", or "error"
+		expectedType   string // "bubblewrap", "namespace", "landlock", "docker", or "error"
 		shouldHaveError bool
 	}{
 		{
@@ -51,6 +51,17 @@ func TestRuntimeSelection(t *testing.T) {
 			expectedType:   "namespace",
 			shouldHaveError: !namespace.IsMountNamespaceAvailable(), // Error if not available
 		},
+		{
+			name: "explicit landlock",
+			config: config.Config{
+				Sandbox: config.SandboxConfig{
+					Runtime:       "landlock",
+					AutoDetectEnv: false,
+				},
+			},
+			expectedType:   "landlock",
+			shouldHaveError: !namespace.IsLandlockAvailable(), // Error if not available
+		},
 		{
 			name: "explicit docker",
 			config: config.Config{
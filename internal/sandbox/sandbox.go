@@ -13,6 +13,7 @@ import (
 	"github.com/vectra-guard/vectra-guard/internal/analyzer"
 	"github.com/vectra-guard/vectra-guard/internal/config"
 	"github.com/vectra-guard/vectra-guard/internal/logging"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
 )
 
 // ExecutionMode determines where and how a command runs
@@ -31,6 +32,9 @@ type ExecutionDecision struct {
 	ShouldCache   bool
 	CacheKey      string
 	SecurityLevel string
+	Overlay       *OverlayWorkspace // Copy-on-write workspace (see PrepareOverlay)
+	Timeout       time.Duration     // Overrides CommandTimeout; negative means none
+	ApprovedFindings []string       // Finding codes the command was approved with; tracing doesn't kill for them
 }
 
 // SandboxConfig controls sandbox behavior and isolation
@@ -40,6 +44,7 @@ type SandboxConfig struct {
 	Image            string        // Container image to use
 	Timeout          time.Duration // Execution timeout
 	WorkDir          string        // Working directory to mount
+	WorkspaceSource  string        // Copy-on-write staging copy mounted at WorkDir
 	
 	// Cache configuration
 	EnableCache      bool     // Enable dependency caching
@@ -58,6 +63,7 @@ type SandboxConfig struct {
 	MemoryLimit      string // Memory limit (e.g., "512m")
 	CPULimit         string // CPU limit (e.g., "1.0")
 	PidsLimit        int    // Max number of processes
+	IOLimit          string // Disk read and write bytes per second (e.g., "50m")
 	
 	// Environment
 	EnvWhitelist     []string          // Environment variables to pass through
@@ -80,9 +86,13 @@ type BindMount struct {
 
 // Executor handles command execution with sandbox support
 type Executor struct {
-	config config.Config
-	logger *logging.Logger
-	trust  *TrustStore
+	config       config.Config
+	logger       *logging.Logger
+	trust        *TrustStore
+	changeReport *FileChangeReport // From the last sandboxed execution
+	resourceUsage *ResourceUsage   // From the last cgroup-limited execution
+	stragglers    []namespace.Process // Left running by the last execution
+	traced        []TracedExec        // Programs the last sandboxed execution ran
 }
 
 // NewExecutor creates a new sandbox executor
@@ -242,13 +252,16 @@ func (e *Executor) DecideExecutionMode(ctx context.Context, cmdArgs []string, ri
 	return decision
 }
 
-// Execute runs a command in the determined execution mode
+// Execute runs a command in the determined execution mode, in its own
+// process group. A command that outlives its timeout is stopped and a
+// *TimeoutError returned.
 func (e *Executor) Execute(ctx context.Context, cmdArgs []string, decision ExecutionDecision) error {
-	if decision.Mode == ExecutionModeHost {
-		return e.executeOnHost(ctx, cmdArgs)
-	}
-	
-	return e.executeInSandbox(ctx, cmdArgs, decision)
+	return e.runWithTimeout(ctx, cmdArgs, decision, func(ctx context.Context) error {
+		if decision.Mode == ExecutionModeHost {
+			return e.executeOnHost(ctx, cmdArgs)
+		}
+		return e.executeInSandbox(ctx, cmdArgs, decision)
+	})
 }
 
 // executeOnHost runs command directly on the host
@@ -257,13 +270,13 @@ func (e *Executor) executeOnHost(ctx context.Context, cmdArgs []string) error {
 		return fmt.Errorf("no command specified")
 	}
 	
-	cmd := exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 	cmd.Env = os.Environ()
 	
-	return cmd.Run()
+	return namespace.RunProcessGroup(ctx, cmd, killGrace(e.config))
 }
 
 // executeInSandbox runs command in an isolated sandbox
@@ -279,6 +292,25 @@ func (e *Executor) executeInSandbox(ctx context.Context, cmdArgs []string, decis
 		"reason":     decision.Reason,
 	})
 	
+	// Record what the command does to the workspace; copy-on-write runs are reviewed instead
+	e.changeReport = nil
+	e.traced = nil
+	var before WorkspaceState
+	workspace := ""
+	if decision.Overlay == nil {
+		if dir, err := e.mountedWorkspace(); err == nil {
+			workspace = dir
+			before, _ = CaptureWorkspace(workspace, e.config.Sandbox.HashFileChanges)
+		}
+	}
+	
+	if e.config.Tracing.Enabled {
+		switch sandboxCfg.Runtime {
+		case "docker", "podman", "process":
+			e.logger.Warn("tracing not supported by runtime", map[string]any{"runtime": sandboxCfg.Runtime})
+		}
+	}
+	
 	var err error
 	switch sandboxCfg.Runtime {
 	case "docker":
@@ -288,11 +320,23 @@ func (e *Executor) executeInSandbox(ctx context.Context, cmdArgs []string, decis
 	case "process":
 		err = e.executeProcess(ctx, cmdArgs, sandboxCfg)
 	default:
-		return fmt.Errorf("unsupported sandbox runtime: %s", sandboxCfg.Runtime)
+		// auto, bubblewrap, namespace and landlock
+		err = e.executeRuntime(ctx, cmdArgs, decision, sandboxCfg)
 	}
 	
 	duration := time.Since(start)
 	
+	if workspace != "" {
+		after, _ := CaptureWorkspace(workspace, e.config.Sandbox.HashFileChanges)
+		report := DiffWorkspace(before, after)
+		e.changeReport = &report
+		e.logger.Info("sandbox file changes", map[string]any{
+			"workspace": workspace,
+			"changes":   len(report.Changes),
+			"summary":   report.Summary(),
+		})
+	}
+	
 	if err == nil {
 		e.logger.Info("sandbox execution completed", map[string]any{
 			"duration": duration.String(),
@@ -303,6 +347,74 @@ func (e *Executor) executeInSandbox(ctx context.Context, cmdArgs []string, decis
 	return err
 }
 
+// executeRuntime runs the command with the runtime picked by RuntimeSelector,
+// using the legacy Docker path when that is Docker.
+func (e *Executor) executeRuntime(ctx context.Context, cmdArgs []string, decision ExecutionDecision, sandboxCfg SandboxConfig) error {
+	selector := NewRuntimeSelector(e.config, e.logger)
+	selector.overlay = decision.Overlay
+	runtime, caps := selector.selectRuntimeType()
+	if runtime == namespace.RuntimeDocker {
+		if e.config.Tracing.Enabled {
+			e.logger.Warn("tracing not supported by runtime", map[string]any{"runtime": "docker"})
+		}
+		return e.executeDocker(ctx, cmdArgs, sandboxCfg)
+	}
+	selector.trace = e.traceConfig(decision)
+	err := e.runLimited(sandboxCfg, func(cgroupPath string) error {
+		selector.cgroupPath = cgroupPath
+		executor, err := selector.createExecutor(runtime, caps)
+		if err != nil {
+			return err
+		}
+		return executor.Execute(ctx, cmdArgs)
+	})
+	if killed := e.killedExec(); killed != nil {
+		return &KilledExecError{Exec: *killed}
+	}
+	return err
+}
+
+// PrepareOverlay creates a copy-on-write view of the sandbox workspace for
+// sandbox.workspace_mode "overlay". Bubblewrap 0.7+ and the namespace
+// runtime mount a kernel overlay; Docker, Podman, Landlock and older
+// bubblewrap run on a staging copy. The caller
+// reviews the changes and cleans up.
+func (e *Executor) PrepareOverlay() (*OverlayWorkspace, error) {
+	workspace, err := e.mountedWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	kernel := false
+	switch e.config.Sandbox.Runtime {
+	case "docker", "podman", "process":
+	default:
+		runtime, caps := NewRuntimeSelector(e.config, e.logger).selectRuntimeType()
+		switch runtime {
+		case namespace.RuntimeNamespace:
+			kernel = caps.OverlayFS
+		case namespace.RuntimeBubblewrap:
+			kernel = namespace.BubblewrapSupportsOverlay()
+		}
+	}
+	return NewOverlayWorkspace(workspace, kernel)
+}
+
+// mountedWorkspace is the directory the sandbox mounts writable: the working
+// directory for containers and the process runtime, else the runtime workspace.
+func (e *Executor) mountedWorkspace() (string, error) {
+	switch e.config.Sandbox.Runtime {
+	case "docker", "podman", "process":
+		return os.Getwd()
+	}
+	return sandboxWorkspace(e.config.Sandbox)
+}
+
+// ChangeReport returns the file changes made by the last sandboxed command,
+// or nil if it ran on the host or on a copy-on-write workspace.
+func (e *Executor) ChangeReport() *FileChangeReport {
+	return e.changeReport
+}
+
 // buildSandboxConfig creates runtime configuration based on security level
 func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig {
 	cfg := e.config.Sandbox
@@ -321,6 +433,9 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 		EnvWhitelist:    cfg.EnvWhitelist,
 		EnvOverrides:    make(map[string]string),
 	}
+	if decision.Overlay != nil && !decision.Overlay.Kernel {
+		sandboxCfg.WorkspaceSource = decision.Overlay.Upper
+	}
 	
 	// Configure security posture based on level
 	switch cfg.SecurityLevel {
@@ -329,6 +444,7 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 		sandboxCfg.ReadOnlyRoot = false
 		sandboxCfg.MemoryLimit = "2g"
 		sandboxCfg.CPULimit = "2.0"
+		sandboxCfg.PidsLimit = 4096
 		
 	case config.SandboxSecurityBalanced:
 		sandboxCfg.NetworkMode = cfg.NetworkMode
@@ -338,6 +454,7 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 		sandboxCfg.ReadOnlyRoot = false
 		sandboxCfg.MemoryLimit = "1g"
 		sandboxCfg.CPULimit = "1.0"
+		sandboxCfg.PidsLimit = 1024
 		sandboxCfg.CapDrop = []string{"ALL"}
 		sandboxCfg.CapAdd = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID"}
 		
@@ -347,6 +464,7 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 		sandboxCfg.MemoryLimit = "512m"
 		sandboxCfg.CPULimit = "0.5"
 		sandboxCfg.PidsLimit = 100
+		sandboxCfg.IOLimit = "100m"
 		sandboxCfg.CapDrop = []string{"ALL"}
 		sandboxCfg.CapAdd = []string{"CHOWN", "DAC_OVERRIDE"}
 		sandboxCfg.SeccompProfile = cfg.SeccompProfile
@@ -357,10 +475,25 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 		sandboxCfg.MemoryLimit = "256m"
 		sandboxCfg.CPULimit = "0.25"
 		sandboxCfg.PidsLimit = 50
+		sandboxCfg.IOLimit = "50m"
 		sandboxCfg.CapDrop = []string{"ALL"}
 		sandboxCfg.SeccompProfile = cfg.SeccompProfile
 	}
 	
+	// Explicit resource limits win over the security level
+	if cfg.MemoryLimit != "" {
+		sandboxCfg.MemoryLimit = cfg.MemoryLimit
+	}
+	if cfg.CPULimit != "" {
+		sandboxCfg.CPULimit = cfg.CPULimit
+	}
+	if cfg.PidsLimit > 0 {
+		sandboxCfg.PidsLimit = cfg.PidsLimit
+	}
+	if cfg.IOLimit != "" {
+		sandboxCfg.IOLimit = cfg.IOLimit
+	}
+	
 	// Add cache mounts if enabled
 	if decision.ShouldCache {
 		sandboxCfg.CacheMounts = e.getCacheMounts()
@@ -380,26 +513,31 @@ func (e *Executor) buildSandboxConfig(decision ExecutionDecision) SandboxConfig
 
 // executeDocker runs command in Docker container
 func (e *Executor) executeDocker(ctx context.Context, cmdArgs []string, cfg SandboxConfig) error {
-	dockerArgs := e.buildDockerArgs(cfg, cmdArgs)
-	
-	cmd := exec.CommandContext(ctx, "docker", dockerArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	
-	return cmd.Run()
+	return e.executeContainer(ctx, "docker", cmdArgs, cfg)
 }
 
 // executePodman runs command in Podman container
 func (e *Executor) executePodman(ctx context.Context, cmdArgs []string, cfg SandboxConfig) error {
-	podmanArgs := e.buildDockerArgs(cfg, cmdArgs) // Podman is CLI-compatible with Docker
-	
-	cmd := exec.CommandContext(ctx, "podman", podmanArgs...)
+	return e.executeContainer(ctx, "podman", cmdArgs, cfg) // Podman is CLI-compatible with Docker
+}
+
+// executeContainer runs command with the docker or podman CLI. The container
+// is named so it can be killed on timeout: stopping the CLI doesn't stop it.
+func (e *Executor) executeContainer(ctx context.Context, engine string, cmdArgs []string, cfg SandboxConfig) error {
+	name := fmt.Sprintf("vectra-guard-%d-%d", os.Getpid(), time.Now().UnixNano())
+	args := e.buildDockerArgs(cfg, cmdArgs)
+	args = append([]string{args[0], "--name", name}, args[1:]...)
+	
+	cmd := exec.Command(engine, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 	
-	return cmd.Run()
+	err := namespace.RunProcessGroup(ctx, cmd, killGrace(e.config))
+	if ctx.Err() != nil {
+		exec.Command(engine, "kill", name).Run()
+	}
+	return err
 }
 
 // executeProcess runs command as isolated process (Linux namespaces)
@@ -420,15 +558,26 @@ func (e *Executor) executeProcess(ctx context.Context, cmdArgs []string, cfg San
 	}
 	
 	unshareArgs = append(unshareArgs, "--")
+	if cfg.WorkspaceSource != "" {
+		// Mount the staging copy over the working directory inside the namespace
+		unshareArgs = append(unshareArgs, "sh", "-c", `mount --bind "$1" "$2" && cd "$2" && shift 2 && exec "$@"`, "vectra-guard", cfg.WorkspaceSource, cfg.WorkDir)
+	}
 	unshareArgs = append(unshareArgs, cmdArgs...)
 	
-	cmd := exec.CommandContext(ctx, "unshare", unshareArgs...)
+	cmd := exec.Command("unshare", unshareArgs...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 	cmd.Env = e.buildEnv(cfg)
 	
-	return cmd.Run()
+	return e.runLimited(cfg, func(cgroupPath string) error {
+		closeCgroup, err := namespace.StartInCgroup(cmd, cgroupPath)
+		if err != nil {
+			return err
+		}
+		defer closeCgroup()
+		return namespace.RunProcessGroup(ctx, cmd, killGrace(e.config))
+	})
 }
 
 // buildDockerArgs constructs Docker/Podman CLI arguments
@@ -441,7 +590,11 @@ func (e *Executor) buildDockerArgs(cfg SandboxConfig, cmdArgs []string) []string
 	}
 	
 	// Working directory mount
-	args = append(args, "-v", fmt.Sprintf("%s:%s", cfg.WorkDir, cfg.WorkDir))
+	source := cfg.WorkDir
+	if cfg.WorkspaceSource != "" {
+		source = cfg.WorkspaceSource
+	}
+	args = append(args, "-v", fmt.Sprintf("%s:%s", source, cfg.WorkDir))
 	args = append(args, "-w", cfg.WorkDir)
 	
 	// Network mode
@@ -0,0 +1,128 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+)
+
+// defaultKillGrace is how long a timed-out command gets between SIGTERM and
+// SIGKILL when timeouts.kill_grace isn't set
+const defaultKillGrace = 10 * time.Second
+
+// TimeoutError is returned by Execute when the command ran past its timeout
+// and was killed
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command timed out after %s", e.Timeout)
+}
+
+// CommandTimeout is how long cmdArgs may run: the timeout of the longest
+// matching timeouts.commands prefix, else of its risk level, else
+// sandbox.timeout. Zero means no timeout.
+func CommandTimeout(cfg config.Config, cmdArgs []string, riskLevel string) time.Duration {
+	cmdString := strings.Join(cmdArgs, " ")
+	longest := -1
+	seconds := 0
+	for prefix, s := range cfg.Timeouts.Commands {
+		prefix = strings.TrimSpace(prefix)
+		if len(prefix) <= longest || !hasCommandPrefix(cmdString, prefix) {
+			continue
+		}
+		longest, seconds = len(prefix), s
+	}
+	if longest < 0 {
+		switch riskLevel {
+		case "low":
+			seconds = cfg.Timeouts.Low
+		case "medium":
+			seconds = cfg.Timeouts.Medium
+		case "high":
+			seconds = cfg.Timeouts.High
+		case "critical":
+			seconds = cfg.Timeouts.Critical
+		}
+	}
+	if seconds <= 0 {
+		seconds = cfg.Sandbox.Timeout
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// hasCommandPrefix matches whole words, so "npm i" doesn't match "npm install"
+func hasCommandPrefix(cmdString, prefix string) bool {
+	if !strings.HasPrefix(cmdString, prefix) {
+		return false
+	}
+	return len(cmdString) == len(prefix) || cmdString[len(prefix)] == ' '
+}
+
+// killGrace is timeouts.kill_grace as a duration
+func killGrace(cfg config.Config) time.Duration {
+	if cfg.Timeouts.KillGrace > 0 {
+		return time.Duration(cfg.Timeouts.KillGrace) * time.Second
+	}
+	return defaultKillGrace
+}
+
+// runWithTimeout calls run with a context that ends after the decision's
+// timeout, then looks for processes the command left behind. Sandboxed
+// commands don't get to leave anything running; host commands only do
+// until they time out.
+func (e *Executor) runWithTimeout(ctx context.Context, cmdArgs []string, decision ExecutionDecision, run func(context.Context) error) error {
+	timeout := decision.Timeout
+	if timeout == 0 {
+		timeout = CommandTimeout(e.config, cmdArgs, decision.RiskLevel)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := namespace.SetChildSubreaper(); err != nil {
+		e.logger.Debug("straggler detection unavailable", map[string]any{"error": err.Error()})
+	}
+
+	err := run(ctx)
+	timedOut := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded
+
+	e.stragglers = namespace.Stragglers()
+	if len(e.stragglers) > 0 {
+		kill := timedOut || decision.Mode == ExecutionModeSandbox
+		fields := map[string]any{
+			"command":    strings.Join(cmdArgs, " "),
+			"stragglers": e.stragglers,
+			"killed":     kill,
+		}
+		e.logger.Warn("command left processes running", fields)
+		if kill {
+			namespace.KillStragglers()
+		}
+	}
+
+	if timedOut {
+		e.logger.Warn("command timed out", map[string]any{
+			"command": strings.Join(cmdArgs, " "),
+			"timeout": timeout.String(),
+		})
+		return &TimeoutError{Timeout: timeout}
+	}
+	return err
+}
+
+// Stragglers returns the processes the last command left running after it
+// exited. They have been killed if the command was sandboxed or timed out.
+func (e *Executor) Stragglers() []namespace.Process {
+	return e.stragglers
+}
@@ -0,0 +1,45 @@
+package sandbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/config"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sandbox.Timeout = 300
+	cfg.Timeouts.Critical = 30
+	cfg.Timeouts.Commands = map[string]int{
+		"npm":         60,
+		"npm install": 900,
+		"terraform":   0,
+	}
+
+	tests := []struct {
+		name string
+		cmd  []string
+		risk string
+		want time.Duration
+	}{
+		{name: "default", cmd: []string{"ls"}, risk: "low", want: 300 * time.Second},
+		{name: "risk level", cmd: []string{"rm", "-rf", "build"}, risk: "critical", want: 30 * time.Second},
+		{name: "command wins over risk", cmd: []string{"npm", "test"}, risk: "critical", want: 60 * time.Second},
+		{name: "longest prefix", cmd: []string{"npm", "install", "left-pad"}, risk: "medium", want: 900 * time.Second},
+		{name: "whole words", cmd: []string{"npmx"}, risk: "low", want: 300 * time.Second},
+		{name: "zero falls back", cmd: []string{"terraform", "plan"}, risk: "low", want: 300 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CommandTimeout(cfg, tt.cmd, tt.risk); got != tt.want {
+				t.Errorf("CommandTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	cfg.Sandbox.Timeout = 0
+	if got := CommandTimeout(cfg, []string{"ls"}, "low"); got != 0 {
+		t.Errorf("CommandTimeout() without timeout = %s, want 0", got)
+	}
+}
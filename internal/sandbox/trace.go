@@ -0,0 +1,117 @@
+package sandbox
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vectra-guard/vectra-guard/internal/analyzer"
+	"github.com/vectra-guard/vectra-guard/internal/config"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+)
+
+// TracedExec is a program a sandboxed command ran, with what the analyzer
+// found in it
+type TracedExec struct {
+	namespace.ExecEvent
+	RiskLevel string
+	Findings  []analyzer.Finding
+	Killed    bool // The command was killed for running it
+}
+
+// Command is the program's command line
+func (t TracedExec) Command() string {
+	return strings.Join(t.Argv, " ")
+}
+
+// KilledExecError is returned by Execute when tracing caught the command
+// running a program with a critical finding and killed the sandbox
+type KilledExecError struct {
+	Exec TracedExec
+}
+
+func (e *KilledExecError) Error() string {
+	var codes []string
+	for _, f := range e.Exec.Findings {
+		if f.Severity == "critical" {
+			codes = append(codes, f.Code)
+		}
+	}
+	return fmt.Sprintf("sandbox killed: command ran %q (%s)", e.Exec.Command(), strings.Join(codes, ", "))
+}
+
+// traceConfig returns the runtime's trace settings when tracing is enabled.
+// Each program is analyzed like an exec'd command; a critical finding the
+// top-level command wasn't approved with kills the sandbox unless
+// tracing.on_critical is "record".
+func (e *Executor) traceConfig(decision ExecutionDecision) *namespace.TraceConfig {
+	tracing := e.config.Tracing
+	if !tracing.Enabled {
+		return nil
+	}
+	approved := map[string]bool{}
+	for _, code := range decision.ApprovedFindings {
+		approved[code] = true
+	}
+	return &namespace.TraceConfig{
+		Poll:         tracing.Mode == "poll",
+		PollInterval: time.Duration(tracing.PollIntervalMS) * time.Millisecond,
+		OnExec: func(ev namespace.ExecEvent) bool {
+			traced := e.analyzeExec(ev)
+			for _, f := range traced.Findings {
+				if f.Severity == "critical" && !approved[f.Code] && tracing.OnCritical != "record" {
+					traced.Killed = true
+				}
+			}
+			e.traced = append(e.traced, traced)
+
+			fields := map[string]any{
+				"command": traced.Command(),
+				"pid":     ev.PID,
+				"ppid":    ev.PPID,
+				"cwd":     ev.Cwd,
+				"risk":    traced.RiskLevel,
+			}
+			switch {
+			case traced.Killed:
+				e.logger.Error("sandboxed command ran a critical program, killing it", fields)
+			case traced.RiskLevel != "low":
+				e.logger.Warn("sandboxed command ran a risky program", fields)
+			default:
+				e.logger.Debug("sandboxed command ran a program", fields)
+			}
+			return !traced.Killed
+		},
+	}
+}
+
+// analyzeExec runs a traced program's command line through the analyzer
+func (e *Executor) analyzeExec(ev namespace.ExecEvent) TracedExec {
+	traced := TracedExec{ExecEvent: ev, RiskLevel: "low"}
+	opts := analyzer.OptionsFromConfig(e.config)
+	opts.Context = config.DetectionContext{Command: traced.Command(), WorkingDir: ev.Cwd}
+	traced.Findings = analyzer.AnalyzeScriptWithOptions("traced-exec", []byte(traced.Command()), e.config.Policies, opts)
+	rank := map[string]int{"low": 0, "medium": 1, "high": 2, "critical": 3}
+	for _, f := range traced.Findings {
+		if rank[f.Severity] > rank[traced.RiskLevel] {
+			traced.RiskLevel = f.Severity
+		}
+	}
+	return traced
+}
+
+// killedExec is the traced program the sandbox was killed for, if any
+func (e *Executor) killedExec() *TracedExec {
+	for i := range e.traced {
+		if e.traced[i].Killed {
+			return &e.traced[i]
+		}
+	}
+	return nil
+}
+
+// TracedExecs returns the programs the last sandboxed command ran, if it
+// was traced.
+func (e *Executor) TracedExecs() []TracedExec {
+	return e.traced
+}
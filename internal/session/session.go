@@ -26,6 +26,7 @@ type Session struct {
 
 // Command represents a single command execution in a session.
 type Command struct {
+	ID          string                 `json:"id,omitempty"`
 	Timestamp   time.Time              `json:"timestamp"`
 	Command     string                 `json:"command"`
 	Args        []string               `json:"args"`
@@ -37,9 +38,25 @@ type Command struct {
 	Approved    bool                   `json:"approved"`
 	ApprovedBy  string                 `json:"approved_by,omitempty"`
 	Findings    []string               `json:"findings,omitempty"`
+	SnapshotID  string                 `json:"snapshot_id,omitempty"` // Workspace snapshot taken before the command ran
+	TimedOut    bool                   `json:"timed_out,omitempty"`   // Stopped for running past its timeout
+	Stragglers  []string               `json:"stragglers,omitempty"`  // Processes it left running after it exited
+	Execs       []Exec                 `json:"execs,omitempty"`       // Programs it ran, when sandboxed with tracing
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// Exec is a program a traced command ran.
+type Exec struct {
+	Timestamp time.Time `json:"timestamp"`
+	PID       int       `json:"pid"`
+	PPID      int       `json:"ppid"`
+	Args      []string  `json:"args"`
+	Cwd       string    `json:"cwd,omitempty"`
+	RiskLevel string    `json:"risk_level"`
+	Findings  []string  `json:"findings,omitempty"`
+	Killed    bool      `json:"killed,omitempty"` // The command was killed for running it
+}
+
 // FileOperation represents a file system operation.
 type FileOperation struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -138,6 +155,9 @@ func (m *Manager) End(session *Session) error {
 
 // AddCommand appends a command to the session and updates risk score.
 func (m *Manager) AddCommand(session *Session, cmd Command) error {
+	if cmd.ID == "" {
+		cmd.ID = fmt.Sprintf("cmd-%d", time.Now().UnixNano())
+	}
 	session.Commands = append(session.Commands, cmd)
 	
 	// Update risk score based on command risk level
@@ -152,21 +172,52 @@ func (m *Manager) AddCommand(session *Session, cmd Command) error {
 		session.RiskScore += 10
 	}
 
+	// A program the tracer had to kill is a violation of its own
+	for _, exec := range cmd.Execs {
+		if exec.Killed {
+			session.RiskScore += 100
+			session.Violations++
+		}
+	}
+
 	return m.save(session)
 }
 
 // AddFileOperation appends a file operation to the session.
 func (m *Manager) AddFileOperation(session *Session, op FileOperation) error {
-	session.FileOps = append(session.FileOps, op)
-	
-	if !op.Allowed {
-		session.Violations++
-		session.RiskScore += 25
+	return m.AddFileOperations(session, []FileOperation{op})
+}
+
+// AddFileOperations appends file operations to the session, saving once.
+func (m *Manager) AddFileOperations(session *Session, ops []FileOperation) error {
+	for _, op := range ops {
+		session.FileOps = append(session.FileOps, op)
+		
+		if !op.Allowed {
+			session.Violations++
+			session.RiskScore += 25
+		}
 	}
 
 	return m.save(session)
 }
 
+// FindCommand returns the command with the given ID from any session.
+func (m *Manager) FindCommand(commandID string) (*Session, *Command, error) {
+	sessions, err := m.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sess := range sessions {
+		for i := range sess.Commands {
+			if sess.Commands[i].ID == commandID {
+				return sess, &sess.Commands[i], nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("command %s not found in any session", commandID)
+}
+
 // List returns all sessions in the workspace.
 func (m *Manager) List() ([]*Session, error) {
 	entries, err := os.ReadDir(m.sessionDir)
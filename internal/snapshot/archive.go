@@ -0,0 +1,195 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (s *Store) createArchive(snap *Snapshot, maxBytes int64) error {
+	var total int64
+	err := filepath.WalkDir(snap.Workspace, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		if maxBytes > 0 && total > maxBytes {
+			return ErrTooLarge
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%d MB)", err, maxBytes>>20)
+	}
+
+	snap.Kind = KindArchive
+	snap.Archive = filepath.Join(s.dir, snap.ID+".tar.gz")
+	f, err := os.OpenFile(snap.Archive, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(snap.Workspace, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == snap.Workspace {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, _ = os.Readlink(p)
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil // sockets, fifos, devices
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(snap.Workspace, p)
+		hdr.Name = filepath.ToSlash(rel)
+		hdr.Format = tar.FormatPAX // Keep sub-second mtimes so unchanged files are skipped on restore
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			snap.Files++
+			in, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, in)
+			in.Close()
+			return err
+		}
+		return nil
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(snap.Archive)
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+func restoreArchive(snap *Snapshot) (RestoreResult, error) {
+	var result RestoreResult
+	f, err := os.Open(snap.Archive)
+	if err != nil {
+		return result, fmt.Errorf("open archive: %w", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return result, fmt.Errorf("read archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	kept := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("read archive: %w", err)
+		}
+		rel := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return result, fmt.Errorf("archive entry escapes workspace: %s", hdr.Name)
+		}
+		kept[rel] = true
+		target := filepath.Join(snap.Workspace, rel)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return result, err
+			}
+		case tar.TypeSymlink:
+			if current, err := os.Readlink(target); err == nil && current == hdr.Linkname {
+				continue
+			}
+			os.RemoveAll(target)
+			os.MkdirAll(filepath.Dir(target), 0o755)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return result, err
+			}
+			result.Restored++
+		case tar.TypeReg:
+			if unchanged(target, hdr) {
+				continue
+			}
+			if err := writeEntry(target, hdr, tr); err != nil {
+				return result, err
+			}
+			result.Restored++
+		}
+	}
+
+	// Remove files and directories created after the snapshot
+	var created []string
+	filepath.WalkDir(snap.Workspace, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == snap.Workspace {
+			return nil
+		}
+		rel, _ := filepath.Rel(snap.Workspace, p)
+		if !kept[rel] {
+			created = append(created, p)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	for _, p := range created {
+		if err := os.RemoveAll(p); err == nil {
+			result.Removed++
+		}
+	}
+	return result, nil
+}
+
+// unchanged reports whether target still has the archived size and mtime.
+func unchanged(target string, hdr *tar.Header) bool {
+	info, err := os.Lstat(target)
+	return err == nil && info.Mode().IsRegular() && info.Size() == hdr.Size && info.ModTime().Equal(hdr.ModTime) &&
+		info.Mode().Perm() == hdr.FileInfo().Mode().Perm()
+}
+
+func writeEntry(target string, hdr *tar.Header, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	os.RemoveAll(target)
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+}
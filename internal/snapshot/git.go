@@ -0,0 +1,197 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotIdentity lets commit-tree work without a configured user.
+var snapshotIdentity = []string{
+	"GIT_AUTHOR_NAME=vectra-guard", "GIT_AUTHOR_EMAIL=vectra-guard@localhost",
+	"GIT_COMMITTER_NAME=vectra-guard", "GIT_COMMITTER_EMAIL=vectra-guard@localhost",
+}
+
+func git(dir string, env []string, args ...string) (string, error) {
+	return gitInput(dir, env, "", args...)
+}
+
+func gitInput(dir string, env []string, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func gitTopLevel(dir string) (string, bool) {
+	top, err := git(dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil || top == "" {
+		return "", false
+	}
+	return top, true
+}
+
+// worktreeTree writes the current working tree (tracked and untracked, not
+// ignored) as a tree object using a scratch index, leaving the real one alone.
+func worktreeTree(top, scratch string) (string, error) {
+	// Start from a copy of the real index so unchanged files aren't rehashed
+	if real, err := git(top, nil, "rev-parse", "--git-path", "index"); err == nil {
+		if !filepath.IsAbs(real) {
+			real = filepath.Join(top, real)
+		}
+		copyFile(real, scratch)
+	}
+	env := []string{"GIT_INDEX_FILE=" + scratch}
+	if _, err := git(top, env, "add", "-A", "--", "."); err != nil {
+		return "", err
+	}
+	return git(top, env, "write-tree")
+}
+
+func createGit(snap *Snapshot) error {
+	scratch, cleanup, err := scratchIndex()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	tree, err := worktreeTree(snap.Workspace, scratch)
+	if err != nil {
+		return err
+	}
+	args := []string{"commit-tree", tree, "-m", "vectra-guard snapshot before: " + snap.Command}
+	if head, err := git(snap.Workspace, nil, "rev-parse", "--verify", "-q", "HEAD"); err == nil && head != "" {
+		snap.Head = head
+		args = append(args, "-p", head)
+	}
+	commit, err := git(snap.Workspace, snapshotIdentity, args...)
+	if err != nil {
+		return err
+	}
+	snap.Kind = KindGit
+	snap.Commit = commit
+	snap.Ref = RefPrefix + snap.ID
+	if _, err := git(snap.Workspace, nil, "update-ref", snap.Ref, commit); err != nil {
+		return err
+	}
+	if files, err := git(snap.Workspace, nil, "ls-tree", "-r", "--name-only", tree); err == nil && files != "" {
+		snap.Files = strings.Count(files, "\n") + 1
+	}
+	return nil
+}
+
+func restoreGit(snap *Snapshot) (RestoreResult, error) {
+	var result RestoreResult
+	top := snap.Workspace
+	if _, err := git(top, nil, "cat-file", "-e", snap.Commit+"^{commit}"); err != nil {
+		return result, fmt.Errorf("snapshot commit %s is gone: %w", snap.Commit, err)
+	}
+
+	current, cleanup, err := scratchIndex()
+	if err != nil {
+		return result, err
+	}
+	defer cleanup()
+	currentTree, err := worktreeTree(top, current)
+	if err != nil {
+		return result, err
+	}
+	snapTree := snap.Commit + "^{tree}"
+
+	// Files created after the snapshot
+	added, err := git(top, nil, "diff-tree", "-r", "-z", "--name-only", "--no-renames", "--diff-filter=A", snapTree, currentTree)
+	if err != nil {
+		return result, err
+	}
+	for _, rel := range strings.Split(added, "\x00") {
+		if rel == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(top, rel)); err == nil {
+			result.Removed++
+			removeEmptyParents(top, filepath.Dir(filepath.Join(top, rel)))
+		}
+	}
+
+	changed, err := git(top, nil, "diff-tree", "-r", "-z", "--name-only", "--no-renames", "--diff-filter=DMT", snapTree, currentTree)
+	if err != nil {
+		return result, err
+	}
+	for _, rel := range strings.Split(changed, "\x00") {
+		if rel != "" {
+			result.Restored++
+		}
+	}
+	if result.Restored > 0 {
+		if err := checkoutSnapshot(top, snap.Commit, changed); err != nil {
+			return result, err
+		}
+	}
+
+	if head, err := git(top, nil, "rev-parse", "--verify", "-q", "HEAD"); err == nil && snap.Head != "" && head != snap.Head {
+		result.Head = head
+	}
+	return result, nil
+}
+
+// checkoutSnapshot writes the NUL-separated paths from commit into the
+// working tree through a scratch index.
+func checkoutSnapshot(top, commit, paths string) error {
+	target, cleanupTarget, err := scratchIndex()
+	if err != nil {
+		return err
+	}
+	defer cleanupTarget()
+	env := []string{"GIT_INDEX_FILE=" + target}
+	if _, err := git(top, env, "read-tree", commit); err != nil {
+		return err
+	}
+	_, err = gitInput(top, env, paths, "checkout-index", "-f", "-z", "--stdin")
+	return err
+}
+
+// scratchIndex returns a path for a temporary index file (git creates it).
+func scratchIndex() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "vectra-guard-index-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create scratch index: %w", err)
+	}
+	return filepath.Join(dir, "index"), func() { os.RemoveAll(dir) }, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeEmptyParents deletes dir and its parents up to (not including) top while they are empty.
+func removeEmptyParents(top, dir string) {
+	for dir != top && strings.HasPrefix(dir, top+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,190 @@
+// Package snapshot captures the workspace before risky commands run on the
+// host so they can be undone.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot kinds
+const (
+	KindGit     = "git" // Commit object under refs/vectra-guard/snapshots/
+	KindArchive = "tar" // Compressed tarball in the snapshot directory
+)
+
+// RefPrefix is where git snapshots are kept; they are not branches or tags,
+// so they don't show up in `git log --all` listings of heads.
+const RefPrefix = "refs/vectra-guard/snapshots/"
+
+// ErrTooLarge is returned when a non-git workspace exceeds the archive size limit.
+var ErrTooLarge = errors.New("workspace exceeds snapshot size limit")
+
+// Snapshot records one captured workspace state.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Workspace string    `json:"workspace"`
+	Command   string    `json:"command"`
+	CreatedAt time.Time `json:"created_at"`
+	Ref       string    `json:"ref,omitempty"`     // Git snapshots
+	Commit    string    `json:"commit,omitempty"`  // Git snapshots
+	Head      string    `json:"head,omitempty"`    // HEAD when the snapshot was taken
+	Archive   string    `json:"archive,omitempty"` // Archive snapshots
+	Files     int       `json:"files"`
+}
+
+// RestoreResult summarizes an undo.
+type RestoreResult struct {
+	Restored int    // Files written back
+	Removed  int    // Files created after the snapshot that were deleted
+	Head     string // Current HEAD when it differs from the snapshot's
+}
+
+// Store keeps snapshot metadata (and archives) in a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore opens the snapshot store, defaulting to ~/.vectra-guard/snapshots.
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get home dir: %w", err)
+		}
+		dir = filepath.Join(homeDir, ".vectra-guard", "snapshots")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Create snapshots workspace before command runs. Git workspaces are stored
+// as a commit of tracked and untracked (not ignored) files; others as a
+// tarball of at most maxBytes (0 for no limit).
+func (s *Store) Create(workspace, command string, maxBytes int64) (*Snapshot, error) {
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{
+		ID:        fmt.Sprintf("snap-%d", time.Now().UnixNano()),
+		Workspace: workspace,
+		Command:   command,
+		CreatedAt: time.Now(),
+	}
+
+	if top, ok := gitTopLevel(workspace); ok {
+		snap.Workspace = top
+		err = createGit(snap)
+	} else {
+		err = s.createArchive(snap, maxBytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(snap); err != nil {
+		s.discard(snap)
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Restore puts the workspace back to the snapshot: changed and deleted files
+// are written back and files created since are removed. Ignored files are
+// left alone in git workspaces.
+func (s *Store) Restore(snap *Snapshot) (RestoreResult, error) {
+	switch snap.Kind {
+	case KindGit:
+		return restoreGit(snap)
+	case KindArchive:
+		return restoreArchive(snap)
+	}
+	return RestoreResult{}, fmt.Errorf("unknown snapshot kind %q", snap.Kind)
+}
+
+// Load reads a snapshot by ID.
+func (s *Store) Load(id string) (*Snapshot, error) {
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid snapshot id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// List returns the snapshots of workspace (all workspaces if empty), newest first.
+func (s *Store) List(workspace string) ([]*Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot directory: %w", err)
+	}
+	if workspace != "" {
+		if top, ok := gitTopLevel(workspace); ok {
+			workspace = top
+		}
+	}
+	var snaps []*Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		snap, err := s.Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		if workspace == "" || snap.Workspace == workspace {
+			snaps = append(snaps, snap)
+		}
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].CreatedAt.After(snaps[j].CreatedAt) })
+	return snaps, nil
+}
+
+// Prune deletes all but the newest keep snapshots of workspace.
+func (s *Store) Prune(workspace string, keep int) error {
+	snaps, err := s.List(workspace)
+	if err != nil || keep <= 0 || len(snaps) <= keep {
+		return err
+	}
+	for _, snap := range snaps[keep:] {
+		s.discard(snap)
+	}
+	return nil
+}
+
+func (s *Store) save(snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, snap.ID+".json"), data, 0o600); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// discard removes a snapshot's ref or archive and its metadata.
+func (s *Store) discard(snap *Snapshot) {
+	switch snap.Kind {
+	case KindGit:
+		git(snap.Workspace, nil, "update-ref", "-d", snap.Ref)
+	case KindArchive:
+		os.Remove(snap.Archive)
+	}
+	os.Remove(filepath.Join(s.dir, snap.ID+".json"))
+}
@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestGitSnapshotRestore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ws := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", ws, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	writeFile(t, ws, "tracked.txt", "v1\n")
+	writeFile(t, ws, ".gitignore", "ignored.log\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	writeFile(t, ws, "tracked.txt", "v2 uncommitted\n")
+	writeFile(t, ws, "src/untracked.go", "package src\n")
+	writeFile(t, ws, "staged.txt", "staged\n")
+	run("add", "staged.txt")
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Create(ws, "rm -rf src", 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if snap.Kind != KindGit || snap.Ref == "" || snap.Files != 4 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	// The user's index is untouched
+	out, _ := exec.Command("git", "-C", ws, "diff", "--cached", "--name-only").Output()
+	if string(out) != "staged.txt\n" {
+		t.Errorf("snapshot changed the index: %q", out)
+	}
+
+	// Damage the workspace
+	os.RemoveAll(filepath.Join(ws, "src"))
+	writeFile(t, ws, "tracked.txt", "clobbered\n")
+	writeFile(t, ws, "new.txt", "created later\n")
+	writeFile(t, ws, "ignored.log", "left alone\n")
+
+	loaded, err := store.Load(snap.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Restore(loaded)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if result.Restored != 2 || result.Removed != 1 {
+		t.Errorf("expected 2 restored and 1 removed, got %+v", result)
+	}
+	if got := readFile(t, ws, "tracked.txt"); got != "v2 uncommitted\n" {
+		t.Errorf("tracked.txt = %q", got)
+	}
+	if got := readFile(t, ws, "src/untracked.go"); got != "package src\n" {
+		t.Errorf("src/untracked.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "new.txt")); !os.IsNotExist(err) {
+		t.Error("expected new.txt to be removed")
+	}
+	if got := readFile(t, ws, "ignored.log"); got != "left alone\n" {
+		t.Errorf("ignored files should be left alone, got %q", got)
+	}
+
+	// Pruning removes the ref as well as the metadata
+	if _, err := store.Create(ws, "second", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Prune(ws, 1); err != nil {
+		t.Fatal(err)
+	}
+	if snaps, _ := store.List(ws); len(snaps) != 1 || snaps[0].Command != "second" {
+		t.Errorf("expected only the newest snapshot after prune, got %v", snaps)
+	}
+	if err := exec.Command("git", "-C", ws, "show-ref", "--verify", "-q", snap.Ref).Run(); err == nil {
+		t.Errorf("expected %s to be deleted", snap.Ref)
+	}
+}
+
+func TestArchiveSnapshotRestore(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, ws, "a.txt", "original\n")
+	writeFile(t, ws, "dir/b.txt", "keep\n")
+	if err := os.Symlink("a.txt", filepath.Join(ws, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create(ws, "too big", 4); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	snap, err := store.Create(ws, "rm -rf dir", 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if snap.Kind != KindArchive || snap.Files != 2 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	os.RemoveAll(filepath.Join(ws, "dir"))
+	writeFile(t, ws, "a.txt", "changed\n")
+	writeFile(t, ws, "extra/new.txt", "new\n")
+
+	result, err := store.Restore(snap)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if result.Restored != 2 || result.Removed != 1 {
+		t.Errorf("expected 2 restored and 1 removed, got %+v", result)
+	}
+	if got := readFile(t, ws, "a.txt"); got != "original\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	if got := readFile(t, ws, "dir/b.txt"); got != "keep\n" {
+		t.Errorf("dir/b.txt = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "extra")); !os.IsNotExist(err) {
+		t.Error("expected extra/ to be removed")
+	}
+	if target, err := os.Readlink(filepath.Join(ws, "link")); err != nil || target != "a.txt" {
+		t.Errorf("link = %q, %v", target, err)
+	}
+}
@@ -1,7 +1,14 @@
 package main
 
-import "github.com/vectra-guard/vectra-guard/cmd"
+import (
+	"github.com/vectra-guard/vectra-guard/cmd"
+	"github.com/vectra-guard/vectra-guard/internal/sandbox/namespace"
+)
 
 func main() {
+	// Namespace sandboxes re-execute vectra-guard as their init
+	if namespace.IsSandboxInit() {
+		namespace.RunSandboxInit()
+	}
 	cmd.Execute()
 }